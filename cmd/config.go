@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/K4rian/kfdsl/internal/settings"
+)
+
+func buildConfigCommand(sett *settings.Settings, command *Command) *cobra.Command {
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "Manage the server configuration file",
+	}
+
+	lintCmd := &cobra.Command{
+		Use:   "lint",
+		Short: "Check the server configuration file for errors",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := parseSettings(sett); err != nil {
+				return err
+			}
+			setCommand(command, "config lint", cmd, args)
+			return nil
+		},
+	}
+	lintCmd.Flags().String("format", "text", "report format (text, json)")
+
+	historyCmd := &cobra.Command{
+		Use:   "history [file]",
+		Short: "List the configuration files backups",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := parseSettings(sett); err != nil {
+				return err
+			}
+			setCommand(command, "config history", cmd, args)
+			return nil
+		},
+	}
+
+	restoreCmd := &cobra.Command{
+		Use:   "restore <id>",
+		Short: "Restore a configuration file backup",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := parseSettings(sett); err != nil {
+				return err
+			}
+			setCommand(command, "config restore", cmd, args)
+			return nil
+		},
+	}
+
+	exportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export the effective configuration as a single document",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := parseSettings(sett); err != nil {
+				return err
+			}
+			setCommand(command, "config export", cmd, args)
+			return nil
+		},
+	}
+	exportCmd.Flags().String("format", "json", "document format (json, yaml)")
+	exportCmd.Flags().StringP("output", "o", "", "write the document to a file instead of stdout")
+	exportCmd.Flags().Bool("secrets", false, "include the passwords and admin mail")
+
+	importCmd := &cobra.Command{
+		Use:   "import <file>",
+		Short: "Apply an exported configuration document",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := parseSettings(sett); err != nil {
+				return err
+			}
+			setCommand(command, "config import", cmd, args)
+			return nil
+		},
+	}
+
+	configCmd.AddCommand(lintCmd, historyCmd, restoreCmd, exportCmd, importCmd)
+	return configCmd
+}
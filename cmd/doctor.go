@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/K4rian/kfdsl/internal/settings"
+)
+
+func buildDoctorCommand(sett *settings.Settings, command *Command) *cobra.Command {
+	doctorCmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Diagnose the SteamCMD and server install",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := parseSettings(sett); err != nil {
+				return err
+			}
+			setCommand(command, "doctor", cmd, args)
+			return nil
+		},
+	}
+	doctorCmd.Flags().String("format", "text", "report format (text, json)")
+	return doctorCmd
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/spf13/viper"
+
+	"github.com/K4rian/kfdsl/internal/settings"
+)
+
+const instancesKey = "instances"
+
+// viperMu serializes the settings reloads, the global viper isn't safe
+// for concurrent use.
+var viperMu sync.Mutex
+
+// InstanceNames returns the sorted names of the server instances defined in
+// the launcher config file. It is empty when a single server is run.
+func InstanceNames() []string {
+	instances := viper.GetStringMap(instancesKey)
+	names := make([]string, 0, len(instances))
+	for name := range instances {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validateInstances checks that each instance is a map of flag values.
+func validateInstances() error {
+	for _, name := range InstanceNames() {
+		if viper.Sub(instancesKey+"."+name) == nil {
+			return fmt.Errorf("invalid instance '%s' in %s: a map of flag values is expected", name, viper.ConfigFileUsed())
+		}
+	}
+	return nil
+}
+
+// InstanceSettings parses the settings of the instance name. The values of
+// its instances entry win over the flags, env and the rest of the launcher
+// config file, which are shared by all the instances.
+func InstanceSettings(name string, sett *settings.Settings) error {
+	sub := viper.Sub(instancesKey + "." + name)
+	if sub == nil {
+		return fmt.Errorf("unknown instance: %s", name)
+	}
+
+	v := viper.New()
+	for _, key := range viper.AllKeys() {
+		if key == instancesKey || strings.HasPrefix(key, instancesKey+".") {
+			continue
+		}
+		v.Set(key, viper.Get(key))
+	}
+	for _, key := range sub.AllKeys() {
+		v.Set(key, sub.Get(key))
+	}
+
+	registerArguments(sett, v)
+	// Every key is set in v, the shared and instance values tell what's given
+	markExplicitArguments(sett, func(key string) bool { return viper.IsSet(key) || sub.IsSet(key) })
+	if err := sett.Parse(); err != nil {
+		return fmt.Errorf("instance %s: %w", name, err)
+	}
+	sett.ExtraArgs = v.GetStringSlice("KF_EXTRAARGS")
+	return nil
+}
+
+// ReloadInstanceSettings reads the launcher config file again and parses
+// the settings of the instance name into sett.
+func ReloadInstanceSettings(name string, sett *settings.Settings) error {
+	viperMu.Lock()
+	defer viperMu.Unlock()
+
+	if err := loadLauncherConfigFile(); err != nil {
+		return err
+	}
+	return InstanceSettings(name, sett)
+}
@@ -0,0 +1,245 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+
+	"github.com/K4rian/kfdsl/internal/config/ini"
+	"github.com/K4rian/kfdsl/internal/cron"
+	"github.com/K4rian/kfdsl/internal/monitor"
+	"github.com/K4rian/kfdsl/internal/settings"
+)
+
+const (
+	iniOverridesKey = "ini_overrides"
+	iniEnvPrefix    = "KF_INI__"
+	scheduleKey     = "schedule"
+	usageRulesKey   = "usage_rules"
+)
+
+// iniOverrideEntry is an ini_overrides entry of the launcher config file.
+type iniOverrideEntry struct {
+	Section string
+	Key     string
+	Value   any
+	Op      string
+}
+
+// scheduleEntry is a schedule entry of the launcher config file.
+type scheduleEntry struct {
+	Cron            string
+	Task            string
+	Message         string
+	Map             string
+	OnlyIfEmpty     bool `mapstructure:"only_if_empty"`
+	DeferUntilEmpty int  `mapstructure:"defer_until_empty"` // In seconds
+	Instances       []string
+}
+
+// usageRuleEntry is a usage_rules entry of the launcher config file.
+type usageRuleEntry struct {
+	Metric          string
+	Above           float64
+	For             int // In seconds
+	Action          string
+	OnlyIfEmpty     bool `mapstructure:"only_if_empty"`
+	DeferUntilEmpty int  `mapstructure:"defer_until_empty"` // In seconds
+}
+
+// loadLauncherConfigFile reads the launcher config file, if any.
+// Its keys use the flag names and have a lower priority than flags and env.
+func loadLauncherConfigFile() error {
+	file := viper.GetString("launcher-config")
+	if file == "" {
+		return nil
+	}
+
+	viper.SetConfigFile(file)
+	if err := viper.ReadInConfig(); err != nil {
+		return fmt.Errorf("failed to read the launcher config file %s: %w", file, err)
+	}
+
+	// Catch malformed overrides, tasks and rules early
+	entries := map[string]iniOverrideEntry{}
+	if err := viper.UnmarshalKey(iniOverridesKey, &entries); err != nil {
+		return fmt.Errorf("invalid %s in %s: %w", iniOverridesKey, file, err)
+	}
+	tasks := map[string]scheduleEntry{}
+	if err := viper.UnmarshalKey(scheduleKey, &tasks); err != nil {
+		return fmt.Errorf("invalid %s in %s: %w", scheduleKey, file, err)
+	}
+	rules := map[string]usageRuleEntry{}
+	if err := viper.UnmarshalKey(usageRulesKey, &rules); err != nil {
+		return fmt.Errorf("invalid %s in %s: %w", usageRulesKey, file, err)
+	}
+	return validateInstances()
+}
+
+// ReloadSettings reads the launcher config file again and parses the
+// settings from the flags, env and config file into sett.
+func ReloadSettings(sett *settings.Settings) error {
+	viperMu.Lock()
+	defer viperMu.Unlock()
+
+	if err := loadLauncherConfigFile(); err != nil {
+		return err
+	}
+	if err := parseSettings(sett); err != nil {
+		return err
+	}
+	sett.ExtraArgs = viper.GetStringSlice("KF_EXTRAARGS")
+	return nil
+}
+
+// iniOverrides returns the ini overrides from the launcher config file,
+// the KF_INI__ env variables and the --ini-set flags, in this order.
+// Later overrides win over earlier ones.
+func iniOverrides(v *viper.Viper) []string {
+	var specs []string
+
+	// Config file entries are sorted by name to be applied in a stable order
+	entries := map[string]iniOverrideEntry{}
+	v.UnmarshalKey(iniOverridesKey, &entries)
+
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		e := entries[name]
+		o := ini.Override{
+			Section: e.Section,
+			Key:     e.Key,
+			Value:   formatIniValue(e.Value),
+			Op:      ini.OverrideOp(strings.ToLower(e.Op)),
+		}
+		if o.Op == "" {
+			o.Op = ini.OverrideSet
+		}
+		specs = append(specs, o.String())
+	}
+
+	specs = append(specs, envIniOverrides()...)
+	return append(specs, v.GetStringSlice("ini-set")...)
+}
+
+// scheduleTasks returns the tasks of the launcher config file schedule,
+// sorted by name.
+func scheduleTasks(v *viper.Viper) (cron.Tasks, error) {
+	entries := map[string]scheduleEntry{}
+	if err := v.UnmarshalKey(scheduleKey, &entries); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tasks := make(cron.Tasks, 0, len(names))
+	for _, name := range names {
+		e := entries[name]
+		tasks = append(tasks, cron.Task{
+			Name:            name,
+			Cron:            e.Cron,
+			Type:            cron.TaskType(strings.ToLower(e.Task)),
+			Message:         e.Message,
+			Map:             e.Map,
+			OnlyIfEmpty:     e.OnlyIfEmpty,
+			DeferUntilEmpty: time.Duration(e.DeferUntilEmpty) * time.Second,
+			Instances:       e.Instances,
+		})
+	}
+	return tasks, nil
+}
+
+// usageRules returns the resource usage rules of the launcher config file,
+// sorted by name.
+func usageRules(v *viper.Viper) (monitor.Rules, error) {
+	entries := map[string]usageRuleEntry{}
+	if err := v.UnmarshalKey(usageRulesKey, &entries); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	rules := make(monitor.Rules, 0, len(names))
+	for _, name := range names {
+		e := entries[name]
+		rules = append(rules, monitor.Rule{
+			Name:            name,
+			Metric:          monitor.Metric(strings.ToLower(e.Metric)),
+			Above:           e.Above,
+			For:             time.Duration(e.For) * time.Second,
+			Action:          monitor.Action(strings.ToLower(e.Action)),
+			OnlyIfEmpty:     e.OnlyIfEmpty,
+			DeferUntilEmpty: time.Duration(e.DeferUntilEmpty) * time.Second,
+		})
+	}
+	return rules, nil
+}
+
+// envIniOverrides parses the KF_INI__<Section>__<Key>[__APPEND|__DELETE] env variables.
+// Underscores in the section name stand for dots: KF_INI__KFmod_KFGameType__StartingCash,
+// and three underscores for a literal one: KF_INI__MyMod___v2_Settings__Enabled.
+func envIniOverrides() []string {
+	var specs []string
+
+	env := os.Environ()
+	sort.Strings(env)
+	for _, kv := range env {
+		name, value, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(name, iniEnvPrefix) {
+			continue
+		}
+
+		// The escaped underscores are kept aside while splitting the name
+		escaped := strings.ReplaceAll(strings.TrimPrefix(name, iniEnvPrefix), "___", "\x00")
+		parts := strings.Split(escaped, "__")
+		op := "="
+		if len(parts) == 3 {
+			switch strings.ToUpper(parts[2]) {
+			case "APPEND":
+				op = "+="
+			case "DELETE":
+				op = "-="
+			default:
+				op = ""
+			}
+		}
+		if op == "" || len(parts) < 2 || len(parts) > 3 {
+			// Let the parser report the malformed variable
+			specs = append(specs, name)
+			continue
+		}
+
+		section := strings.ReplaceAll(strings.ReplaceAll(parts[0], "_", "."), "\x00", "_")
+		key := strings.ReplaceAll(parts[1], "\x00", "_")
+		specs = append(specs, section+"."+key+op+value)
+	}
+	return specs
+}
+
+// formatIniValue converts a config file value to its ini representation.
+func formatIniValue(v any) string {
+	if b, ok := v.(bool); ok {
+		if b {
+			return "True"
+		}
+		return "False"
+	}
+	return cast.ToString(v)
+}
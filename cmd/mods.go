@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/K4rian/kfdsl/internal/settings"
+)
+
+func buildModsCommand(sett *settings.Settings, command *Command) *cobra.Command {
+	modsCmd := &cobra.Command{
+		Use:   "mods",
+		Short: "Manage the installed mods",
+	}
+
+	rollbackCmd := &cobra.Command{
+		Use:   "rollback",
+		Short: "Restore the mods installed before the last install",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := parseSettings(sett); err != nil {
+				return err
+			}
+			setCommand(command, "mods rollback", cmd, args)
+			return nil
+		},
+	}
+
+	signCmd := &cobra.Command{
+		Use:   "sign [mod...]",
+		Short: "Sign the mods file entries (all by default) or the whole file",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := parseBaseSettings(sett); err != nil {
+				return err
+			}
+			setCommand(command, "mods sign", cmd, args)
+			return nil
+		},
+	}
+	signCmd.Flags().String("key", "", "private key file")
+	signCmd.Flags().String("key-id", "", "publisher key ID (defaults to the key file name)")
+	signCmd.Flags().Bool("detached", false, "sign the whole mods file in a detached .sig file")
+	signCmd.MarkFlagRequired("key")
+
+	keygenCmd := &cobra.Command{
+		Use:   "keygen <key-id>",
+		Short: "Generate a new publisher key pair",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := parseBaseSettings(sett); err != nil {
+				return err
+			}
+			setCommand(command, "mods keygen", cmd, args)
+			return nil
+		},
+	}
+	keygenCmd.Flags().String("dir", ".", "output directory")
+
+	modsCmd.AddCommand(rollbackCmd, signCmd, keygenCmd)
+	return modsCmd
+}
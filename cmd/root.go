@@ -6,21 +6,29 @@ import (
 	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 
 	"github.com/K4rian/kfdsl/internal/arguments"
 	"github.com/K4rian/kfdsl/internal/settings"
 )
 
-func BuildRootCommand(sett *settings.Settings) *cobra.Command {
+// Command is the subcommand selected on the command-line.
+// Name is empty when the launcher should start the server.
+type Command struct {
+	Name  string
+	Args  []string
+	Flags *pflag.FlagSet
+}
+
+func BuildRootCommand(sett *settings.Settings, command *Command) *cobra.Command {
 	rootCmd := &cobra.Command{
 		Use:   "./kfdsl",
 		Short: "KF Dedicated Server Launcher",
 		Long:  "A command-line tool to configure and run a Killing Floor Dedicated Server.",
+		Args:  cobra.ArbitraryArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			registerArguments(sett)
-
-			if err := sett.Parse(); err != nil {
+			if err := parseSettings(sett); err != nil {
 				return err
 			}
 			viper.SetDefault("KF_EXTRAARGS", args)
@@ -28,42 +36,72 @@ func BuildRootCommand(sett *settings.Settings) *cobra.Command {
 			return nil
 		},
 	}
+	rootCmd.CompletionOptions.DisableDefaultCmd = true
+
+	// Read the launcher config file before any (sub)command parses the settings
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		return loadLauncherConfigFile()
+	}
 
 	var userHome, _ = os.UserHomeDir()
 
-	var configFile, modsFile, serverName, shortName, gameMode, startupMap, gameDifficulty,
+	var launcherConfigFile, configFile, modsFile, modsTrustStore, serverName, shortName, gameMode, startupMap, gameDifficulty,
 		gameLength, password, adminName, adminMail, adminPassword, motd, specimenType, mutators,
-		serverMutators, redirectURL, mapList, allTradersMessage, logLevel, logFilePath,
+		serverMutators, redirectURL, mapList, allTradersMessage, logLevel, logFilePath, ip, webadminIP, cpuAffinity, runAs, envAllow, shutdownWarnings, apiAddr, apiToken,
 		logFileFormat, steamRootDir, steamAppInstallDir string
 
-	var gamePort, webadminPort, gamespyPort, maxPlayers, maxSpectators, region,
+	var configBackups, gamePort, webadminPort, gamespyPort, maxPlayers, maxSpectators, region,
 		mapVoteRepeatLimit, logMaxSize, logMaxBackups, logMaxAge,
-		maxRestarts, restartDelay, shutdownTimeout, killTimeout int
+		maxRestarts, restartDelay, shutdownTimeout, killTimeout, reloadWait, hangTimeout, crashReports, shutdownGrace,
+		idleRestart, idleRestartUptime, idleReset, updateCheck, usageInterval,
+		nice, limitNoFile, limitAS, oomScoreAdj int
 
 	var friendlyFire float64
 
+	var startingCash, minRespawnCash, timeBetweenWaves, maxZombiesOnce int
+
+	var disableWaveFunding, disableEndGameBoss bool
+
+	var customWavesFile string
+
+	var iniSet []string
+
 	var enableWebAdmin, enableMapVote, enableAdminPause, disableWeaponThrow,
 		disableWeaponShake, enableThirdPerson, enableLowGore, uncap, unsecure, noSteam,
 		disableValidation, enableAutoRestart, enableMutloader, enableKFPatcher, enableShowPerks,
-		disableZEDTime, enableBuyEverywhere, enableAllTraders, enableFileLogging bool
+		disableZEDTime, enableBuyEverywhere, enableAllTraders, enableFileLogging, modsRequireSignature, crashCore, noNewPrivs, subreaper, shutdownWaitWave bool
 
 	flags := map[string]struct {
 		Value   interface{}
 		Desc    string
 		Default interface{}
 	}{
+		"launcher-config":        {&launcherConfigFile, "launcher configuration file (yaml, json or toml)", settings.DefaultLauncherConfigFile},
+		"config-backups":         {&configBackups, "number of backups to keep for each configuration file (0 = disabled)", settings.DefaultConfigBackups},
+		"ini-set":                {&iniSet, "override a server configuration key (Section.Key=value, Section.Key+=value to append, Section.Key-=[value] to delete), can be repeated", []string{}},
 		"mods":                   {&modsFile, "mods file", settings.DefaultModsFile},
+		"mods-trust-store":       {&modsTrustStore, "directory of the trusted mods publisher keys", filepath.Join(userHome, settings.DefaultModsTrustStore)},
+		"mods-require-signature": {&modsRequireSignature, "refuse to install unsigned mods", settings.DefaultModsRequireSignature},
 		"config":                 {&configFile, "configuration file", settings.DefaultConfigFile},
 		"servername":             {&serverName, "server name", settings.DefaultServerName},
 		"shortname":              {&shortName, "server short name", settings.DefaultShortName},
+		"ip":                     {&ip, "IP address to bind the server to (multihome), all the addresses if empty", settings.DefaultIP},
 		"port":                   {&gamePort, "game UDP port", settings.DefaultGamePort},
 		"webadminport":           {&webadminPort, "WebAdmin TCP port", settings.DefaultWebAdminPort},
+		"webadmin-ip":            {&webadminIP, "address of the WebAdmin panel URL, the server IP if empty (the panel listens on the server IP)", settings.DefaultWebAdminIP},
 		"gamespyport":            {&gamespyPort, "GameSpy UDP port", settings.DefaultGameSpyPort},
 		"gamemode":               {&gameMode, "game mode", settings.DefaultGameMode},
 		"map":                    {&startupMap, "starting map", settings.DefaultStartupMap},
 		"difficulty":             {&gameDifficulty, "game difficulty (easy, normal, hard, suicidal, hell)", settings.DefaultGameDifficulty},
-		"length":                 {&gameLength, "game length (waves) (short, medium, long)", settings.DefaultGameLength},
+		"length":                 {&gameLength, "game length (waves) (short, medium, long, custom)", settings.DefaultGameLength},
 		"friendlyfire":           {&friendlyFire, "friendly fire rate (0.0-1.0)", settings.DefaultFriendlyFire},
+		"startingcash":           {&startingCash, "players starting cash", settings.DefaultStartingCash},
+		"minrespawncash":         {&minRespawnCash, "minimum cash given to respawning players", settings.DefaultMinRespawnCash},
+		"timebetweenwaves":       {&timeBetweenWaves, "trader time between waves (in secs)", settings.DefaultTimeBetweenWaves},
+		"nowavefunding":          {&disableWaveFunding, "don't give cash to the players at the end of each wave", settings.DefaultDisableWaveFunding},
+		"maxzombies":             {&maxZombiesOnce, "maximum specimens alive at the same time", settings.DefaultMaxZombiesOnce},
+		"noendgameboss":          {&disableEndGameBoss, "don't spawn the Patriarch on the final wave", settings.DefaultDisableEndGameBoss},
+		"waves":                  {&customWavesFile, "custom waves file, used when the game length is custom", settings.DefaultCustomWavesFile},
 		"maxplayers":             {&maxPlayers, "maximum players", settings.DefaultMaxPlayers},
 		"maxspectators":          {&maxSpectators, "maximum spectators", settings.DefaultMaxSpectators},
 		"password":               {&password, "server password", settings.DefaultPassword},
@@ -108,6 +146,29 @@ func BuildRootCommand(sett *settings.Settings) *cobra.Command {
 		"restart-delay":          {&restartDelay, "delay between restart (in secs)", settings.DefaultRestartDelay},
 		"shutdown-timeout":       {&shutdownTimeout, "server shutdown timeout (in secs)", settings.DefaultShutdownTimeout},
 		"kill-timeout":           {&killTimeout, "server process kill timeout (in secs)", settings.DefaultKillTimeout},
+		"shutdown-warnings":      {&shutdownWarnings, "countdown warnings broadcast to the players before stopping the server (e.g. 5m,1m,10s)", settings.DefaultShutdownWarnings},
+		"shutdown-wait-wave":     {&shutdownWaitWave, "wait for the end of the current wave before stopping the server", settings.DefaultShutdownWaitWave},
+		"shutdown-grace":         {&shutdownGrace, "max time to warn the players before stopping the server (in secs)", settings.DefaultShutdownGrace},
+		"reload-wait":            {&reloadWait, "max time to wait for an empty server before applying a reload (in secs)", settings.DefaultReloadWait},
+		"api-addr":               {&apiAddr, "address the launcher HTTP API listens on (e.g. 127.0.0.1:7780, empty = disabled)", settings.DefaultAPIAddr},
+		"api-token":              {&apiToken, "bearer token required by the launcher HTTP API", settings.DefaultAPIToken},
+		"crash-reports":          {&crashReports, "number of crash reports to keep (0 = disabled)", settings.DefaultCrashReports},
+		"crash-core":             {&crashCore, "enable the server core dumps and add them to the crash reports", settings.DefaultCrashCoreDump},
+		"hang-timeout":           {&hangTimeout, "time without answering queries after which a stuck server is restarted (in secs, 0 = disabled)", settings.DefaultHangTimeout},
+		"idle-restart":           {&idleRestart, "time the server must be empty before it's restarted (in secs, 0 = disabled)", settings.DefaultIdleRestart},
+		"idle-restart-uptime":    {&idleRestartUptime, "minimum server uptime before an idle restart (in secs)", settings.DefaultIdleRestartUptime},
+		"idle-reset":             {&idleReset, "time the server must be empty before it returns to its startup map (in secs, 0 = disabled)", settings.DefaultIdleReset},
+		"update-check":           {&updateCheck, "interval between the checks for a server update on Steam (in secs, 0 = disabled)", settings.DefaultUpdateCheck},
+		"usage-interval":         {&usageInterval, "interval between the server resource usage samples (in secs, 0 = disabled)", settings.DefaultUsageInterval},
+		"nice":                   {&nice, "server process scheduling priority (-20 to 19)", settings.DefaultNice},
+		"cpu-affinity":           {&cpuAffinity, "CPUs the server process runs on (e.g. 0,2-3, empty = all)", settings.DefaultCPUAffinity},
+		"limit-nofile":           {&limitNoFile, "max open files of the server process (0 = unchanged)", settings.DefaultLimitNoFile},
+		"limit-as":               {&limitAS, "max address space of the server process (MB, 0 = unchanged)", settings.DefaultLimitAS},
+		"oom-score-adj":          {&oomScoreAdj, "OOM score adjustment of the server process (-1000 to 1000)", settings.DefaultOOMScoreAdj},
+		"run-as":                 {&runAs, "user[:group] the server process runs as (empty = launcher user)", settings.DefaultRunAs},
+		"env-allow":              {&envAllow, "environment variables passed to the server process (comma-separated, * = all but KF_* and STEAMACC_*)", settings.DefaultEnvAllow},
+		"no-new-privs":           {&noNewPrivs, "prevent the server process from gaining privileges", settings.DefaultNoNewPrivs},
+		"subreaper":              {&subreaper, "reap the orphaned processes of the servers and SteamCMD", settings.DefaultSubreaper},
 		"steamcmd-root":          {&steamRootDir, "SteamCMD root directory", filepath.Join(userHome, "steamcmd")},
 		"steamcmd-appinstalldir": {&steamAppInstallDir, "server installatation directory", filepath.Join(userHome, "gameserver")},
 	}
@@ -116,16 +177,19 @@ func BuildRootCommand(sett *settings.Settings) *cobra.Command {
 		switch v := data.Default.(type) {
 		case string:
 			val := data.Value.(*string)
-			rootCmd.Flags().StringVar(val, flag, v, data.Desc)
+			rootCmd.PersistentFlags().StringVar(val, flag, v, data.Desc)
 		case int:
 			val := data.Value.(*int)
-			rootCmd.Flags().IntVar(val, flag, v, data.Desc)
+			rootCmd.PersistentFlags().IntVar(val, flag, v, data.Desc)
 		case float64:
 			val := data.Value.(*float64)
-			rootCmd.Flags().Float64Var(val, flag, v, data.Desc)
+			rootCmd.PersistentFlags().Float64Var(val, flag, v, data.Desc)
 		case bool:
 			val := data.Value.(*bool)
-			rootCmd.Flags().BoolVar(val, flag, v, data.Desc)
+			rootCmd.PersistentFlags().BoolVar(val, flag, v, data.Desc)
+		case []string:
+			val := data.Value.(*[]string)
+			rootCmd.PersistentFlags().StringArrayVar(val, flag, v, data.Desc)
 		}
 
 		// SteamCMD-related configurations don't use the 'KF' prefix
@@ -135,7 +199,7 @@ func BuildRootCommand(sett *settings.Settings) *cobra.Command {
 		} else {
 			viper.BindEnv(flag)
 		}
-		viper.BindPFlag(flag, rootCmd.Flags().Lookup(flag))
+		viper.BindPFlag(flag, rootCmd.PersistentFlags().Lookup(flag))
 	}
 
 	viper.BindEnv("STEAMACC_USERNAME")
@@ -148,69 +212,202 @@ func BuildRootCommand(sett *settings.Settings) *cobra.Command {
 	viper.SetEnvPrefix("KF")
 	viper.AutomaticEnv()
 
+	rootCmd.AddCommand(buildModsCommand(sett, command))
+	rootCmd.AddCommand(buildConfigCommand(sett, command))
+	rootCmd.AddCommand(buildDoctorCommand(sett, command))
+
 	return rootCmd
 }
 
-func registerArguments(sett *settings.Settings) {
-	sett.ConfigFile = arguments.New("Config File", viper.GetString("config"), nil, nil, false)
-	sett.ModsFile = arguments.New("Mods File", viper.GetString("mods"), nil, nil, false)
-	sett.ServerName = arguments.New("Server Name", viper.GetString("servername"), arguments.ParseNonEmptyStr, nil, false)
-	sett.ShortName = arguments.New("Short Name", viper.GetString("shortname"), arguments.ParseNonEmptyStr, nil, false)
-	sett.GamePort = arguments.New("Game Port", viper.GetInt("port"), arguments.ParsePort, nil, false)
-	sett.WebAdminPort = arguments.New("WebAdmin Port", viper.GetInt("webadminport"), arguments.ParsePort, nil, false)
-	sett.GameSpyPort = arguments.New("GameSpy Port", viper.GetInt("gamespyport"), arguments.ParsePort, nil, false)
-	sett.GameMode = arguments.New("Game Mode", viper.GetString("gamemode"), arguments.ParseGameMode, arguments.FormatGameMode, false)
-	sett.StartupMap = arguments.New("Startup Map", viper.GetString("map"), arguments.ParseNonEmptyStr, nil, false)
-	sett.GameDifficulty = arguments.New("Game Difficulty", settings.DefaultInternalGameDifficulty, arguments.ParseGameDifficulty(viper.GetString("difficulty")), arguments.FormatGameDifficulty, false)
-	sett.GameLength = arguments.New("Game Length", settings.DefaultInternalGameLength, arguments.ParseGameLength(viper.GetString("length")), arguments.FormatGameLength, false)
-	sett.FriendlyFire = arguments.New("Friendly Fire Rate", viper.GetFloat64("friendlyfire"), arguments.ParseFriendlyFireRate, arguments.FormatFriendlyFireRate, false)
-	sett.MaxPlayers = arguments.New("Max Players", viper.GetInt("maxplayers"), nil, nil, false)
-	sett.MaxSpectators = arguments.New("Max Spectators", viper.GetInt("maxspectators"), nil, nil, false)
-	sett.Password = arguments.New("Game Password", viper.GetString("password"), arguments.ParsePassword, nil, true)
-	sett.Region = arguments.New("Region", viper.GetInt("region"), arguments.ParseUnsignedInt, nil, false)
-	sett.AdminName = arguments.New("Admin Name", viper.GetString("adminname"), nil, nil, false)
-	sett.AdminMail = arguments.New("Admin Mail", viper.GetString("adminmail"), arguments.ParseMail, nil, true)
-	sett.AdminPassword = arguments.New("Admin Password", viper.GetString("adminpassword"), arguments.ParsePassword, nil, true)
-	sett.MOTD = arguments.New("MOTD", viper.GetString("motd"), nil, nil, false)
-	sett.SpecimenType = arguments.New("Specimens Type", viper.GetString("specimentype"), arguments.ParseSpecimenType, arguments.FormatSpecimenType, false)
-	sett.Mutators = arguments.New("Mutators", viper.GetString("mutators"), nil, nil, false)
-	sett.ServerMutators = arguments.New("Server Mutators", viper.GetString("servermutators"), nil, nil, false)
-	sett.RedirectURL = arguments.New("Redirect URL", viper.GetString("redirecturl"), arguments.ParseURL, nil, false)
-	sett.Maplist = arguments.New("Maplist", viper.GetString("maplist"), nil, nil, false)
-	sett.EnableWebAdmin = arguments.New("Web Admin", viper.GetBool("webadmin"), nil, arguments.FormatBool, false)
-	sett.EnableMapVote = arguments.New("Map Voting", viper.GetBool("mapvote"), nil, arguments.FormatBool, false)
-	sett.MapVoteRepeatLimit = arguments.New("Map Vote Repeat Limit", viper.GetInt("mapvote-repeatlimit"), arguments.ParseUnsignedInt, nil, false)
-	sett.EnableAdminPause = arguments.New("Admin Pause", viper.GetBool("adminpause"), nil, arguments.FormatBool, false)
-	sett.DisableWeaponThrow = arguments.New("No Weapon Throw", viper.GetBool("noweaponthrow"), nil, arguments.FormatBool, false)
-	sett.DisableWeaponShake = arguments.New("No Weapon Shake", viper.GetBool("noweaponshake"), nil, arguments.FormatBool, false)
-	sett.EnableThirdPerson = arguments.New("Third Person View", viper.GetBool("thirdperson"), nil, arguments.FormatBool, false)
-	sett.EnableLowGore = arguments.New("Low Gore", viper.GetBool("lowgore"), nil, arguments.FormatBool, false)
-	sett.Uncap = arguments.New("Uncap Framerate", viper.GetBool("uncap"), nil, arguments.FormatBool, false)
-	sett.Unsecure = arguments.New("Unsecure (no VAC)", viper.GetBool("unsecure"), nil, arguments.FormatBool, false)
-	sett.NoSteam = arguments.New("Skip SteamCMD", viper.GetBool("nosteam"), nil, arguments.FormatBool, false)
-	sett.NoValidate = arguments.New("Files Validation", viper.GetBool("novalidate"), nil, arguments.FormatBool, false)
-	sett.AutoRestart = arguments.New("Server Auto Restart", viper.GetBool("autorestart"), nil, arguments.FormatBool, false)
-	sett.EnableMutLoader = arguments.New("Use MutLoader", viper.GetBool("mutloader"), nil, arguments.FormatBool, false)
-	sett.EnableKFPatcher = arguments.New("Use KFPatcher", viper.GetBool("kfpatcher"), nil, arguments.FormatBool, false)
-	sett.KFPHidePerks = arguments.New("KFP Hide Perks", viper.GetBool("hideperks"), nil, arguments.FormatBool, false)
-	sett.KFPDisableZedTime = arguments.New("KFP Disable ZED Time", viper.GetBool("nozedtime"), nil, arguments.FormatBool, false)
-	sett.KFPBuyEverywhere = arguments.New("KFP Buy Everywhere", viper.GetBool("buyeverywhere"), nil, arguments.FormatBool, false)
-	sett.KFPEnableAllTraders = arguments.New("KFP All Traders", viper.GetBool("alltraders"), nil, arguments.FormatBool, false)
-	sett.KFPAllTradersMessage = arguments.New("KFP All Traders Msg", viper.GetString("alltraders-message"), nil, nil, false)
-	sett.LogToFile = arguments.New("Log to File", viper.GetBool("log-to-file"), nil, arguments.FormatBool, false)
-	sett.LogLevel = arguments.New("Log Level", viper.GetString("log-level"), arguments.ParseLogLevel, nil, false)
-	sett.LogFile = arguments.New("Log File", viper.GetString("log-file"), nil, nil, false)
-	sett.LogFileFormat = arguments.New("Log File Format", viper.GetString("log-file-format"), arguments.ParseLogFileFormat, nil, false)
-	sett.LogMaxSize = arguments.New("Log Max Size (MB)", viper.GetInt("log-max-size"), arguments.ParsePositiveInt, nil, false)
-	sett.LogMaxBackups = arguments.New("Log Max Backups", viper.GetInt("log-max-backups"), arguments.ParsePositiveInt, nil, false)
-	sett.LogMaxAge = arguments.New("Log Max Age (days)", viper.GetInt("log-max-age"), arguments.ParsePositiveInt, nil, false)
-	sett.MaxRestarts = arguments.New("Max Restarts", viper.GetInt("max-restarts"), arguments.ParseUnsignedInt, nil, false)
-	sett.RestartDelay = arguments.New("Restart Delay (secs)", viper.GetDuration("restart-delay"), arguments.ParseDuration, nil, false)
-	sett.ShutdownTimeout = arguments.New("Shutdown Timeout (secs)", viper.GetDuration("shutdown-timeout"), arguments.ParseDuration, nil, false)
-	sett.KillTimeout = arguments.New("Kill Timeout (secs)", viper.GetDuration("kill-timeout"), arguments.ParseDuration, nil, false)
-	sett.SteamCMDRoot = arguments.New("SteamCMD Root", viper.GetString("steamcmd-root"), arguments.ParseExistingDir, nil, false)
-	sett.ServerInstallDir = arguments.New("Server Install Dir", viper.GetString("steamcmd-appinstalldir"), arguments.ParseExistingDir, nil, false)
+// parseSettings registers and parses the launcher settings.
+func parseSettings(sett *settings.Settings) error {
+	registerArguments(sett, viper.GetViper())
+	return sett.Parse()
+}
+
+// parseBaseSettings registers all the launcher settings but only parses the
+// ones needed by commands that don't touch the server installation.
+func parseBaseSettings(sett *settings.Settings) error {
+	registerArguments(sett, viper.GetViper())
+
+	for _, arg := range []arguments.ParsableArgument{
+		sett.ModsFile,
+		sett.ModsTrustStore,
+		sett.ModsRequireSignature,
+		sett.LogToFile,
+		sett.LogLevel,
+		sett.LogFile,
+		sett.LogFileFormat,
+		sett.LogMaxSize,
+		sett.LogMaxBackups,
+		sett.LogMaxAge,
+	} {
+		if err := arg.Parse(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setCommand records the subcommand to be run by the launcher.
+func setCommand(command *Command, name string, cmd *cobra.Command, args []string) {
+	*command = Command{
+		Name:  name,
+		Args:  args,
+		Flags: cmd.Flags(),
+	}
+}
+
+// registerArguments creates the launcher settings from the values of v.
+func registerArguments(sett *settings.Settings, v *viper.Viper) {
+	sett.LauncherConfigFile = arguments.New("Launcher Config File", v.GetString("launcher-config"), nil, nil, false)
+	sett.ConfigFile = arguments.New("Config File", v.GetString("config"), nil, nil, false)
+	sett.ConfigBackups = arguments.New("Config Backups", v.GetInt("config-backups"), arguments.ParseUnsignedInt, nil, false)
+	sett.IniOverrides = arguments.New("Config Overrides", iniOverrides(v), arguments.ParseIniOverrides, arguments.FormatIniOverrides, false)
+	sett.ModsFile = arguments.New("Mods File", v.GetString("mods"), nil, nil, false)
+	sett.ModsTrustStore = arguments.New("Mods Trust Store", v.GetString("mods-trust-store"), nil, nil, false)
+	sett.ModsRequireSignature = arguments.New("Mods Signature", v.GetBool("mods-require-signature"), nil, arguments.FormatRequired, false)
+	sett.ServerName = arguments.New("Server Name", v.GetString("servername"), arguments.ParseNonEmptyStr, nil, false)
+	sett.ShortName = arguments.New("Short Name", v.GetString("shortname"), arguments.ParseNonEmptyStr, nil, false)
+	sett.IP = arguments.New("IP Address", v.GetString("ip"), arguments.ParseOptionalIP, nil, false)
+	sett.GamePort = arguments.New("Game Port", v.GetInt("port"), arguments.ParsePort, nil, false)
+	sett.WebAdminPort = arguments.New("WebAdmin Port", v.GetInt("webadminport"), arguments.ParsePort, nil, false)
+	sett.WebAdminIP = arguments.New("WebAdmin IP Address", v.GetString("webadmin-ip"), arguments.ParseOptionalIP, nil, false)
+	sett.GameSpyPort = arguments.New("GameSpy Port", v.GetInt("gamespyport"), arguments.ParsePort, nil, false)
+	sett.GameMode = arguments.New("Game Mode", v.GetString("gamemode"), arguments.ParseGameMode, arguments.FormatGameMode, false)
+	sett.StartupMap = arguments.New("Startup Map", v.GetString("map"), arguments.ParseNonEmptyStr, nil, false)
+	sett.GameDifficulty = arguments.New("Game Difficulty", settings.DefaultInternalGameDifficulty, arguments.ParseGameDifficulty(v.GetString("difficulty")), arguments.FormatGameDifficulty, false)
+	sett.GameLength = arguments.New("Game Length", settings.DefaultInternalGameLength, arguments.ParseGameLength(v.GetString("length")), arguments.FormatGameLength, false)
+	sett.FriendlyFire = arguments.New("Friendly Fire Rate", v.GetFloat64("friendlyfire"), arguments.ParseFriendlyFireRate, arguments.FormatFriendlyFireRate, false)
+	sett.StartingCash = arguments.New("Starting Cash", v.GetInt("startingcash"), arguments.ParseUnsignedInt, nil, false)
+	sett.MinRespawnCash = arguments.New("Min Respawn Cash", v.GetInt("minrespawncash"), arguments.ParseUnsignedInt, nil, false)
+	sett.TimeBetweenWaves = arguments.New("Time Between Waves", v.GetInt("timebetweenwaves"), nil, nil, false)
+	sett.DisableWaveFunding = arguments.New("No Wave Funding", v.GetBool("nowavefunding"), nil, arguments.FormatBool, false)
+	sett.MaxZombiesOnce = arguments.New("Max Zombies", v.GetInt("maxzombies"), nil, nil, false)
+	sett.DisableEndGameBoss = arguments.New("No End Game Boss", v.GetBool("noendgameboss"), nil, arguments.FormatBool, false)
+	sett.CustomWavesFile = arguments.New("Custom Waves File", v.GetString("waves"), nil, nil, false)
+	sett.MaxPlayers = arguments.New("Max Players", v.GetInt("maxplayers"), nil, nil, false)
+	sett.MaxSpectators = arguments.New("Max Spectators", v.GetInt("maxspectators"), nil, nil, false)
+	sett.Password = arguments.New("Game Password", v.GetString("password"), arguments.ParsePassword, nil, true)
+	sett.Region = arguments.New("Region", v.GetInt("region"), arguments.ParseUnsignedInt, nil, false)
+	sett.AdminName = arguments.New("Admin Name", v.GetString("adminname"), nil, nil, false)
+	sett.AdminMail = arguments.New("Admin Mail", v.GetString("adminmail"), arguments.ParseMail, nil, true)
+	sett.AdminPassword = arguments.New("Admin Password", v.GetString("adminpassword"), arguments.ParsePassword, nil, true)
+	sett.MOTD = arguments.New("MOTD", v.GetString("motd"), nil, nil, false)
+	sett.SpecimenType = arguments.New("Specimens Type", v.GetString("specimentype"), arguments.ParseSpecimenType, arguments.FormatSpecimenType, false)
+	sett.Mutators = arguments.New("Mutators", v.GetString("mutators"), nil, nil, false)
+	sett.ServerMutators = arguments.New("Server Mutators", v.GetString("servermutators"), nil, nil, false)
+	sett.RedirectURL = arguments.New("Redirect URL", v.GetString("redirecturl"), arguments.ParseURL, nil, false)
+	sett.Maplist = arguments.New("Maplist", v.GetString("maplist"), nil, nil, false)
+	sett.EnableWebAdmin = arguments.New("Web Admin", v.GetBool("webadmin"), nil, arguments.FormatBool, false)
+	sett.EnableMapVote = arguments.New("Map Voting", v.GetBool("mapvote"), nil, arguments.FormatBool, false)
+	sett.MapVoteRepeatLimit = arguments.New("Map Vote Repeat Limit", v.GetInt("mapvote-repeatlimit"), arguments.ParseUnsignedInt, nil, false)
+	sett.EnableAdminPause = arguments.New("Admin Pause", v.GetBool("adminpause"), nil, arguments.FormatBool, false)
+	sett.DisableWeaponThrow = arguments.New("No Weapon Throw", v.GetBool("noweaponthrow"), nil, arguments.FormatBool, false)
+	sett.DisableWeaponShake = arguments.New("No Weapon Shake", v.GetBool("noweaponshake"), nil, arguments.FormatBool, false)
+	sett.EnableThirdPerson = arguments.New("Third Person View", v.GetBool("thirdperson"), nil, arguments.FormatBool, false)
+	sett.EnableLowGore = arguments.New("Low Gore", v.GetBool("lowgore"), nil, arguments.FormatBool, false)
+	sett.Uncap = arguments.New("Uncap Framerate", v.GetBool("uncap"), nil, arguments.FormatBool, false)
+	sett.Unsecure = arguments.New("Unsecure (no VAC)", v.GetBool("unsecure"), nil, arguments.FormatBool, false)
+	sett.NoSteam = arguments.New("Skip SteamCMD", v.GetBool("nosteam"), nil, arguments.FormatBool, false)
+	sett.NoValidate = arguments.New("Files Validation", v.GetBool("novalidate"), nil, arguments.FormatBool, false)
+	sett.AutoRestart = arguments.New("Server Auto Restart", v.GetBool("autorestart"), nil, arguments.FormatBool, false)
+	sett.EnableMutLoader = arguments.New("Use MutLoader", v.GetBool("mutloader"), nil, arguments.FormatBool, false)
+	sett.EnableKFPatcher = arguments.New("Use KFPatcher", v.GetBool("kfpatcher"), nil, arguments.FormatBool, false)
+	sett.KFPHidePerks = arguments.New("KFP Hide Perks", v.GetBool("hideperks"), nil, arguments.FormatBool, false)
+	sett.KFPDisableZedTime = arguments.New("KFP Disable ZED Time", v.GetBool("nozedtime"), nil, arguments.FormatBool, false)
+	sett.KFPBuyEverywhere = arguments.New("KFP Buy Everywhere", v.GetBool("buyeverywhere"), nil, arguments.FormatBool, false)
+	sett.KFPEnableAllTraders = arguments.New("KFP All Traders", v.GetBool("alltraders"), nil, arguments.FormatBool, false)
+	sett.KFPAllTradersMessage = arguments.New("KFP All Traders Msg", v.GetString("alltraders-message"), nil, nil, false)
+	sett.LogToFile = arguments.New("Log to File", v.GetBool("log-to-file"), nil, arguments.FormatBool, false)
+	sett.LogLevel = arguments.New("Log Level", v.GetString("log-level"), arguments.ParseLogLevel, nil, false)
+	sett.LogFile = arguments.New("Log File", v.GetString("log-file"), nil, nil, false)
+	sett.LogFileFormat = arguments.New("Log File Format", v.GetString("log-file-format"), arguments.ParseLogFileFormat, nil, false)
+	sett.LogMaxSize = arguments.New("Log Max Size (MB)", v.GetInt("log-max-size"), arguments.ParsePositiveInt, nil, false)
+	sett.LogMaxBackups = arguments.New("Log Max Backups", v.GetInt("log-max-backups"), arguments.ParsePositiveInt, nil, false)
+	sett.LogMaxAge = arguments.New("Log Max Age (days)", v.GetInt("log-max-age"), arguments.ParsePositiveInt, nil, false)
+	sett.MaxRestarts = arguments.New("Max Restarts", v.GetInt("max-restarts"), arguments.ParseUnsignedInt, nil, false)
+	sett.RestartDelay = arguments.New("Restart Delay (secs)", v.GetDuration("restart-delay"), arguments.ParseDuration, nil, false)
+	sett.ShutdownTimeout = arguments.New("Shutdown Timeout (secs)", v.GetDuration("shutdown-timeout"), arguments.ParseDuration, nil, false)
+	sett.KillTimeout = arguments.New("Kill Timeout (secs)", v.GetDuration("kill-timeout"), arguments.ParseDuration, nil, false)
+	sett.ShutdownWarnings = arguments.New("Shutdown Warnings", v.GetString("shutdown-warnings"), arguments.ParseDurationList, nil, false)
+	sett.ShutdownWaitWave = arguments.New("Shutdown Wait Wave", v.GetBool("shutdown-wait-wave"), nil, arguments.FormatBool, false)
+	sett.ShutdownGrace = arguments.New("Shutdown Grace (secs)", v.GetDuration("shutdown-grace"), arguments.ParseDuration, nil, false)
+	sett.ReloadWait = arguments.New("Reload Wait (secs)", v.GetDuration("reload-wait"), arguments.ParseDuration, nil, false)
+	sett.APIAddr = arguments.New("API Address", v.GetString("api-addr"), arguments.ParseOptionalAddress, nil, false)
+	sett.APIToken = arguments.New("API Token", v.GetString("api-token"), nil, nil, true)
+	tasks, err := scheduleTasks(v)
+	sett.Schedule = arguments.New("Schedule", tasks, arguments.ParseSchedule(err), arguments.FormatSchedule, false)
+	sett.CrashReports = arguments.New("Crash Reports", v.GetInt("crash-reports"), arguments.ParseUnsignedInt, nil, false)
+	sett.CrashCoreDump = arguments.New("Crash Core Dump", v.GetBool("crash-core"), nil, arguments.FormatBool, false)
+	sett.HangTimeout = arguments.New("Hang Timeout (secs)", v.GetDuration("hang-timeout"), arguments.ParseDuration, nil, false)
+	sett.IdleRestart = arguments.New("Idle Restart (secs)", v.GetDuration("idle-restart"), arguments.ParseDuration, nil, false)
+	sett.IdleRestartUptime = arguments.New("Idle Restart Uptime (secs)", v.GetDuration("idle-restart-uptime"), arguments.ParseDuration, nil, false)
+	sett.IdleReset = arguments.New("Idle Reset (secs)", v.GetDuration("idle-reset"), arguments.ParseDuration, nil, false)
+	sett.UpdateCheck = arguments.New("Update Check (secs)", v.GetDuration("update-check"), arguments.ParseDuration, nil, false)
+	sett.UsageInterval = arguments.New("Usage Interval (secs)", v.GetDuration("usage-interval"), arguments.ParseDuration, nil, false)
+	rules, err := usageRules(v)
+	sett.UsageRules = arguments.New("Usage Rules", rules, arguments.ParseUsageRules(err), arguments.FormatUsageRules, false)
+	sett.Nice = arguments.New("Nice", v.GetInt("nice"), nil, nil, false)
+	sett.CPUAffinity = arguments.New("CPU Affinity", v.GetString("cpu-affinity"), arguments.ParseCPUList, nil, false)
+	sett.LimitNoFile = arguments.New("Limit Open Files", v.GetInt("limit-nofile"), arguments.ParseUnsignedInt, nil, false)
+	sett.LimitAS = arguments.New("Limit Address Space (MB)", v.GetInt("limit-as"), arguments.ParseUnsignedInt, nil, false)
+	sett.OOMScoreAdj = arguments.New("OOM Score Adjustment", v.GetInt("oom-score-adj"), nil, nil, false)
+	sett.RunAs = arguments.New("Run As", v.GetString("run-as"), arguments.ParseRunAs, nil, false)
+	sett.EnvAllow = arguments.New("Env Allow", v.GetString("env-allow"), nil, nil, false)
+	sett.NoNewPrivs = arguments.New("No New Privileges", v.GetBool("no-new-privs"), nil, arguments.FormatBool, false)
+	sett.Subreaper = arguments.New("Subreaper", v.GetBool("subreaper"), nil, arguments.FormatBool, false)
+	sett.SteamCMDRoot = arguments.New("SteamCMD Root", v.GetString("steamcmd-root"), arguments.ParseExistingDir, nil, false)
+	sett.ServerInstallDir = arguments.New("Server Install Dir", v.GetString("steamcmd-appinstalldir"), arguments.ParseExistingDir, nil, false)
 
 	sett.MaxPlayers.SetParserFunction(arguments.ParseIntRange(sett.MaxPlayers, 0, 32))
 	sett.MaxSpectators.SetParserFunction(arguments.ParseIntRange(sett.MaxSpectators, 0, 32))
+	sett.TimeBetweenWaves.SetParserFunction(arguments.ParseIntRange(sett.TimeBetweenWaves, 5, 600))
+	sett.MaxZombiesOnce.SetParserFunction(arguments.ParseIntRange(sett.MaxZombiesOnce, 1, 128))
+	sett.Nice.SetParserFunction(arguments.ParseIntRange(sett.Nice, -20, 19))
+	sett.OOMScoreAdj.SetParserFunction(arguments.ParseIntRange(sett.OOMScoreAdj, -1000, 1000))
+
+	markExplicitArguments(sett, v.IsSet)
+}
+
+// markExplicitArguments records which settings written to the server
+// configuration files are given, isSet reporting whether a key is set. The
+// others are left as-is in the files, so the values tuned or imported in the
+// files aren't overwritten.
+func markExplicitArguments(sett *settings.Settings, isSet func(key string) bool) {
+	for key, arg := range map[string]interface{ SetExplicit(bool) }{
+		"servername":          sett.ServerName,
+		"shortname":           sett.ShortName,
+		"difficulty":          sett.GameDifficulty,
+		"length":              sett.GameLength,
+		"friendlyfire":        sett.FriendlyFire,
+		"maxspectators":       sett.MaxSpectators,
+		"password":            sett.Password,
+		"region":              sett.Region,
+		"adminname":           sett.AdminName,
+		"adminmail":           sett.AdminMail,
+		"adminpassword":       sett.AdminPassword,
+		"motd":                sett.MOTD,
+		"specimentype":        sett.SpecimenType,
+		"redirecturl":         sett.RedirectURL,
+		"webadmin":            sett.EnableWebAdmin,
+		"mapvote":             sett.EnableMapVote,
+		"mapvote-repeatlimit": sett.MapVoteRepeatLimit,
+		"servermutators":      sett.ServerMutators,
+		"maplist":             sett.Maplist,
+		"uncap":               sett.Uncap,
+		"hideperks":           sett.KFPHidePerks,
+		"nozedtime":           sett.KFPDisableZedTime,
+		"buyeverywhere":       sett.KFPBuyEverywhere,
+		"alltraders":          sett.KFPEnableAllTraders,
+		"alltraders-message":  sett.KFPAllTradersMessage,
+		"startingcash":        sett.StartingCash,
+		"minrespawncash":      sett.MinRespawnCash,
+		"timebetweenwaves":    sett.TimeBetweenWaves,
+		"nowavefunding":       sett.DisableWaveFunding,
+		"maxzombies":          sett.MaxZombiesOnce,
+		"noendgameboss":       sett.DisableEndGameBoss,
+	} {
+		arg.SetExplicit(isSet(key))
+	}
 }
@@ -10,6 +10,7 @@ type ParsableArgument interface {
 	Parse() error
 	Name() string
 	FormattedValue() string
+	AnyValue() any
 	IsSensitive() bool
 }
 
@@ -25,6 +26,7 @@ type Argument[T any] struct {
 	parseFunc      ParseFunction[T]  // Parser function or raw value
 	formatFunc     FormatFunction[T] // Format function or string
 	sensitive      bool              // True if the argument contains sensitive data
+	explicit       bool              // True if the value was given rather than defaulted
 }
 
 func New[T any](name string, rawValue T, parseFunc ParseFunction[T], formatFunc FormatFunction[T], sensitive bool) *Argument[T] {
@@ -59,10 +61,24 @@ func (a *Argument[T]) FormattedValue() string {
 	return a.formattedValue
 }
 
+func (a *Argument[T]) AnyValue() any {
+	return a.parsedValue
+}
+
 func (a *Argument[T]) IsSensitive() bool {
 	return a.sensitive
 }
 
+// IsExplicit reports whether the value was given by a flag, an environment
+// variable or the launcher config file.
+func (a *Argument[T]) IsExplicit() bool {
+	return a.explicit
+}
+
+func (a *Argument[T]) SetExplicit(explicit bool) {
+	a.explicit = explicit
+}
+
 func (a *Argument[T]) String() string {
 	return fmt.Sprintf("%v", a.parsedValue)
 }
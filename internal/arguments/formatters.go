@@ -3,6 +3,9 @@ package arguments
 import (
 	"fmt"
 	"math"
+
+	"github.com/K4rian/kfdsl/internal/cron"
+	"github.com/K4rian/kfdsl/internal/monitor"
 )
 
 func FormatBool(a *Argument[bool]) string {
@@ -59,3 +62,34 @@ func FormatSpecimenType(a *Argument[string]) string {
 	}
 	return specimenTypes[a.Value()]
 }
+
+func FormatRequired(a *Argument[bool]) string {
+	if a.Value() {
+		return "Required"
+	}
+	return "Optional"
+}
+
+func FormatIniOverrides(a *Argument[[]string]) string {
+	count := len(a.Value())
+	if count == 0 {
+		return "None"
+	}
+	return fmt.Sprintf("%d key(s)", count)
+}
+
+func FormatSchedule(a *Argument[cron.Tasks]) string {
+	count := len(a.Value())
+	if count == 0 {
+		return "None"
+	}
+	return fmt.Sprintf("%d task(s)", count)
+}
+
+func FormatUsageRules(a *Argument[monitor.Rules]) string {
+	count := len(a.Value())
+	if count == 0 {
+		return "None"
+	}
+	return fmt.Sprintf("%d rule(s)", count)
+}
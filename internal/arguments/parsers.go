@@ -7,10 +7,19 @@ import (
 	"net/url"
 	"os"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/K4rian/kfdsl/internal/config/ini"
+	"github.com/K4rian/kfdsl/internal/cron"
+	"github.com/K4rian/kfdsl/internal/monitor"
+	"github.com/K4rian/kfdsl/internal/utils"
 )
 
+// maxCPUs is the number of CPUs an affinity mask can hold (CPU_SETSIZE).
+const maxCPUs = 1024
+
 func ParseNonEmptyStr(a *Argument[string]) (string, error) {
 	raw := a.RawValue()
 	val := strings.TrimSpace(strings.ToLower(raw))
@@ -111,9 +120,118 @@ func ParseIP(a *Argument[string]) (string, error) {
 	if parsedIP == nil {
 		return "", fmt.Errorf("invalid IP address: '%s'", raw)
 	}
+	// UE2 only supports IPv4
+	if parsedIP.To4() == nil {
+		return "", fmt.Errorf("invalid IP address: '%s', must be an IPv4 address", raw)
+	}
+	return val, nil
+}
+
+// ParseOptionalIP is like ParseIP but accepts an empty value.
+func ParseOptionalIP(a *Argument[string]) (string, error) {
+	if strings.TrimSpace(a.RawValue()) == "" {
+		return "", nil
+	}
+	return ParseIP(a)
+}
+
+// ParseOptionalAddress parses a host:port address to listen on, such as
+// 127.0.0.1:7780 or :7780. An empty value is accepted.
+func ParseOptionalAddress(a *Argument[string]) (string, error) {
+	raw := a.RawValue()
+	val := strings.TrimSpace(raw)
+	if val == "" {
+		return "", nil
+	}
+
+	_, port, err := net.SplitHostPort(val)
+	if err != nil {
+		return "", fmt.Errorf("invalid address: '%s', expected host:port", raw)
+	}
+	if p, err := strconv.Atoi(port); err != nil || p < 1 || p > 65535 {
+		return "", fmt.Errorf("invalid address: '%s', the port must be between 1 and 65535", raw)
+	}
+	return val, nil
+}
+
+func ParseCPUList(a *Argument[string]) (string, error) {
+	raw := a.RawValue()
+	cpus, err := utils.ParseCPUList(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s: %v", a.Name(), err)
+	}
+	for _, cpu := range cpus {
+		if cpu >= maxCPUs {
+			return "", fmt.Errorf("invalid %s: CPU %d is out of range", a.Name(), cpu)
+		}
+	}
+	return strings.TrimSpace(raw), nil
+}
+
+func ParseRunAs(a *Argument[string]) (string, error) {
+	raw := a.RawValue()
+	val := strings.TrimSpace(raw)
+	if val == "" {
+		return "", nil
+	}
+	if _, _, err := utils.LookupCredential(val); err != nil {
+		return "", fmt.Errorf("invalid %s: %v", a.Name(), err)
+	}
 	return val, nil
 }
 
+func ParseDurationList(a *Argument[string]) (string, error) {
+	raw := a.RawValue()
+	if _, err := utils.ParseDurationList(raw); err != nil {
+		return "", fmt.Errorf("invalid %s: %v", a.Name(), err)
+	}
+	return strings.TrimSpace(raw), nil
+}
+
+func ParseIniOverrides(a *Argument[[]string]) ([]string, error) {
+	raw := a.RawValue()
+	for _, spec := range raw {
+		if _, err := ini.ParseOverride(spec); err != nil {
+			return nil, err
+		}
+	}
+	return raw, nil
+}
+
+// ParseSchedule validates the tasks, decodeErr being the error raised while
+// decoding them from the launcher config file.
+func ParseSchedule(decodeErr error) func(a *Argument[cron.Tasks]) (cron.Tasks, error) {
+	return func(a *Argument[cron.Tasks]) (cron.Tasks, error) {
+		if decodeErr != nil {
+			return nil, fmt.Errorf("invalid %s: %v", a.Name(), decodeErr)
+		}
+		raw := a.RawValue()
+		for _, task := range raw {
+			if err := task.Validate(); err != nil {
+				return nil, fmt.Errorf("invalid %s: %v", a.Name(), err)
+			}
+		}
+		return raw, nil
+	}
+}
+
+// ParseUsageRules validates the rules, decodeErr being the error raised
+// while decoding them from the launcher config file.
+func ParseUsageRules(decodeErr error) func(a *Argument[monitor.Rules]) (monitor.Rules, error) {
+	return func(a *Argument[monitor.Rules]) (monitor.Rules, error) {
+		if decodeErr != nil {
+			return nil, fmt.Errorf("invalid %s: %v", a.Name(), decodeErr)
+		}
+		raw := a.RawValue()
+		for _, rule := range raw {
+			if err := rule.Validate(); err != nil {
+				return nil, fmt.Errorf("invalid %s: %v", a.Name(), err)
+			}
+		}
+		return raw, nil
+	}
+}
+
 func ParseExistingDir(a *Argument[string]) (string, error) {
 	raw := a.RawValue()
 	val := strings.TrimSpace(raw)
@@ -0,0 +1,202 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/K4rian/kfdsl/internal/log"
+	"github.com/K4rian/kfdsl/internal/utils"
+)
+
+const backupTimeFormat = "20060102-150405.000"
+
+// ConfigBackup is a saved version of a configuration file.
+// Its ID is made of the backup date and the beginning of the content hash.
+type ConfigBackup struct {
+	ID   string    `json:"id"`
+	File string    `json:"file"`
+	Date time.Time `json:"date"`
+	Hash string    `json:"hash"`
+	Size int64     `json:"size"`
+	Path string    `json:"-"`
+}
+
+// BackupsDir returns the directory holding the configuration files backups.
+func BackupsDir(dir string) string {
+	return filepath.Join(dir, ".kfdsl", "config")
+}
+
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// BackupFile saves the current content of filePath in backupsDir and keeps
+// the last retention backups of the file. Nothing is saved if the file is
+// missing or if its content is identical to the last backup.
+func BackupFile(backupsDir string, filePath string, retention int) (*ConfigBackup, error) {
+	if retention <= 0 {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	fileName := filepath.Base(filePath)
+	hash := contentHash(data)
+
+	backups, err := ListBackups(backupsDir, fileName)
+	if err != nil {
+		return nil, err
+	}
+	if len(backups) > 0 && backups[0].Hash == hash {
+		log.Logger.Debug("Configuration file unchanged since the last backup",
+			"function", "BackupFile", "file", filePath, "backup", backups[0].ID)
+		return nil, nil
+	}
+
+	now := time.Now().UTC()
+	backup := &ConfigBackup{
+		ID:   fmt.Sprintf("%s-%s", now.Format(backupTimeFormat), hash[:8]),
+		File: fileName,
+		Date: now,
+		Hash: hash,
+		Size: int64(len(data)),
+	}
+
+	fileDir := filepath.Join(backupsDir, fileName)
+	if _, err := utils.CreateDirIfNotExists(fileDir); err != nil {
+		return nil, err
+	}
+
+	backup.Path = filepath.Join(fileDir, backup.ID)
+	if err := os.WriteFile(backup.Path, data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write backup %s: %w", backup.Path, err)
+	}
+
+	// Remove the oldest backups
+	backups = append([]ConfigBackup{*backup}, backups...)
+	for _, old := range backups[min(retention, len(backups)):] {
+		if err := os.Remove(old.Path); err != nil {
+			log.Logger.Warn("Failed to remove old configuration backup",
+				"function", "BackupFile", "backup", old.Path, "error", err)
+		}
+	}
+	return backup, nil
+}
+
+// ListBackups returns the backups of fileName, or of all the files if empty,
+// the most recent first.
+func ListBackups(backupsDir string, fileName string) ([]ConfigBackup, error) {
+	var fileNames []string
+	if fileName != "" {
+		fileNames = []string{fileName}
+	} else {
+		entries, err := os.ReadDir(backupsDir)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		for _, e := range entries {
+			if e.IsDir() {
+				fileNames = append(fileNames, e.Name())
+			}
+		}
+	}
+
+	var backups []ConfigBackup
+	for _, name := range fileNames {
+		fileDir := filepath.Join(backupsDir, name)
+		entries, err := os.ReadDir(fileDir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		for _, e := range entries {
+			backup, err := readBackup(fileDir, name, e.Name())
+			if err != nil {
+				log.Logger.Debug("Ignoring invalid configuration backup",
+					"function", "ListBackups", "backup", filepath.Join(fileDir, e.Name()), "error", err)
+				continue
+			}
+			backups = append(backups, *backup)
+		}
+	}
+
+	sort.SliceStable(backups, func(i, j int) bool {
+		return backups[i].ID > backups[j].ID
+	})
+	return backups, nil
+}
+
+func readBackup(fileDir string, fileName string, id string) (*ConfigBackup, error) {
+	if len(id) <= len(backupTimeFormat) {
+		return nil, fmt.Errorf("invalid backup ID: %s", id)
+	}
+	date, err := time.Parse(backupTimeFormat, id[:len(backupTimeFormat)])
+	if err != nil {
+		return nil, fmt.Errorf("invalid backup ID %s: %w", id, err)
+	}
+
+	path := filepath.Join(fileDir, id)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ConfigBackup{
+		ID:   id,
+		File: fileName,
+		Date: date,
+		Hash: contentHash(data),
+		Size: int64(len(data)),
+		Path: path,
+	}, nil
+}
+
+// RestoreBackup restores the backup id into dir. Unless backups are
+// disabled, the current file is backed up first so a restore can be reverted.
+func RestoreBackup(backupsDir string, id string, dir string, retention int) (*ConfigBackup, error) {
+	backups, err := ListBackups(backupsDir, "")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, backup := range backups {
+		if backup.ID != id {
+			continue
+		}
+
+		filePath := filepath.Join(dir, backup.File)
+		data, err := os.ReadFile(backup.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := BackupFile(backupsDir, filePath, retention); err != nil {
+			return nil, fmt.Errorf("failed to back up %s: %w", filePath, err)
+		}
+
+		tempFilePath := filePath + ".tmp"
+		if err := os.WriteFile(tempFilePath, data, 0644); err != nil {
+			return nil, err
+		}
+		if err := os.Rename(tempFilePath, filePath); err != nil {
+			return nil, err
+		}
+		return &backup, nil
+	}
+	return nil, fmt.Errorf("backup not found: %s", id)
+}
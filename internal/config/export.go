@@ -0,0 +1,283 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+
+	"github.com/K4rian/kfdsl/internal/settings"
+)
+
+const configDocumentVersion = 1
+
+// ConfigDocument holds the effective configuration of the server.
+// Missing sections and values are left untouched on import.
+type ConfigDocument struct {
+	Version        int                `json:"version" yaml:"version"`
+	Launcher       map[string]any     `json:"launcher,omitempty" yaml:"launcher,omitempty"` // Informative only, not imported
+	Server         *ServerSettings    `json:"server,omitempty" yaml:"server,omitempty"`
+	KFPatcher      *KFPatcherSettings `json:"kfpatcher,omitempty" yaml:"kfpatcher,omitempty"`
+	Maplist        []string           `json:"maplist" yaml:"maplist"`
+	ServerMutators []string           `json:"server_mutators" yaml:"server_mutators"`
+}
+
+// ServerSettings holds the typed values of the server configuration file.
+type ServerSettings struct {
+	ServerName            *string  `json:"server_name,omitempty" yaml:"server_name,omitempty"`
+	ShortName             *string  `json:"short_name,omitempty" yaml:"short_name,omitempty"`
+	GamePort              *int     `json:"game_port,omitempty" yaml:"game_port,omitempty"`
+	WebAdminPort          *int     `json:"webadmin_port,omitempty" yaml:"webadmin_port,omitempty"`
+	WebAdminAddress       *string  `json:"webadmin_address,omitempty" yaml:"webadmin_address,omitempty"`
+	GameSpyPort           *int     `json:"gamespy_port,omitempty" yaml:"gamespy_port,omitempty"`
+	GameDifficulty        *int     `json:"game_difficulty,omitempty" yaml:"game_difficulty,omitempty"`
+	GameLength            *int     `json:"game_length,omitempty" yaml:"game_length,omitempty"`
+	FriendlyFireRate      *float64 `json:"friendly_fire_rate,omitempty" yaml:"friendly_fire_rate,omitempty"`
+	StartingCash          *int     `json:"starting_cash,omitempty" yaml:"starting_cash,omitempty"`
+	MinRespawnCash        *int     `json:"min_respawn_cash,omitempty" yaml:"min_respawn_cash,omitempty"`
+	TimeBetweenWaves      *int     `json:"time_between_waves,omitempty" yaml:"time_between_waves,omitempty"`
+	WaveFunding           *bool    `json:"wave_funding,omitempty" yaml:"wave_funding,omitempty"`
+	MaxZombiesOnce        *int     `json:"max_zombies_once,omitempty" yaml:"max_zombies_once,omitempty"`
+	EndGameBoss           *bool    `json:"end_game_boss,omitempty" yaml:"end_game_boss,omitempty"`
+	MaxPlayers            *int     `json:"max_players,omitempty" yaml:"max_players,omitempty"`
+	MaxSpectators         *int     `json:"max_spectators,omitempty" yaml:"max_spectators,omitempty"`
+	Password              *string  `json:"password,omitempty" yaml:"password,omitempty"`
+	Region                *int     `json:"region,omitempty" yaml:"region,omitempty"`
+	AdminName             *string  `json:"admin_name,omitempty" yaml:"admin_name,omitempty"`
+	AdminMail             *string  `json:"admin_mail,omitempty" yaml:"admin_mail,omitempty"`
+	AdminPassword         *string  `json:"admin_password,omitempty" yaml:"admin_password,omitempty"`
+	MOTD                  *string  `json:"motd,omitempty" yaml:"motd,omitempty"`
+	SpecimenType          *string  `json:"specimen_type,omitempty" yaml:"specimen_type,omitempty"`
+	RedirectURL           *string  `json:"redirect_url,omitempty" yaml:"redirect_url,omitempty"`
+	WebAdmin              *bool    `json:"webadmin,omitempty" yaml:"webadmin,omitempty"`
+	MapVote               *bool    `json:"mapvote,omitempty" yaml:"mapvote,omitempty"`
+	MapVoteRepeatLimit    *int     `json:"mapvote_repeat_limit,omitempty" yaml:"mapvote_repeat_limit,omitempty"`
+	AdminPause            *bool    `json:"admin_pause,omitempty" yaml:"admin_pause,omitempty"`
+	WeaponThrowing        *bool    `json:"weapon_throwing,omitempty" yaml:"weapon_throwing,omitempty"`
+	WeaponShakeEffect     *bool    `json:"weapon_shake_effect,omitempty" yaml:"weapon_shake_effect,omitempty"`
+	ThirdPerson           *bool    `json:"third_person,omitempty" yaml:"third_person,omitempty"`
+	LowGore               *bool    `json:"low_gore,omitempty" yaml:"low_gore,omitempty"`
+	MaxInternetClientRate *int     `json:"max_internet_client_rate,omitempty" yaml:"max_internet_client_rate,omitempty"`
+}
+
+// KFPatcherSettings holds the typed values of the KFPatcher configuration file.
+type KFPatcherSettings struct {
+	ShowPerks         *bool   `json:"show_perks,omitempty" yaml:"show_perks,omitempty"`
+	ZEDTime           *bool   `json:"zed_time,omitempty" yaml:"zed_time,omitempty"`
+	AllTradersOpen    *bool   `json:"all_traders_open,omitempty" yaml:"all_traders_open,omitempty"`
+	AllTradersMessage *string `json:"all_traders_message,omitempty" yaml:"all_traders_message,omitempty"`
+	BuyEverywhere     *bool   `json:"buy_everywhere,omitempty" yaml:"buy_everywhere,omitempty"`
+}
+
+// valueSetter applies a single value through a typed setter.
+type valueSetter struct {
+	name  string
+	apply func() (bool, error)
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+// setValue calls set when v is defined and differs from the current value.
+// Redacted values are skipped so an export can be imported back as-is.
+func setValue[T comparable](name string, v *T, get func() T, set func(T) bool) valueSetter {
+	return valueSetter{name: name, apply: func() (bool, error) {
+		if v == nil || any(*v) == any(settings.RedactedValue) || get() == *v {
+			return false, nil
+		}
+		if !set(*v) {
+			return false, fmt.Errorf("[%s]: failed to set the new value: %v", name, *v)
+		}
+		return true, nil
+	}}
+}
+
+func applyValues(setters []valueSetter) (int, error) {
+	changed := 0
+	for _, s := range setters {
+		ok, err := s.apply()
+		if err != nil {
+			return changed, err
+		}
+		if ok {
+			changed++
+		}
+	}
+	return changed, nil
+}
+
+func redact(v string, secrets bool) string {
+	if !secrets && v != "" {
+		return settings.RedactedValue
+	}
+	return v
+}
+
+// ExportServerSettings reads the typed values of iniFile.
+// The passwords and admin mail are redacted unless secrets is true.
+func ExportServerSettings(iniFile ServerIniFile, secrets bool) *ServerSettings {
+	return &ServerSettings{
+		ServerName:            ptr(iniFile.GetServerName()),
+		ShortName:             ptr(iniFile.GetShortName()),
+		GamePort:              ptr(iniFile.GetGamePort()),
+		WebAdminPort:          ptr(iniFile.GetWebAdminPort()),
+		WebAdminAddress:       ptr(iniFile.GetWebAdminAddress()),
+		GameSpyPort:           ptr(iniFile.GetGameSpyPort()),
+		GameDifficulty:        ptr(iniFile.GetGameDifficulty()),
+		GameLength:            ptr(iniFile.GetGameLength()),
+		FriendlyFireRate:      ptr(iniFile.GetFriendlyFireRate()),
+		StartingCash:          ptr(iniFile.GetStartingCash()),
+		MinRespawnCash:        ptr(iniFile.GetMinRespawnCash()),
+		TimeBetweenWaves:      ptr(iniFile.GetTimeBetweenWaves()),
+		WaveFunding:           ptr(iniFile.IsWaveFundingEnabled()),
+		MaxZombiesOnce:        ptr(iniFile.GetMaxZombiesOnce()),
+		EndGameBoss:           ptr(iniFile.IsEndGameBossEnabled()),
+		MaxPlayers:            ptr(iniFile.GetMaxPlayers()),
+		MaxSpectators:         ptr(iniFile.GetMaxSpectators()),
+		Password:              ptr(redact(iniFile.GetPassword(), secrets)),
+		Region:                ptr(iniFile.GetRegion()),
+		AdminName:             ptr(iniFile.GetAdminName()),
+		AdminMail:             ptr(redact(iniFile.GetAdminMail(), secrets)),
+		AdminPassword:         ptr(redact(iniFile.GetAdminPassword(), secrets)),
+		MOTD:                  ptr(iniFile.GetMOTD()),
+		SpecimenType:          ptr(iniFile.GetSpecimenType()),
+		RedirectURL:           ptr(iniFile.GetRedirectURL()),
+		WebAdmin:              ptr(iniFile.IsWebAdminEnabled()),
+		MapVote:               ptr(iniFile.IsMapVoteEnabled()),
+		MapVoteRepeatLimit:    ptr(iniFile.GetMapVoteRepeatLimit()),
+		AdminPause:            ptr(iniFile.IsAdminPauseEnabled()),
+		WeaponThrowing:        ptr(iniFile.IsWeaponThrowingEnabled()),
+		WeaponShakeEffect:     ptr(iniFile.IsWeaponShakeEffectEnabled()),
+		ThirdPerson:           ptr(iniFile.IsThirdPersonEnabled()),
+		LowGore:               ptr(iniFile.IsLowGoreEnabled()),
+		MaxInternetClientRate: ptr(iniFile.GetMaxInternetClientRate()),
+	}
+}
+
+// Apply sets the defined values in iniFile and returns the number of changed values.
+func (s *ServerSettings) Apply(iniFile ServerIniFile) (int, error) {
+	return applyValues([]valueSetter{
+		setValue(kfKeyServerName, s.ServerName, iniFile.GetServerName, iniFile.SetServerName),
+		setValue(kfKeyShortName, s.ShortName, iniFile.GetShortName, iniFile.SetShortName),
+		setValue(kfKeyGamePort, s.GamePort, iniFile.GetGamePort, iniFile.SetGamePort),
+		setValue(kfKeyWebAdminPort, s.WebAdminPort, iniFile.GetWebAdminPort, iniFile.SetWebAdminPort),
+		setValue(kfKeyWebAdminAddress, s.WebAdminAddress, iniFile.GetWebAdminAddress, iniFile.SetWebAdminAddress),
+		setValue(kfKeyGameSpyPort, s.GameSpyPort, iniFile.GetGameSpyPort, iniFile.SetGameSpyPort),
+		setValue(kfKeyGameDifficulty, s.GameDifficulty, iniFile.GetGameDifficulty, iniFile.SetGameDifficulty),
+		setValue(kfKeyGameLength, s.GameLength, iniFile.GetGameLength, iniFile.SetGameLength),
+		setValue(kfKeyFriendlyFireRate, s.FriendlyFireRate, iniFile.GetFriendlyFireRate, iniFile.SetFriendlyFireRate),
+		setValue(kfKeyStartingCash, s.StartingCash, iniFile.GetStartingCash, iniFile.SetStartingCash),
+		setValue(kfKeyMinRespawnCash, s.MinRespawnCash, iniFile.GetMinRespawnCash, iniFile.SetMinRespawnCash),
+		setValue(kfKeyTimeBetweenWaves, s.TimeBetweenWaves, iniFile.GetTimeBetweenWaves, iniFile.SetTimeBetweenWaves),
+		setValue(kfKeyWaveFunding, s.WaveFunding, iniFile.IsWaveFundingEnabled, iniFile.SetWaveFundingEnabled),
+		setValue(kfKeyMaxZombiesOnce, s.MaxZombiesOnce, iniFile.GetMaxZombiesOnce, iniFile.SetMaxZombiesOnce),
+		setValue(kfKeyUseEndGameBoss, s.EndGameBoss, iniFile.IsEndGameBossEnabled, iniFile.SetEndGameBossEnabled),
+		setValue(kfKeyMaxPlayers, s.MaxPlayers, iniFile.GetMaxPlayers, iniFile.SetMaxPlayers),
+		setValue(kfKeyMaxSpectators, s.MaxSpectators, iniFile.GetMaxSpectators, iniFile.SetMaxSpectators),
+		setValue(kfKeyPassword, s.Password, iniFile.GetPassword, iniFile.SetPassword),
+		setValue(kfKeyRegion, s.Region, iniFile.GetRegion, iniFile.SetRegion),
+		setValue(kfKeyAdminName, s.AdminName, iniFile.GetAdminName, iniFile.SetAdminName),
+		setValue(kfKeyAdminMail, s.AdminMail, iniFile.GetAdminMail, iniFile.SetAdminMail),
+		setValue(kfKeyAdminPassword, s.AdminPassword, iniFile.GetAdminPassword, iniFile.SetAdminPassword),
+		setValue(kfKeyMOTD, s.MOTD, iniFile.GetMOTD, iniFile.SetMOTD),
+		setValue(kfKeySpecimenType, s.SpecimenType, iniFile.GetSpecimenType, iniFile.SetSpecimenType),
+		setValue(kfKeyRedirectURL, s.RedirectURL, iniFile.GetRedirectURL, iniFile.SetRedirectURL),
+		setValue(kfKeyEnableWebAdmin, s.WebAdmin, iniFile.IsWebAdminEnabled, iniFile.SetWebAdminEnabled),
+		setValue(kfKeyEnableMapVote, s.MapVote, iniFile.IsMapVoteEnabled, func(v bool) bool { return iniFile.SetMapVoteEnabled(v) == nil }),
+		setValue(kfKeyMapVoteRepeatLimit, s.MapVoteRepeatLimit, iniFile.GetMapVoteRepeatLimit, iniFile.SetMapVoteRepeatLimit),
+		setValue(kfKeyEnableAdminPause, s.AdminPause, iniFile.IsAdminPauseEnabled, iniFile.SetAdminPauseEnabled),
+		setValue(kfKeyEnableWeaponThrow, s.WeaponThrowing, iniFile.IsWeaponThrowingEnabled, iniFile.SetWeaponThrowingEnabled),
+		setValue(kfKeyWeaponShakeEffect, s.WeaponShakeEffect, iniFile.IsWeaponShakeEffectEnabled, iniFile.SetWeaponShakeEffectEnabled),
+		setValue(kfKeyEnableThirdPerson, s.ThirdPerson, iniFile.IsThirdPersonEnabled, iniFile.SetThirdPersonEnabled),
+		setValue(kfKeyEnableLowGore, s.LowGore, iniFile.IsLowGoreEnabled, iniFile.SetLowGoreEnabled),
+		setValue(kfKeyMaxInternetRate, s.MaxInternetClientRate, iniFile.GetMaxInternetClientRate, iniFile.SetMaxInternetClientRate),
+	})
+}
+
+// ExportKFPatcherSettings reads the typed values of the KFPatcher configuration file.
+func ExportKFPatcherSettings(kfpi *KFPIniFile) *KFPatcherSettings {
+	return &KFPatcherSettings{
+		ShowPerks:         ptr(kfpi.IsShowPerksEnabled()),
+		ZEDTime:           ptr(kfpi.IsZEDTimeEnabled()),
+		AllTradersOpen:    ptr(kfpi.IsAllTradersOpenEnabled()),
+		AllTradersMessage: ptr(kfpi.GetAllTradersMessage()),
+		BuyEverywhere:     ptr(kfpi.IsBuyEverywhereEnabled()),
+	}
+}
+
+// Apply sets the defined values in kfpi and returns the number of changed values.
+func (s *KFPatcherSettings) Apply(kfpi *KFPIniFile) (int, error) {
+	return applyValues([]valueSetter{
+		setValue(kfpKeyShowPerk, s.ShowPerks, kfpi.IsShowPerksEnabled, kfpi.SetShowPerksEnabled),
+		setValue(kfpKeyAllowZedTime, s.ZEDTime, kfpi.IsZEDTimeEnabled, kfpi.SetZEDTimeEnabled),
+		setValue(kfpKeyAllTradersOpen, s.AllTradersOpen, kfpi.IsAllTradersOpenEnabled, kfpi.SetAllTradersOpenEnabled),
+		setValue(kfpKeyAllTradersMessage, s.AllTradersMessage, kfpi.GetAllTradersMessage, kfpi.SetAllTradersMessage),
+		setValue(kfpKeyBuyEverywhere, s.BuyEverywhere, kfpi.IsBuyEverywhereEnabled, kfpi.SetBuyEverywhereEnabled),
+	})
+}
+
+// NewConfigDocument returns an empty document of the current version.
+func NewConfigDocument() *ConfigDocument {
+	return &ConfigDocument{
+		Version:        configDocumentVersion,
+		Maplist:        []string{},
+		ServerMutators: []string{},
+	}
+}
+
+// Marshal encodes the document in the given format (json or yaml).
+func (d *ConfigDocument) Marshal(format string) ([]byte, error) {
+	switch format {
+	case "json":
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "    ")
+		if err := enc.Encode(d); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case "yaml":
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(d); err != nil {
+			return nil, err
+		}
+		if err := enc.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	return nil, fmt.Errorf("unknown document format: %s", format)
+}
+
+// ReadConfigDocument reads a document written by Marshal.
+// YAML is used for the .yaml and .yml files, JSON otherwise.
+func ReadConfigDocument(filePath string) (*ConfigDocument, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc ConfigDocument
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &doc)
+	default:
+		err = json.Unmarshal(data, &doc)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration document %s: %w", filePath, err)
+	}
+
+	if doc.Version != configDocumentVersion {
+		return nil, fmt.Errorf("unsupported configuration document version %d, expected %d", doc.Version, configDocumentVersion)
+	}
+	return &doc, nil
+}
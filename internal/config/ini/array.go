@@ -0,0 +1,222 @@
+package ini
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// UE2 arrays are written either as indexed keys (Key[0]=A, Key[1]=B) for
+// static arrays, or as repeated keys (Key=A, Key=B) for dynamic arrays.
+
+// MaxArrayIndex is the highest index of an indexed key, so a single key
+// such as Maps[99999999] can't allocate a huge array.
+const MaxArrayIndex = 4096
+
+// splitArrayKey splits an indexed key name such as "Maps[3]" into its base
+// name and index, whatever the index.
+func splitArrayKey(name string) (string, int, bool) {
+	open := strings.IndexByte(name, '[')
+	if open <= 0 || !strings.HasSuffix(name, "]") {
+		return name, 0, false
+	}
+
+	idx, err := strconv.Atoi(strings.TrimSpace(name[open+1 : len(name)-1]))
+	if err != nil || idx < 0 {
+		return name, 0, false
+	}
+	return strings.TrimSpace(name[:open]), idx, true
+}
+
+// parseArrayKey splits an indexed key name such as "Maps[3]" into its
+// base name and index. Indexes above MaxArrayIndex are not array keys.
+func parseArrayKey(name string) (string, int, bool) {
+	base, idx, ok := splitArrayKey(name)
+	if !ok || idx > MaxArrayIndex {
+		return name, 0, false
+	}
+	return base, idx, true
+}
+
+// checkArrayKey returns an error when name is an indexed key whose index is
+// out of range.
+func checkArrayKey(name string) error {
+	if _, idx, ok := splitArrayKey(name); ok && idx > MaxArrayIndex {
+		return fmt.Errorf("array index of key '%s' out of range, expected 0-%d", name, MaxArrayIndex)
+	}
+	return nil
+}
+
+func arrayKeyName(name string, index int) string {
+	return fmt.Sprintf("%s[%d]", name, index)
+}
+
+// isArrayKey reports whether key is an element of the array name, in either form.
+func isArrayKey(key *IniKey, name string) bool {
+	if strings.EqualFold(key.Name, name) {
+		return true
+	}
+	base, _, ok := parseArrayKey(key.Name)
+	return ok && strings.EqualFold(base, name)
+}
+
+// GetArray returns the elements of the array name.
+// Missing indexed elements are returned as empty strings.
+func (s *IniSection) GetArray(name string) []string {
+	var values []string
+	for _, key := range s.keys {
+		if !isArrayKey(key, name) {
+			continue
+		}
+
+		base, idx, ok := parseArrayKey(key.Name)
+		if !ok || !strings.EqualFold(base, name) {
+			values = append(values, key.Value)
+			continue
+		}
+		if idx >= len(values) {
+			values = append(values, make([]string, idx-len(values)+1)...)
+		}
+		values[idx] = key.Value
+	}
+	return values
+}
+
+// IsIndexedArray reports whether the array name is written using indexed keys.
+func (s *IniSection) IsIndexedArray(name string) bool {
+	for _, key := range s.keys {
+		if base, _, ok := parseArrayKey(key.Name); ok && strings.EqualFold(base, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// DeleteArray removes every element of the array name, in both forms.
+// It returns the position of the first removed element, or -1.
+func (s *IniSection) DeleteArray(name string) int {
+	pos := -1
+	newKeys := []*IniKey{}
+	for i, key := range s.keys {
+		if isArrayKey(key, name) {
+			if pos < 0 {
+				pos = i
+			}
+			continue
+		}
+		newKeys = append(newKeys, key)
+	}
+	s.keys = newKeys
+	s.recalculateIndices()
+	return pos
+}
+
+// SetArray replaces the elements of the array name, keeping them where
+// the previous elements were. Empty indexed elements are skipped.
+func (s *IniSection) SetArray(name string, values []string, indexed bool) {
+	pos := s.DeleteArray(name)
+	if pos < 0 {
+		pos = len(s.keys)
+	}
+
+	keys := make([]*IniKey, 0, len(values))
+	for i, value := range values {
+		if indexed {
+			if value == "" {
+				continue
+			}
+			keys = append(keys, &IniKey{Name: arrayKeyName(name, i), Value: value})
+		} else {
+			keys = append(keys, &IniKey{Name: name, Value: value})
+		}
+	}
+	s.keys = slices.Insert(s.keys, pos, keys...)
+	s.recalculateIndices()
+}
+
+// SetArrayElement sets a single element of an indexed array.
+// New elements are added after the last element of the array.
+func (s *IniSection) SetArrayElement(name string, index int, value string) {
+	pos := len(s.keys)
+	for i, key := range s.keys {
+		base, idx, ok := parseArrayKey(key.Name)
+		if !ok || !strings.EqualFold(base, name) {
+			continue
+		}
+		if idx == index {
+			key.Value = value
+			return
+		}
+		pos = i + 1
+	}
+	s.keys = slices.Insert(s.keys, pos, &IniKey{Name: arrayKeyName(name, index), Value: value})
+	s.recalculateIndices()
+}
+
+func (f *GenericIniFile) GetArray(section string, key string) []string {
+	if sect := f.GetSection(section); sect != nil {
+		return sect.GetArray(key)
+	}
+	return nil
+}
+
+func (f *GenericIniFile) GetArrayElement(section string, key string, index int, defvalue string) string {
+	values := f.GetArray(section, key)
+	if index >= 0 && index < len(values) && values[index] != "" {
+		return values[index]
+	}
+	return defvalue
+}
+
+func (f *GenericIniFile) IsIndexedArray(section string, key string) bool {
+	if sect := f.GetSection(section); sect != nil {
+		return sect.IsIndexedArray(key)
+	}
+	return false
+}
+
+func (f *GenericIniFile) SetArray(section string, key string, values []string, indexed bool) bool {
+	sect, err := f.getOrAddSection(section)
+	if err != nil {
+		return false
+	}
+	sect.SetArray(key, values, indexed)
+
+	f.Logger.Debug("Setting array",
+		"function", "SetArray", "section", section, "key", key, "values", values, "indexed", indexed)
+	return slices.Equal(trimTrailingEmpty(sect.GetArray(key)), trimTrailingEmpty(values))
+}
+
+func (f *GenericIniFile) SetArrayElement(section string, key string, index int, value string) bool {
+	if index < 0 || index > MaxArrayIndex {
+		return false
+	}
+	sect, err := f.getOrAddSection(section)
+	if err != nil {
+		return false
+	}
+	sect.SetArrayElement(key, index, value)
+
+	f.Logger.Debug("Setting array element",
+		"function", "SetArrayElement", "section", section, "key", key, "index", index, "value", value)
+	return f.GetArrayElement(section, key, index, "") == value
+}
+
+func (f *GenericIniFile) DeleteArray(section string, key string) bool {
+	if sect := f.GetSection(section); sect != nil {
+		if sect.DeleteArray(key) >= 0 {
+			f.Logger.Debug("Deleting array",
+				"function", "DeleteArray", "section", section, "key", key)
+			return true
+		}
+	}
+	return false
+}
+
+func trimTrailingEmpty(values []string) []string {
+	for len(values) > 0 && values[len(values)-1] == "" {
+		values = values[:len(values)-1]
+	}
+	return values
+}
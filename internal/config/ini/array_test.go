@@ -0,0 +1,204 @@
+package ini
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseArrayKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		wantBase string
+		wantIdx  int
+		wantOk   bool
+	}{
+		{"Maps[0]", "Maps", 0, true},
+		{"Maps[12]", "Maps", 12, true},
+		{"Maps [ 3 ]", "Maps", 3, true},
+		{"Maps[4096]", "Maps", MaxArrayIndex, true},
+		{"Maps[4097]", "Maps[4097]", 0, false},
+		{"Maps[-1]", "Maps[-1]", 0, false},
+		{"Maps[x]", "Maps[x]", 0, false},
+		{"[0]", "[0]", 0, false},
+		{"Maps", "Maps", 0, false},
+	}
+
+	for _, tt := range tests {
+		base, idx, ok := parseArrayKey(tt.name)
+		if base != tt.wantBase || idx != tt.wantIdx || ok != tt.wantOk {
+			t.Errorf("parseArrayKey(%q) = %q, %d, %v, want %q, %d, %v",
+				tt.name, base, idx, ok, tt.wantBase, tt.wantIdx, tt.wantOk)
+		}
+	}
+}
+
+func TestCheckArrayKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{"Maps[0]", false},
+		{"Maps[4096]", false},
+		{"Maps[4097]", true},
+		{"Maps [ 99999999 ]", true},
+		{"Maps[-1]", false},
+		{"Maps[x]", false},
+		{"[99999999]", false},
+		{"Maps", false},
+	}
+
+	for _, tt := range tests {
+		if err := checkArrayKey(tt.name); (err != nil) != tt.wantErr {
+			t.Errorf("checkArrayKey(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestGetArray(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+		indexed bool
+	}{
+		{"dynamic", "Maps=KF-Farm\nMaps=KF-Manor\n", []string{"KF-Farm", "KF-Manor"}, false},
+		{"indexed", "Maps[0]=KF-Farm\nMaps[1]=KF-Manor\n", []string{"KF-Farm", "KF-Manor"}, true},
+		{"unordered", "Maps[1]=KF-Manor\nMaps[0]=KF-Farm\n", []string{"KF-Farm", "KF-Manor"}, true},
+		{"gaps", "Maps[0]=KF-Farm\nMaps[2]=KF-Manor\n", []string{"KF-Farm", "", "KF-Manor"}, true},
+		{"case insensitive", "maps[0]=KF-Farm\nMAPS[1]=KF-Manor\n", []string{"KF-Farm", "KF-Manor"}, true},
+		{"other keys", "Maps=KF-Farm\nMapsCount=1\nMap=KF-Manor\n", []string{"KF-Farm"}, false},
+		{"missing", "Other=1\n", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := loadTestFile(t, "[KFmod.KFMaplist]\n"+tt.content)
+			if got := f.GetArray("KFmod.KFMaplist", "Maps"); !slices.Equal(got, tt.want) {
+				t.Errorf("GetArray() = %q, want %q", got, tt.want)
+			}
+			if got := f.IsIndexedArray("KFmod.KFMaplist", "Maps"); got != tt.indexed {
+				t.Errorf("IsIndexedArray() = %v, want %v", got, tt.indexed)
+			}
+		})
+	}
+}
+
+func TestLoadArrayIndexOutOfRange(t *testing.T) {
+	f := loadTestFile(t, "[KFmod.KFMaplist]\nMaps[0]=KF-Farm\n")
+	if f.SetArrayElement("KFmod.KFMaplist", "Maps", MaxArrayIndex+1, "KF-Manor") {
+		t.Error("SetArrayElement() accepted an index above MaxArrayIndex")
+	}
+
+	path := filepath.Join(t.TempDir(), "test.ini")
+	if err := os.WriteFile(path, []byte("[KFmod.KFMaplist]\nMaps[99999999]=KF-Farm\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err := NewGenericIniFile("test.ini").Load(path)
+	if err == nil || !strings.Contains(err.Error(), "out of range") {
+		t.Errorf("Load() error = %v, want an out of range error", err)
+	}
+}
+
+func TestSetArray(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  []string
+		indexed bool
+		want    string
+	}{
+		{"dynamic", []string{"KF-Offices", "KF-Farm"}, false, "Maps=KF-Offices\nMaps=KF-Farm\n"},
+		{"indexed", []string{"KF-Offices", "", "KF-Farm"}, true, "Maps[0]=KF-Offices\nMaps[2]=KF-Farm\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := loadTestFile(t, "[KFmod.KFMaplist]\nBefore=1\nMaps=KF-Manor\nMaps[1]=KF-Farm\nAfter=1\n")
+			f.SetArray("KFmod.KFMaplist", "Maps", tt.values, tt.indexed)
+
+			var got strings.Builder
+			for _, key := range f.GetSection("KFmod.KFMaplist").Keys() {
+				got.WriteString(key.Name + "=" + key.Value + "\n")
+			}
+			// The new elements take the place of the previous ones
+			want := "Before=1\n" + tt.want + "After=1\n"
+			if got.String() != want {
+				t.Errorf("keys = %q, want %q", got.String(), want)
+			}
+		})
+	}
+}
+
+func TestParseStruct(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    map[string]string
+		wantErr bool
+	}{
+		{value: "(A=1,B=2)", want: map[string]string{"A": "1", "B": "2"}},
+		{value: " ( A = 1 , B = 2 ) ", want: map[string]string{"A": "1", "B": "2"}},
+		{value: "()", want: map[string]string{}},
+		{value: `(Name="a,b",Tag="x(y")`, want: map[string]string{"Name": `"a,b"`, "Tag": `"x(y"`}},
+		{value: `(Name="say \"hi\", ok")`, want: map[string]string{"Name": `"say \"hi\", ok"`}},
+		{value: "(Pos=(X=1,Y=2),Z=3)", want: map[string]string{"Pos": "(X=1,Y=2)", "Z": "3"}},
+		{value: "(A=1,A=2)", want: map[string]string{"A": "2"}},
+		{value: "(A=1,)", want: map[string]string{"A": "1"}},
+		{value: "A=1", wantErr: true},
+		{value: "(A)", wantErr: true},
+		{value: `(A="1)`, wantErr: true},
+		{value: "(A=(1)", wantErr: true},
+		{value: "(A=1))", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseStruct(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseStruct(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !maps.Equal(got, tt.want) {
+			t.Errorf("ParseStruct(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestFormatStruct(t *testing.T) {
+	fields := map[string]string{"C": "3", "A": "1", "B": `"x"`}
+	tests := []struct {
+		order []string
+		want  string
+	}{
+		{nil, `(A=1,B="x",C=3)`},
+		{[]string{"C", "B"}, `(C=3,B="x",A=1)`},
+		{[]string{"D", "C", "C"}, `(C=3,A=1,B="x")`},
+	}
+
+	for _, tt := range tests {
+		if got := FormatStruct(fields, tt.order); got != tt.want {
+			t.Errorf("FormatStruct(%v) = %s, want %s", tt.order, got, tt.want)
+		}
+	}
+}
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		raw    string
+		quoted string
+	}{
+		{"KF-Farm", `"KF-Farm"`},
+		{`say "hi"`, `"say \"hi\""`},
+		{"", `""`},
+	}
+
+	for _, tt := range tests {
+		if got := Quote(tt.raw); got != tt.quoted {
+			t.Errorf("Quote(%q) = %s, want %s", tt.raw, got, tt.quoted)
+		}
+		if got := Unquote(tt.quoted); got != tt.raw {
+			t.Errorf("Unquote(%s) = %q, want %q", tt.quoted, got, tt.raw)
+		}
+	}
+}
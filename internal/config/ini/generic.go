@@ -13,21 +13,22 @@ import (
 	"github.com/K4rian/kfdsl/internal/log"
 )
 
+// GenericIniFile is an ordered ini file.
+// Like UE2, section and key names are case-insensitive, but their original
+// spelling is preserved on save.
 type GenericIniFile struct {
-	name            string
-	sections        []*IniSection   // Ordered list of sections
-	sectionMap      map[string]int  // Map of section name to its index in Sections slice
-	normalizedNames map[string]bool // Tracks lowercase section names to prevent duplicates
-	Logger          *dslogger.Logger
+	name       string
+	sections   []*IniSection  // Ordered list of sections
+	sectionMap map[string]int // Map of lowercase section name to its index in Sections slice
+	Logger     *dslogger.Logger
 }
 
 func NewGenericIniFile(name string) *GenericIniFile {
 	return &GenericIniFile{
-		name:            name,
-		sections:        []*IniSection{},
-		sectionMap:      make(map[string]int),
-		normalizedNames: make(map[string]bool),
-		Logger:          log.Logger.WithService(name),
+		name:       name,
+		sections:   []*IniSection{},
+		sectionMap: make(map[string]int),
+		Logger:     log.Logger.WithService(name),
 	}
 }
 
@@ -40,11 +41,7 @@ func (f *GenericIniFile) Sections() []*IniSection {
 }
 
 func (f *GenericIniFile) GetSection(name string) *IniSection {
-	if _, exists := f.normalizedNames[strings.ToLower(name)]; !exists {
-		return nil
-	}
-
-	if idx, exists := f.sectionMap[name]; exists {
+	if idx, exists := f.sectionMap[strings.ToLower(name)]; exists {
 		return f.sections[idx]
 	}
 	return nil
@@ -52,14 +49,13 @@ func (f *GenericIniFile) GetSection(name string) *IniSection {
 
 func (f *GenericIniFile) AddSection(name string) (*IniSection, error) {
 	lowerName := strings.ToLower(name)
-	if _, exists := f.normalizedNames[strings.ToLower(name)]; exists {
+	if _, exists := f.sectionMap[lowerName]; exists {
 		return nil, fmt.Errorf("duplicate section found: %s", name)
 	}
 
 	section := NewIniSection(name)
 	f.sections = append(f.sections, section)
-	f.sectionMap[name] = len(f.sections) - 1
-	f.normalizedNames[lowerName] = true
+	f.sectionMap[lowerName] = len(f.sections) - 1
 
 	f.Logger.Debug("Adding new section",
 		"function", "AddSection", "section", name, "totalSections", len(f.sections))
@@ -75,7 +71,6 @@ func (f *GenericIniFile) DeleteSection(name string) bool {
 
 	f.sections = slices.Delete(f.sections, idx, idx+1)
 	delete(f.sectionMap, lowerName)
-	delete(f.normalizedNames, lowerName)
 
 	// Rebuild the map
 	for i, section := range f.sections {
@@ -214,6 +209,18 @@ func (f *GenericIniFile) Load(filePath string) error {
 
 	scanner := bufio.NewScanner(file)
 	var currentSection *IniSection
+	var mergeInto *IniSection // Existing section of the duplicate section being read
+
+	mergeSection := func() {
+		if mergeInto == nil {
+			return
+		}
+		if replaced := mergeInto.merge(currentSection); len(replaced) > 0 {
+			f.Logger.Warn("Keys defined in duplicate sections, the last values are used",
+				"function", "Load", "file", filePath, "section", mergeInto.Name(), "keys", replaced)
+		}
+		mergeInto = nil
+	}
 
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
@@ -224,6 +231,16 @@ func (f *GenericIniFile) Load(filePath string) error {
 		// Check for section header
 		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
 			sectionName := strings.TrimSpace(line[1 : len(line)-1])
+			mergeSection()
+
+			// Duplicate sections are merged into the first one once read
+			if existing := f.GetSection(sectionName); existing != nil {
+				f.Logger.Warn("Duplicate section found, merging its keys into the existing one",
+					"function", "Load", "file", filePath, "section", sectionName, "existingSection", existing.Name())
+				mergeInto, currentSection = existing, NewIniSection(sectionName)
+				continue
+			}
+
 			if currentSection, err = f.AddSection(sectionName); err != nil {
 				return err
 			}
@@ -236,6 +253,9 @@ func (f *GenericIniFile) Load(filePath string) error {
 
 			key := strings.TrimSpace(parts[0])
 			val := strings.TrimSpace(parts[1])
+			if err := checkArrayKey(key); err != nil {
+				return fmt.Errorf("invalid key in file '%s': %v", filePath, err)
+			}
 			currentSection.AddKey(key, val)
 
 			f.Logger.Debug("Parsing key",
@@ -248,6 +268,7 @@ func (f *GenericIniFile) Load(filePath string) error {
 	if err := scanner.Err(); err != nil {
 		return fmt.Errorf("error reading file '%s': %v", filePath, err)
 	}
+	mergeSection()
 
 	f.Logger.Debug("Ini file successfully loaded",
 		"function", "Save", "file", filePath)
@@ -319,18 +340,24 @@ func (f *GenericIniFile) Save(filePath string) error {
 	return nil
 }
 
-func (f *GenericIniFile) setKeyValue(section string, key string, value any, unique bool) bool {
-	sect := f.GetSection(section)
+func (f *GenericIniFile) getOrAddSection(section string) (*IniSection, error) {
+	if sect := f.GetSection(section); sect != nil {
+		return sect, nil
+	}
 
 	// Add the section if it doesn't exists
-	if sect == nil {
-		var err error
+	sect, err := f.AddSection(section)
+	if err != nil {
+		f.Logger.Error("Failed to add new section", "section", section, "error", err)
+		return nil, err
+	}
+	return sect, nil
+}
 
-		sect, err = f.AddSection(section)
-		if err != nil {
-			f.Logger.Error("Failed to add new section", "section", section, "error", err)
-			return false
-		}
+func (f *GenericIniFile) setKeyValue(section string, key string, value any, unique bool) bool {
+	sect, err := f.getOrAddSection(section)
+	if err != nil {
+		return false
 	}
 
 	val := cast.ToString(value)
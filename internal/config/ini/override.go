@@ -0,0 +1,135 @@
+package ini
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+type OverrideOp string
+
+const (
+	OverrideSet    OverrideOp = "set"    // Section.Key=value
+	OverrideAppend OverrideOp = "append" // Section.Key+=value
+	OverrideDelete OverrideOp = "delete" // Section.Key-=[value]
+)
+
+// Override is a single key update applied to an ini file.
+type Override struct {
+	Section string     `json:"section"`
+	Key     string     `json:"key"`
+	Value   string     `json:"value,omitempty"`
+	Op      OverrideOp `json:"op,omitempty"`
+}
+
+// ParseOverride parses an override written as 'Section.Key=value'.
+// The key is the part after the last dot, so sections such as
+// KFmod.KFGameType can be used as-is. '+=' appends a value to a
+// multi-value key and '-=' deletes the given value, or the whole key if
+// no value is given.
+func ParseOverride(spec string) (*Override, error) {
+	eq := strings.IndexByte(spec, '=')
+	if eq < 0 {
+		return nil, fmt.Errorf("invalid ini override '%s': expected Section.Key=value", spec)
+	}
+
+	name, value := spec[:eq], spec[eq+1:]
+	op := OverrideSet
+	switch {
+	case strings.HasSuffix(name, "+"):
+		op = OverrideAppend
+		name = name[:len(name)-1]
+	case strings.HasSuffix(name, "-"):
+		op = OverrideDelete
+		name = name[:len(name)-1]
+	}
+
+	dot := strings.LastIndexByte(name, '.')
+	if dot < 0 {
+		return nil, fmt.Errorf("invalid ini override '%s': expected Section.Key=value", spec)
+	}
+
+	o := &Override{
+		Section: strings.TrimSpace(name[:dot]),
+		Key:     strings.TrimSpace(name[dot+1:]),
+		Value:   value,
+		Op:      op,
+	}
+	return o, o.Validate()
+}
+
+// Validate checks that the override targets a key with a known operation.
+func (o *Override) Validate() error {
+	if o.Op == "" {
+		o.Op = OverrideSet
+	}
+	if o.Section == "" || o.Key == "" {
+		return fmt.Errorf("invalid ini override '%s': section and key are required", o)
+	}
+	if err := checkArrayKey(o.Key); err != nil {
+		return fmt.Errorf("invalid ini override '%s': %v", o, err)
+	}
+	switch o.Op {
+	case OverrideSet, OverrideAppend, OverrideDelete:
+		return nil
+	}
+	return fmt.Errorf("invalid ini override '%s': unknown operation '%s'", o, o.Op)
+}
+
+func (o *Override) String() string {
+	switch o.Op {
+	case OverrideAppend:
+		return fmt.Sprintf("%s.%s+=%s", o.Section, o.Key, o.Value)
+	case OverrideDelete:
+		return fmt.Sprintf("%s.%s-=%s", o.Section, o.Key, o.Value)
+	}
+	return fmt.Sprintf("%s.%s=%s", o.Section, o.Key, o.Value)
+}
+
+// ApplyOverride applies o to the file and reports whether the file changed.
+// Overrides are idempotent: applying the same override twice changes nothing.
+func (f *GenericIniFile) ApplyOverride(o *Override) (bool, error) {
+	current := f.GetKeys(o.Section, o.Key)
+
+	switch o.Op {
+	case OverrideSet:
+		if slices.Equal(current, []string{o.Value}) {
+			return false, nil
+		}
+		// Multi-value keys are replaced by the single value
+		if len(current) > 1 {
+			f.DeleteKey(o.Section, o.Key)
+		}
+		if !f.SetKey(o.Section, o.Key, o.Value, true) {
+			return false, fmt.Errorf("%s: failed to set the new value", o)
+		}
+	case OverrideAppend:
+		if slices.Contains(current, o.Value) {
+			return false, nil
+		}
+		if !f.SetKey(o.Section, o.Key, o.Value, false) {
+			return false, fmt.Errorf("%s: failed to append the new value", o)
+		}
+	case OverrideDelete:
+		if o.Value == "" {
+			if len(current) == 0 {
+				return false, nil
+			}
+			if !f.DeleteKey(o.Section, o.Key) {
+				return false, fmt.Errorf("%s: failed to delete the key", o)
+			}
+			return true, nil
+		}
+		if !slices.Contains(current, o.Value) {
+			return false, nil
+		}
+		sect := f.GetSection(o.Section)
+		sect.DeleteUniqueKey(o.Key, &o.Value, nil)
+		if slices.Contains(sect.GetKeys(o.Key), o.Value) {
+			return false, fmt.Errorf("%s: failed to delete the value", o)
+		}
+	default:
+		return false, fmt.Errorf("%s: unknown operation '%s'", o, o.Op)
+	}
+	return true, nil
+}
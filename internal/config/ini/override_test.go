@@ -0,0 +1,144 @@
+package ini
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/K4rian/kfdsl/internal/log"
+)
+
+func TestMain(m *testing.M) {
+	if err := log.Init("error", "", "text", 1, 1, 1, false); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// loadTestFile loads content as an ini file.
+func loadTestFile(t *testing.T, content string) *GenericIniFile {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.ini")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f := NewGenericIniFile("test.ini")
+	if err := f.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestParseOverride(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    Override
+		wantErr bool
+	}{
+		{spec: "KFmod.KFGameType.StartingCash=500", want: Override{"KFmod.KFGameType", "StartingCash", "500", OverrideSet}},
+		{spec: "Engine.GameInfo.bChangeLevels=", want: Override{"Engine.GameInfo", "bChangeLevels", "", OverrideSet}},
+		{spec: "Engine.GameReplicationInfo.MOTD=a=b", want: Override{"Engine.GameReplicationInfo", "MOTD", "a=b", OverrideSet}},
+		{spec: " Engine.GameInfo . MaxPlayers =6", want: Override{"Engine.GameInfo", "MaxPlayers", "6", OverrideSet}},
+		{spec: "KFmod.KFMaplist.Maps+=KF-Farm", want: Override{"KFmod.KFMaplist", "Maps", "KF-Farm", OverrideAppend}},
+		{spec: "KFmod.KFMaplist.Maps-=KF-Farm", want: Override{"KFmod.KFMaplist", "Maps", "KF-Farm", OverrideDelete}},
+		{spec: "KFmod.KFMaplist.Maps-=", want: Override{"KFmod.KFMaplist", "Maps", "", OverrideDelete}},
+		{spec: "KFmod.KFMaplist.Maps[3]=KF-Farm", want: Override{"KFmod.KFMaplist", "Maps[3]", "KF-Farm", OverrideSet}},
+		{spec: "Engine.GameInfo.MaxPlayers", wantErr: true},
+		{spec: "MaxPlayers=6", wantErr: true},
+		{spec: ".MaxPlayers=6", wantErr: true},
+		{spec: "Engine.GameInfo.=6", wantErr: true},
+		{spec: "KFmod.KFMaplist.Maps[5000]=KF-Farm", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseOverride(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseOverride(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && *got != tt.want {
+			t.Errorf("ParseOverride(%q) = %+v, want %+v", tt.spec, *got, tt.want)
+		}
+	}
+}
+
+func TestOverrideValidate(t *testing.T) {
+	tests := []struct {
+		o       Override
+		wantOp  OverrideOp
+		wantErr bool
+	}{
+		{o: Override{Section: "Engine.GameInfo", Key: "MaxPlayers", Value: "6"}, wantOp: OverrideSet},
+		{o: Override{Section: "KFmod.KFMaplist", Key: "Maps", Value: "KF-Farm", Op: OverrideAppend}, wantOp: OverrideAppend},
+		{o: Override{Section: "Engine.GameInfo", Key: "MaxPlayers", Op: "replace"}, wantErr: true},
+		{o: Override{Key: "MaxPlayers"}, wantErr: true},
+		{o: Override{Section: "Engine.GameInfo"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		o := tt.o
+		err := o.Validate()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%+v.Validate() error = %v, wantErr %v", tt.o, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && o.Op != tt.wantOp {
+			t.Errorf("%+v.Validate() op = %s, want %s", tt.o, o.Op, tt.wantOp)
+		}
+	}
+}
+
+func TestApplyOverride(t *testing.T) {
+	const content = `[KFmod.KFMaplist]
+Maps=KF-Farm
+Maps=KF-Manor
+
+[Engine.GameInfo]
+MaxPlayers=6
+`
+
+	tests := []struct {
+		spec        string
+		wantChanged bool
+		wantValues  []string
+	}{
+		{"Engine.GameInfo.MaxPlayers=6", false, []string{"6"}},
+		{"Engine.GameInfo.MaxPlayers=8", true, []string{"8"}},
+		{"Engine.GameInfo.GoalScore=10", true, []string{"10"}},
+		{"Engine.AccessControl.AdminName=admin", true, []string{"admin"}},
+		{"KFmod.KFMaplist.Maps=KF-Offices", true, []string{"KF-Offices"}},
+		{"KFmod.KFMaplist.Maps+=KF-Farm", false, []string{"KF-Farm", "KF-Manor"}},
+		{"KFmod.KFMaplist.Maps+=KF-Offices", true, []string{"KF-Farm", "KF-Manor", "KF-Offices"}},
+		{"KFmod.KFMaplist.Maps-=KF-Farm", true, []string{"KF-Manor"}},
+		{"KFmod.KFMaplist.Maps-=KF-Offices", false, []string{"KF-Farm", "KF-Manor"}},
+		{"KFmod.KFMaplist.Maps-=", true, nil},
+		{"Engine.GameInfo.GoalScore-=", false, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			o, err := ParseOverride(tt.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f := loadTestFile(t, content)
+
+			changed, err := f.ApplyOverride(o)
+			if err != nil {
+				t.Fatalf("ApplyOverride() error = %v", err)
+			}
+			if changed != tt.wantChanged {
+				t.Errorf("ApplyOverride() = %v, want %v", changed, tt.wantChanged)
+			}
+			if got := f.GetKeys(o.Section, o.Key); !slices.Equal(got, tt.wantValues) {
+				t.Errorf("values = %q, want %q", got, tt.wantValues)
+			}
+
+			// Overrides are idempotent
+			if changed, err := f.ApplyOverride(o); changed || err != nil {
+				t.Errorf("second ApplyOverride() = %v, %v, want false, nil", changed, err)
+			}
+		})
+	}
+}
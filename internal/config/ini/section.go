@@ -1,5 +1,12 @@
 package ini
 
+import (
+	"slices"
+	"strings"
+)
+
+// IniSection is an ordered list of keys.
+// Key names are matched case-insensitively.
 type IniSection struct {
 	name string
 	keys []*IniKey // Slice to maintain order and support duplicates
@@ -22,7 +29,7 @@ func (s *IniSection) Keys() []*IniKey {
 
 func (s *IniSection) GetKey(name string) (string, bool) {
 	for _, key := range s.keys {
-		if key.Name == name {
+		if strings.EqualFold(key.Name, name) {
 			return key.Value, true
 		}
 	}
@@ -32,7 +39,7 @@ func (s *IniSection) GetKey(name string) (string, bool) {
 func (s *IniSection) GetKeys(name string) []string {
 	var values []string
 	for _, key := range s.keys {
-		if key.Name == name {
+		if strings.EqualFold(key.Name, name) {
 			values = append(values, key.Value)
 		}
 	}
@@ -46,7 +53,7 @@ func (s *IniSection) AddKey(name, value string) {
 
 func (s *IniSection) AddUniqueKey(name, value string) {
 	for _, key := range s.keys {
-		if key.Name == name && key.Value == value {
+		if strings.EqualFold(key.Name, name) && key.Value == value {
 			return
 		}
 	}
@@ -56,7 +63,7 @@ func (s *IniSection) AddUniqueKey(name, value string) {
 func (s *IniSection) DeleteKey(name string) {
 	newKeys := []*IniKey{}
 	for _, key := range s.keys {
-		if key.Name != name {
+		if !strings.EqualFold(key.Name, name) {
 			newKeys = append(newKeys, key)
 		}
 	}
@@ -67,7 +74,7 @@ func (s *IniSection) DeleteKey(name string) {
 func (s *IniSection) DeleteUniqueKey(name string, targetValue *string, targetIndex *int) {
 	newKeys := []*IniKey{}
 	for i, key := range s.keys {
-		if key.Name == name {
+		if strings.EqualFold(key.Name, name) {
 			if targetValue != nil && key.Value == *targetValue {
 				continue
 			}
@@ -87,13 +94,14 @@ func (s *IniSection) SetKey(name, value string) {
 
 func (s *IniSection) SetUniqueKey(name, value string) {
 	for _, key := range s.keys {
-		if key.Name == name && key.Value == value {
+		if strings.EqualFold(key.Name, name) && key.Value == value {
 			return
 		}
 	}
 
+	// Keep the original key spelling
 	for _, key := range s.keys {
-		if key.Name == name {
+		if strings.EqualFold(key.Name, name) {
 			key.Value = value
 			return
 		}
@@ -101,6 +109,54 @@ func (s *IniSection) SetUniqueKey(name, value string) {
 	s.AddKey(name, value)
 }
 
+// merge adds the keys of a duplicate section. Like UE2, the last values of
+// the single keys win: a key defined once in the section is replaced by the
+// values of the duplicate, while a list, defined several times, goes on with
+// them. It returns the names of the keys whose values changed.
+func (s *IniSection) merge(dup *IniSection) []string {
+	var replaced []string
+	merged := make(map[string]bool)
+	for _, key := range dup.keys {
+		name := strings.ToLower(key.Name)
+		if merged[name] {
+			continue
+		}
+		merged[name] = true
+
+		values := dup.GetKeys(key.Name)
+		existing := s.GetKeys(key.Name)
+		if len(existing) == 1 {
+			if !slices.Equal(existing, values) {
+				replaced = append(replaced, key.Name)
+			}
+			s.replaceKey(key.Name, values)
+			continue
+		}
+		for _, value := range values {
+			s.keys = append(s.keys, &IniKey{Name: key.Name, Value: value})
+		}
+	}
+	s.recalculateIndices()
+	return replaced
+}
+
+// replaceKey replaces the value of the single key name with values, the
+// extra values being added right after it.
+func (s *IniSection) replaceKey(name string, values []string) {
+	for i, key := range s.keys {
+		if !strings.EqualFold(key.Name, name) {
+			continue
+		}
+		key.Value = values[0]
+		extra := make([]*IniKey, 0, len(values)-1)
+		for _, value := range values[1:] {
+			extra = append(extra, &IniKey{Name: key.Name, Value: value})
+		}
+		s.keys = slices.Insert(s.keys, i+1, extra...)
+		return
+	}
+}
+
 func (s *IniSection) recalculateIndices() {
 	for i, key := range s.keys {
 		key.Index = i
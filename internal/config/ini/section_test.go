@@ -0,0 +1,105 @@
+package ini
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSectionCaseInsensitive(t *testing.T) {
+	s := NewIniSection("Engine.GameInfo")
+	s.AddKey("MaxPlayers", "6")
+	s.AddKey("ServerActors", "IpDrv.UdpBeacon")
+	s.AddKey("serveractors", "UWeb.WebServer")
+
+	if value, ok := s.GetKey("MAXPLAYERS"); !ok || value != "6" {
+		t.Errorf("GetKey() = %q, %v, want %q, true", value, ok, "6")
+	}
+	if got, want := s.GetKeys("ServerActors"), []string{"IpDrv.UdpBeacon", "UWeb.WebServer"}; !slices.Equal(got, want) {
+		t.Errorf("GetKeys() = %q, want %q", got, want)
+	}
+
+	// The original spelling is kept
+	s.SetUniqueKey("maxplayers", "12")
+	if keys := s.Keys(); keys[0].Name != "MaxPlayers" || keys[0].Value != "12" {
+		t.Errorf("SetUniqueKey() key = %s=%s, want MaxPlayers=12", keys[0].Name, keys[0].Value)
+	}
+
+	s.DeleteKey("SERVERACTORS")
+	if got := s.GetKeys("ServerActors"); len(got) != 0 {
+		t.Errorf("GetKeys() after DeleteKey() = %q, want none", got)
+	}
+}
+
+func TestSectionMerge(t *testing.T) {
+	type key struct{ name, value string }
+	tests := []struct {
+		name         string
+		keys         []key
+		dup          []key
+		want         []key
+		wantReplaced []string
+	}{
+		{
+			"single key replaced",
+			[]key{{"MaxPlayers", "6"}},
+			[]key{{"maxplayers", "12"}},
+			[]key{{"MaxPlayers", "12"}},
+			[]string{"maxplayers"},
+		},
+		{
+			"same value",
+			[]key{{"MaxPlayers", "6"}},
+			[]key{{"MaxPlayers", "6"}},
+			[]key{{"MaxPlayers", "6"}},
+			nil,
+		},
+		{
+			"single key replaced by a list",
+			[]key{{"Maps", "KF-Farm"}, {"Other", "1"}},
+			[]key{{"Maps", "KF-Manor"}, {"Maps", "KF-Offices"}},
+			[]key{{"Maps", "KF-Manor"}, {"Maps", "KF-Offices"}, {"Other", "1"}},
+			[]string{"Maps"},
+		},
+		{
+			"list goes on",
+			[]key{{"Maps", "KF-Farm"}, {"Maps", "KF-Manor"}},
+			[]key{{"Maps", "KF-Offices"}},
+			[]key{{"Maps", "KF-Farm"}, {"Maps", "KF-Manor"}, {"Maps", "KF-Offices"}},
+			nil,
+		},
+		{
+			"new keys",
+			[]key{{"MaxPlayers", "6"}},
+			[]key{{"Maps", "KF-Farm"}, {"Maps", "KF-Manor"}},
+			[]key{{"MaxPlayers", "6"}, {"Maps", "KF-Farm"}, {"Maps", "KF-Manor"}},
+			nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, dup := NewIniSection("Section"), NewIniSection("Section")
+			for _, k := range tt.keys {
+				s.AddKey(k.name, k.value)
+			}
+			for _, k := range tt.dup {
+				dup.AddKey(k.name, k.value)
+			}
+
+			replaced := s.merge(dup)
+			if !slices.Equal(replaced, tt.wantReplaced) {
+				t.Errorf("merge() = %q, want %q", replaced, tt.wantReplaced)
+			}
+			var got []key
+			for i, k := range s.Keys() {
+				if k.Index != i {
+					t.Errorf("key %s index = %d, want %d", k.Name, k.Index, i)
+				}
+				got = append(got, key{k.Name, k.Value})
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("keys after merge() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
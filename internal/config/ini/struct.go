@@ -0,0 +1,153 @@
+package ini
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ParseStruct parses a UE2 struct literal such as (A=1,B="x") into its fields.
+// Values are returned as written: quotes and nested structs are preserved,
+// use Unquote to get the raw string.
+func ParseStruct(value string) (map[string]string, error) {
+	fields, _, err := parseStruct(value)
+	return fields, err
+}
+
+// FormatStruct serializes fields into a UE2 struct literal.
+// Fields listed in order come first, the others are sorted by name.
+func FormatStruct(fields map[string]string, order []string) string {
+	names := make([]string, 0, len(fields))
+	seen := make(map[string]bool, len(fields))
+	for _, name := range order {
+		if _, ok := fields[name]; ok && !seen[name] {
+			names = append(names, name)
+			seen[name] = true
+		}
+	}
+
+	var rest []string
+	for name := range fields {
+		if !seen[name] {
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+	names = append(names, rest...)
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, name+"="+fields[name])
+	}
+	return "(" + strings.Join(parts, ",") + ")"
+}
+
+// Quote returns s as a UE2 quoted string.
+func Quote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
+
+// Unquote removes the surrounding quotes of a UE2 string, if any.
+func Unquote(s string) string {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		return strings.ReplaceAll(s[1:len(s)-1], `\"`, `"`)
+	}
+	return s
+}
+
+func parseStruct(value string) (map[string]string, []string, error) {
+	v := strings.TrimSpace(value)
+	if len(v) < 2 || v[0] != '(' || v[len(v)-1] != ')' {
+		return nil, nil, fmt.Errorf("not a struct literal: %s", value)
+	}
+
+	parts, err := splitStructFields(v[1 : len(v)-1])
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid struct literal %s: %w", value, err)
+	}
+
+	fields := make(map[string]string, len(parts))
+	order := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return nil, nil, fmt.Errorf("invalid struct field %q in %s", part, value)
+		}
+
+		name := strings.TrimSpace(kv[0])
+		if _, exists := fields[name]; !exists {
+			order = append(order, name)
+		}
+		fields[name] = strings.TrimSpace(kv[1])
+	}
+	return fields, order, nil
+}
+
+// splitStructFields splits the struct body on top-level commas,
+// ignoring the ones in quoted strings and nested structs.
+func splitStructFields(body string) ([]string, error) {
+	var parts []string
+	var quoted, escaped bool
+	depth, start := 0, 0
+
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		switch {
+		case escaped:
+			escaped = false
+		case quoted && c == '\\':
+			escaped = true
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '(':
+			depth++
+		case c == ')':
+			if depth--; depth < 0 {
+				return nil, fmt.Errorf("unbalanced parentheses")
+			}
+		case c == ',' && depth == 0:
+			parts = append(parts, body[start:i])
+			start = i + 1
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("unterminated string")
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("unbalanced parentheses")
+	}
+	return append(parts, body[start:]), nil
+}
+
+func (f *GenericIniFile) GetKeyStruct(section string, key string) (map[string]string, error) {
+	if !f.HasKey(section, key) {
+		return nil, fmt.Errorf("key not found: [%s] %s", section, key)
+	}
+	return ParseStruct(f.GetKey(section, key, ""))
+}
+
+func (f *GenericIniFile) GetKeysStruct(section string, key string) ([]map[string]string, error) {
+	var ret []map[string]string
+	for _, value := range f.GetKeys(section, key) {
+		fields, err := ParseStruct(value)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, fields)
+	}
+	return ret, nil
+}
+
+// SetKeyStruct sets key to the struct literal of fields.
+// When updating a unique key, the existing fields order is preserved.
+func (f *GenericIniFile) SetKeyStruct(section string, key string, fields map[string]string, unique bool) bool {
+	var order []string
+	if unique && f.HasKey(section, key) {
+		_, order, _ = parseStruct(f.GetKey(section, key, ""))
+	}
+	return f.setKeyValue(section, key, FormatStruct(fields, order), unique)
+}
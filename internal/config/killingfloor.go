@@ -34,6 +34,7 @@ const (
 	kfKeyShortName          = "ShortName"
 	kfKeyGamePort           = "Port"
 	kfKeyWebAdminPort       = "ListenPort"
+	kfKeyWebAdminAddress    = "ServerName"
 	kfKeyGameSpyPort        = "OldQueryPortNumber"
 	kfKeyGameDifficulty     = "GameDifficulty"
 	kfKeyGameLength         = "KFGameLength"
@@ -58,6 +59,20 @@ const (
 	kfKeyEnableLowGore      = "bLowGore"
 	kfKeyMaxInternetRate    = "MaxInternetClientRate"
 
+	// Gameplay
+	kfKeyStartingCash     = "StartingCash"
+	kfKeyMinRespawnCash   = "MinRespawnCash"
+	kfKeyTimeBetweenWaves = "TimeBetweenWaves"
+	kfKeyWaveFunding      = "bWaveFunding"
+	kfKeyMaxZombiesOnce   = "MaxZombiesOnce"
+	kfKeyUseEndGameBoss   = "bUseEndGameBoss"
+
+	// Custom game length
+	kfKeyMonsterClasses = "MonsterClasses"
+	kfKeyMonsterSquad   = "MonsterSquad"
+	kfKeyWaves          = "Waves"
+	kfKeyFinalWave      = "FinalWave"
+
 	// Mutators
 	kfKeyServerActors = "ServerActors"
 
@@ -105,6 +120,10 @@ func (kf *KFIniFile) GetWebAdminPort() int {
 	return kf.GetKeyInt(kfSectionWebServer, kfKeyWebAdminPort, settings.DefaultWebAdminPort)
 }
 
+func (kf *KFIniFile) GetWebAdminAddress() string {
+	return kf.GetKey(kfSectionWebServer, kfKeyWebAdminAddress, settings.DefaultWebAdminIP)
+}
+
 func (kf *KFIniFile) GetGameSpyPort() int {
 	return kf.GetKeyInt(kfSectionUdpGamespyQuery, kfKeyGameSpyPort, settings.DefaultGameSpyPort)
 }
@@ -117,6 +136,30 @@ func (kf *KFIniFile) GetGameLength() int {
 	return kf.GetKeyInt(kf.gameMode, kfKeyGameLength, settings.DefaultInternalGameLength)
 }
 
+func (kf *KFIniFile) GetStartingCash() int {
+	return kf.GetKeyInt(kf.gameMode, kfKeyStartingCash, settings.DefaultStartingCash)
+}
+
+func (kf *KFIniFile) GetMinRespawnCash() int {
+	return kf.GetKeyInt(kf.gameMode, kfKeyMinRespawnCash, settings.DefaultMinRespawnCash)
+}
+
+func (kf *KFIniFile) GetTimeBetweenWaves() int {
+	return kf.GetKeyInt(kf.gameMode, kfKeyTimeBetweenWaves, settings.DefaultTimeBetweenWaves)
+}
+
+func (kf *KFIniFile) IsWaveFundingEnabled() bool {
+	return kf.GetKeyBool(kf.gameMode, kfKeyWaveFunding, !settings.DefaultDisableWaveFunding)
+}
+
+func (kf *KFIniFile) GetMaxZombiesOnce() int {
+	return kf.GetKeyInt(kf.gameMode, kfKeyMaxZombiesOnce, settings.DefaultMaxZombiesOnce)
+}
+
+func (kf *KFIniFile) IsEndGameBossEnabled() bool {
+	return kf.GetKeyBool(kf.gameMode, kfKeyUseEndGameBoss, !settings.DefaultDisableEndGameBoss)
+}
+
 func (kf *KFIniFile) GetFriendlyFireRate() float64 {
 	return kf.GetKeyFloat(kf.gameMode, kfKeyFriendlyFireRate, settings.DefaultFriendlyFire)
 }
@@ -213,6 +256,10 @@ func (kf *KFIniFile) SetWebAdminPort(port int) bool {
 	return kf.SetKeyInt(kfSectionWebServer, kfKeyWebAdminPort, port, true)
 }
 
+func (kf *KFIniFile) SetWebAdminAddress(address string) bool {
+	return kf.SetKey(kfSectionWebServer, kfKeyWebAdminAddress, address, true)
+}
+
 func (kf *KFIniFile) SetGameSpyPort(port int) bool {
 	return kf.SetKeyInt(kfSectionUdpGamespyQuery, kfKeyGameSpyPort, port, true)
 }
@@ -225,6 +272,48 @@ func (kf *KFIniFile) SetGameLength(length int) bool {
 	return kf.SetKeyInt(kf.gameMode, kfKeyGameLength, length, true)
 }
 
+func (kf *KFIniFile) SetStartingCash(cash int) bool {
+	return kf.SetKeyInt(kf.gameMode, kfKeyStartingCash, cash, true)
+}
+
+func (kf *KFIniFile) SetMinRespawnCash(cash int) bool {
+	return kf.SetKeyInt(kf.gameMode, kfKeyMinRespawnCash, cash, true)
+}
+
+func (kf *KFIniFile) SetTimeBetweenWaves(seconds int) bool {
+	return kf.SetKeyInt(kf.gameMode, kfKeyTimeBetweenWaves, seconds, true)
+}
+
+func (kf *KFIniFile) SetWaveFundingEnabled(enabled bool) bool {
+	return kf.SetKeyBool(kf.gameMode, kfKeyWaveFunding, enabled, true)
+}
+
+func (kf *KFIniFile) SetMaxZombiesOnce(zombies int) bool {
+	return kf.SetKeyInt(kf.gameMode, kfKeyMaxZombiesOnce, zombies, true)
+}
+
+func (kf *KFIniFile) SetEndGameBossEnabled(enabled bool) bool {
+	return kf.SetKeyBool(kf.gameMode, kfKeyUseEndGameBoss, enabled, true)
+}
+
+// SetCustomWaves writes the waves used by the custom game length.
+// Squads are stored in MonsterSquad and selected by each wave through its WaveMask.
+func (kf *KFIniFile) SetCustomWaves(waves *CustomWaves) error {
+	if !kf.SetArray(kf.gameMode, kfKeyMonsterClasses, waves.monsterClasses(), false) {
+		return fmt.Errorf("unable to set %s.%s", kf.gameMode, kfKeyMonsterClasses)
+	}
+	if !kf.SetArray(kf.gameMode, kfKeyMonsterSquad, waves.Squads(), false) {
+		return fmt.Errorf("unable to set %s.%s", kf.gameMode, kfKeyMonsterSquad)
+	}
+	if !kf.SetArray(kf.gameMode, kfKeyWaves, waves.waves(), true) {
+		return fmt.Errorf("unable to set %s.%s", kf.gameMode, kfKeyWaves)
+	}
+	if !kf.SetKeyInt(kf.gameMode, kfKeyFinalWave, len(waves.Waves), true) {
+		return fmt.Errorf("unable to set %s.%s to %d", kf.gameMode, kfKeyFinalWave, len(waves.Waves))
+	}
+	return nil
+}
+
 func (kf *KFIniFile) SetFriendlyFireRate(rate float64) bool {
 	return kf.SetKeyFloat(kf.gameMode, kfKeyFriendlyFireRate, rate, true)
 }
@@ -335,6 +424,23 @@ func (kf *KFIniFile) ServerMutatorExists(mutator string) bool {
 	return false
 }
 
+// GetServerMutators returns the server actors, except the base ones.
+func (kf *KFIniFile) GetServerMutators() []string {
+	mutators := []string{}
+	for _, actor := range kf.GetKeys(kfSectionGameEngine, kfKeyServerActors) {
+		act := strings.TrimSpace(actor)
+		if act == "" {
+			continue
+		}
+		switch strings.ToLower(act) {
+		case kfBaseActorMasterServer, kfBaseActorWebServer:
+			continue
+		}
+		mutators = append(mutators, act)
+	}
+	return mutators
+}
+
 func (kf *KFIniFile) ClearServerMutators() error {
 	// Get server actors
 	actors := kf.GetKeys(kfSectionGameEngine, kfKeyServerActors)
@@ -373,11 +479,19 @@ func (kf *KFIniFile) SetServerMutators(mutators []string) error {
 	return nil
 }
 
+func (kf *KFIniFile) GetMaplist(sectionName string) []string {
+	maps := kf.GetArray(sectionName, kfKeyMaps)
+	if maps == nil {
+		return []string{}
+	}
+	return maps
+}
+
 func (kf *KFIniFile) ClearMaplist(sectionName string) error {
 	if section := kf.GetSection(sectionName); section != nil {
-		section.DeleteKey(kfKeyMaps)
+		section.DeleteArray(kfKeyMaps)
 
-		if len(section.GetKeys(kfKeyMaps)) > 0 {
+		if len(section.GetArray(kfKeyMaps)) > 0 {
 			return fmt.Errorf("unable to clear the maplist: %s", sectionName)
 		}
 	}
@@ -386,19 +500,16 @@ func (kf *KFIniFile) ClearMaplist(sectionName string) error {
 
 func (kf *KFIniFile) SetMaplist(sectionName string, maps []string) error {
 	// Create the section if it doesn't exist
-	section := kf.GetSection(sectionName)
-	if section == nil {
+	if kf.GetSection(sectionName) == nil {
 		if added := kf.SetKeyInt(sectionName, kfKeyMapNum, 0, true); !added {
 			return fmt.Errorf("unable to create the maplist section '%s'", sectionName)
 		}
-		section = kf.GetSection(sectionName)
-	} else {
-		// Clear all existing maps
-		section.DeleteKey(kfKeyMaps)
 	}
 
-	for _, m := range maps {
-		section.SetKey(kfKeyMaps, m)
+	// Keep the indexed form (Maps[0]=) if the maplist already uses it
+	indexed := kf.IsIndexedArray(sectionName, kfKeyMaps)
+	if !kf.SetArray(sectionName, kfKeyMaps, maps, indexed) {
+		return fmt.Errorf("unable to set the maplist: %s", sectionName)
 	}
 	return nil
 }
@@ -0,0 +1,218 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/K4rian/kfdsl/internal/config/ini"
+)
+
+type LintSeverity string
+
+const (
+	LintError   LintSeverity = "error"
+	LintWarning LintSeverity = "warning"
+)
+
+type LintIssue struct {
+	Severity LintSeverity `json:"severity"`
+	Section  string       `json:"section,omitempty"`
+	Key      string       `json:"key,omitempty"`
+	Value    string       `json:"value,omitempty"`
+	Message  string       `json:"message"`
+}
+
+type LintReport struct {
+	File     string      `json:"file"`
+	Errors   int         `json:"errors"`
+	Warnings int         `json:"warnings"`
+	Issues   []LintIssue `json:"issues"`
+}
+
+func (r *LintReport) add(severity LintSeverity, section, key, value, format string, args ...any) {
+	r.Issues = append(r.Issues, LintIssue{
+		Severity: severity,
+		Section:  section,
+		Key:      key,
+		Value:    value,
+		Message:  fmt.Sprintf(format, args...),
+	})
+	if severity == LintError {
+		r.Errors++
+	} else {
+		r.Warnings++
+	}
+}
+
+// Lint checks the server configuration file against the schema of known
+// sections and keys. The packages referenced by the server actors and
+// packages, as well as the given mutators, must exist in systemDir.
+func Lint(iniFile ServerIniFile, systemDir string, mutators []string) *LintReport {
+	report := &LintReport{File: iniFile.FilePath(), Issues: []LintIssue{}}
+
+	packages, err := listPackages(systemDir)
+	if err != nil {
+		report.add(LintWarning, "", "", "", "unable to list the packages of %s, packages are not checked: %v", systemDir, err)
+	}
+
+	// Index the schema by lowercase names, UE2 names are case-insensitive
+	sectionNames := make(map[string]string, len(serverIniSchema))
+	for name := range serverIniSchema {
+		sectionNames[strings.ToLower(name)] = name
+	}
+
+	for _, section := range iniFile.Sections() {
+		schemaName, known := sectionNames[strings.ToLower(section.Name())]
+		if !known {
+			if suggestion := closestName(section.Name(), serverIniSchema); suggestion != "" {
+				report.add(LintWarning, section.Name(), "", "", "unknown section, did you mean [%s]?", suggestion)
+			}
+			continue
+		}
+		lintSection(report, section.Name(), section.Keys(), serverIniSchema[schemaName], packages)
+	}
+
+	for _, mutator := range mutators {
+		if packages != nil && !packageExists(packages, mutator) {
+			report.add(LintError, "", "Mutators", mutator, "package %s not found in %s", packageName(mutator), systemDir)
+		}
+	}
+	return report
+}
+
+func lintSection(report *LintReport, section string, keys []*ini.IniKey, schema map[string]keySchema, packages map[string]bool) {
+	keyNames := make(map[string]string, len(schema))
+	for name := range schema {
+		keyNames[strings.ToLower(name)] = name
+	}
+
+	counts := map[string]int{}
+	for _, key := range keys {
+		// Indexed array elements (Key[N]) are not checked
+		if strings.Contains(key.Name, "[") {
+			continue
+		}
+
+		schemaName, known := keyNames[strings.ToLower(key.Name)]
+		if !known {
+			if suggestion := closestName(key.Name, schema); suggestion != "" {
+				report.add(LintWarning, section, key.Name, key.Value, "unknown key, did you mean %s?", suggestion)
+			}
+			continue
+		}
+
+		ks := schema[schemaName]
+		counts[schemaName]++
+		if counts[schemaName] == 2 && !ks.multi {
+			report.add(LintError, section, key.Name, "", "key defined more than once, only one value is used")
+		}
+
+		if msg := lintValue(key.Value, ks); msg != "" {
+			report.add(LintError, section, key.Name, key.Value, "%s", msg)
+		}
+		if ks.packages && packages != nil && key.Value != "" && !packageExists(packages, key.Value) {
+			report.add(LintError, section, key.Name, key.Value, "package %s not found", packageName(key.Value))
+		}
+	}
+}
+
+func lintValue(value string, ks keySchema) string {
+	var num float64
+
+	switch ks.kind {
+	case kindString:
+		return ""
+	case kindBool:
+		switch strings.ToLower(value) {
+		case "true", "false", "1", "0":
+			return ""
+		}
+		return fmt.Sprintf("invalid value, expected %s", ks.kind)
+	case kindInt:
+		n, err := strconv.Atoi(strings.TrimPrefix(value, "+"))
+		if err != nil {
+			return fmt.Sprintf("invalid value, expected %s", ks.kind)
+		}
+		num = float64(n)
+	case kindFloat:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Sprintf("invalid value, expected %s", ks.kind)
+		}
+		num = f
+	}
+
+	if ks.min < ks.max && (num < ks.min || num > ks.max) {
+		return fmt.Sprintf("value out of range (%v-%v)", ks.min, ks.max)
+	}
+	if len(ks.allowed) > 0 && !slices.Contains(ks.allowed, num) {
+		return fmt.Sprintf("invalid value, expected one of %v", ks.allowed)
+	}
+	return ""
+}
+
+// listPackages returns the lowercase names of the .u packages of dir.
+func listPackages(dir string) (map[string]bool, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	packages := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		name := strings.ToLower(e.Name())
+		if !e.IsDir() && strings.HasSuffix(name, ".u") {
+			packages[strings.TrimSuffix(name, ".u")] = true
+		}
+	}
+	return packages, nil
+}
+
+// packageName returns the package of a Package.Class reference.
+func packageName(ref string) string {
+	name, _, _ := strings.Cut(strings.TrimSpace(ref), ".")
+	return name
+}
+
+func packageExists(packages map[string]bool, ref string) bool {
+	return packages[strings.ToLower(packageName(ref))]
+}
+
+// closestName returns the name of m that is one typo away from name, if any.
+func closestName[T any](name string, m map[string]T) string {
+	lower := strings.ToLower(name)
+	best, bestDist := "", 2
+	for candidate := range m {
+		lc := strings.ToLower(candidate)
+		if lc == lower {
+			return ""
+		}
+		if d := levenshtein(lower, lc); d < bestDist {
+			best, bestDist = candidate, d
+		}
+	}
+	return best
+}
+
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,138 @@
+package config
+
+// valueKind is the expected type of an ini value.
+type valueKind int
+
+const (
+	kindString valueKind = iota
+	kindBool
+	kindInt
+	kindFloat
+)
+
+func (k valueKind) String() string {
+	switch k {
+	case kindBool:
+		return "bool"
+	case kindInt:
+		return "int"
+	case kindFloat:
+		return "float"
+	}
+	return "string"
+}
+
+// keySchema describes a known key of the server configuration file.
+type keySchema struct {
+	kind     valueKind
+	min, max float64   // Allowed range, if min < max
+	allowed  []float64 // Allowed values, if any
+	multi    bool      // The key can be defined several times
+	packages bool      // Values reference UE2 packages (Package.Class or Package)
+}
+
+var (
+	portKey    = keySchema{kind: kindInt, min: 1024, max: 65535}
+	boolKey    = keySchema{kind: kindBool}
+	stringKey  = keySchema{kind: kindString}
+	cashKey    = keySchema{kind: kindInt, min: 0, max: 1000000}
+	rateKey    = keySchema{kind: kindInt, min: 2600, max: 25000}
+	tickKey    = keySchema{kind: kindInt, min: 10, max: 120}
+	playersKey = keySchema{kind: kindInt, min: 0, max: 32}
+)
+
+// gameTypeSchema holds the keys shared by every game type section.
+var gameTypeSchema = map[string]keySchema{
+	kfKeyGameLength:       {kind: kindInt, min: 0, max: 3},
+	kfKeyFriendlyFireRate: {kind: kindFloat, min: 0, max: 1},
+	kfKeySpecimenType:     stringKey,
+	kfKeyStartingCash:     cashKey,
+	kfKeyMinRespawnCash:   cashKey,
+	kfKeyTimeBetweenWaves: {kind: kindInt, min: 5, max: 600},
+	kfKeyWaveFunding:      boolKey,
+	kfKeyMaxZombiesOnce:   {kind: kindInt, min: 1, max: 128},
+	kfKeyUseEndGameBoss:   boolKey,
+	kfKeyMonsterClasses:   {kind: kindString, multi: true},
+	kfKeyMonsterSquad:     {kind: kindString, multi: true},
+	kfKeyFinalWave:        {kind: kindInt, min: 1, max: 16},
+}
+
+// serverIniSchema lists the known sections and keys of the server configuration file.
+// Keys that aren't listed are not checked, except for likely typos.
+var serverIniSchema = map[string]map[string]keySchema{
+	kfSectionURL: {
+		kfKeyGamePort: portKey,
+	},
+	kfSectionGameEngine: {
+		kfKeyServerActors: {kind: kindString, multi: true, packages: true},
+		"ServerPackages":  {kind: kindString, multi: true, packages: true},
+		"CacheSizeMegs":   {kind: kindInt, min: 1, max: 1024},
+	},
+	kfSectionGameInfo: {
+		kfKeyGameDifficulty:    {kind: kindFloat, allowed: []float64{1, 2, 4, 5, 7}},
+		kfKeyMaxPlayers:        playersKey,
+		kfKeyMaxSpectators:     playersKey,
+		kfKeyEnableAdminPause:  boolKey,
+		kfKeyEnableWeaponThrow: boolKey,
+		kfKeyWeaponShakeEffect: boolKey,
+		kfKeyEnableThirdPerson: boolKey,
+		kfKeyEnableLowGore:     boolKey,
+		"bNoBots":              boolKey,
+		"bChangeLevels":        boolKey,
+		"GameSpeed":            {kind: kindFloat, min: 0.1, max: 10},
+		"MaxIdleTime":          {kind: kindFloat},
+	},
+	kfSectionGameReplication: {
+		kfKeyServerName: stringKey,
+		kfKeyShortName:  stringKey,
+		kfKeyRegion:     {kind: kindInt, min: 0, max: 255},
+		kfKeyAdminName:  stringKey,
+		kfKeyAdminMail:  stringKey,
+		kfKeyMOTD:       stringKey,
+	},
+	kfSectionAccessControl: {
+		kfKeyPassword:      stringKey,
+		kfKeyAdminPassword: stringKey,
+		"bBanByID":         boolKey,
+	},
+	kfSectionTcpNetDriver: {
+		kfKeyMaxInternetRate:   rateKey,
+		"MaxClientRate":        rateKey,
+		"NetServerMaxTickRate": tickKey,
+		"LanServerMaxTickRate": tickKey,
+		"AllowDownloads":       boolKey,
+		"DownloadManagers":     {kind: kindString, multi: true, packages: true},
+	},
+	kfSectionUdpGamespyQuery: {
+		kfKeyGameSpyPort: portKey,
+	},
+	kfSectionWebServer: {
+		kfKeyWebAdminPort:    portKey,
+		kfKeyWebAdminAddress: stringKey,
+		kfKeyEnableWebAdmin:  boolKey,
+	},
+	kfSectionHttpDownload: {
+		kfKeyRedirectURL:  stringKey,
+		"UseCompression":  boolKey,
+		"ProxyServerPort": {kind: kindInt, min: 0, max: 65535},
+	},
+	kfSectionVotingHandler: {
+		kfKeyEnableMapVote:      boolKey,
+		kfKeyMapVoteRepeatLimit: {kind: kindInt, min: 0, max: 100},
+		kfKeyMapListLoaderType:  stringKey,
+		"VoteTimeLimit":         {kind: kindInt, min: 0, max: 600},
+		"bKickVote":             boolKey,
+		"KickPercent":           {kind: kindInt, min: 0, max: 100},
+	},
+	kfSectionDefaultMapListLoader: {
+		kfKeyUseMapList:      boolKey,
+		kfKeyMapNamePrefixes: stringKey,
+	},
+	"KFmod.KFMaplist": {
+		kfKeyMapNum: {kind: kindInt, min: 0},
+		kfKeyMaps:   {kind: kindString, multi: true},
+	},
+	"KFmod.KFGameType":            gameTypeSchema,
+	"KFStoryGame.KFstoryGameInfo": gameTypeSchema,
+	"KFCharPuppets.TOYGameInfo":   gameTypeSchema,
+}
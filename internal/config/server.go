@@ -1,17 +1,27 @@
 package config
 
+import "github.com/K4rian/kfdsl/internal/config/ini"
+
 type ServerIniFile interface {
 	FilePath() string
 	Load(filePath string) error
 	Save(filePath string) error
+	Sections() []*ini.IniSection
 
 	GetServerName() string
 	GetShortName() string
 	GetGamePort() int
 	GetWebAdminPort() int
+	GetWebAdminAddress() string
 	GetGameSpyPort() int
 	GetGameDifficulty() int
 	GetGameLength() int
+	GetStartingCash() int
+	GetMinRespawnCash() int
+	GetTimeBetweenWaves() int
+	IsWaveFundingEnabled() bool
+	GetMaxZombiesOnce() int
+	IsEndGameBossEnabled() bool
 	GetFriendlyFireRate() float64
 	GetMaxPlayers() int
 	GetMaxSpectators() int
@@ -37,9 +47,17 @@ type ServerIniFile interface {
 	SetShortName(shortname string) bool
 	SetGamePort(port int) bool
 	SetWebAdminPort(port int) bool
+	SetWebAdminAddress(address string) bool
 	SetGameSpyPort(port int) bool
 	SetGameDifficulty(difficulty int) bool
 	SetGameLength(length int) bool
+	SetStartingCash(cash int) bool
+	SetMinRespawnCash(cash int) bool
+	SetTimeBetweenWaves(seconds int) bool
+	SetWaveFundingEnabled(enabled bool) bool
+	SetMaxZombiesOnce(zombies int) bool
+	SetEndGameBossEnabled(enabled bool) bool
+	SetCustomWaves(waves *CustomWaves) error
 	SetFriendlyFireRate(rate float64) bool
 	SetMaxPlayers(players int) bool
 	SetMaxSpectators(spectators int) bool
@@ -62,9 +80,13 @@ type ServerIniFile interface {
 	SetMaxInternetClientRate(rate int) bool
 
 	ServerMutatorExists(mutator string) bool
+	GetServerMutators() []string
 	ClearServerMutators() error
 	SetServerMutators(mutators []string) error
 
+	GetMaplist(sectionName string) []string
 	ClearMaplist(sectionName string) error
 	SetMaplist(sectionName string, maps []string) error
+
+	ApplyOverride(o *ini.Override) (bool, error)
 }
@@ -0,0 +1,162 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+const (
+	maxCustomWaves  = 16 // Size of the KFGameType Waves static array
+	maxCustomSquads = 32 // A wave selects its squads through a 32-bit mask
+	maxWaveByte     = 255
+)
+
+// DefaultMonsterClasses maps the stock specimens to their squad IDs.
+var DefaultMonsterClasses = map[string]string{
+	"A": "KFChar.ZombieClot",
+	"B": "KFChar.ZombieCrawler",
+	"C": "KFChar.ZombieGorefast",
+	"D": "KFChar.ZombieStalker",
+	"E": "KFChar.ZombieScrake",
+	"F": "KFChar.ZombieFleshpound",
+	"G": "KFChar.ZombieBloat",
+	"H": "KFChar.ZombieSiren",
+	"I": "KFChar.ZombieHusk",
+}
+
+// A squad is a list of <count><monster ID>, e.g. 4A1G
+var squadPattern = regexp.MustCompile(`^([0-9]+[A-Z])+$`)
+var squadPartPattern = regexp.MustCompile(`([0-9]+)([A-Z])`)
+
+// CustomWave defines a wave of the custom game length.
+type CustomWave struct {
+	Squads      []string `json:"squads"`       // Squads spawned during the wave, e.g. "4A1G"
+	MaxMonsters int      `json:"max_monsters"` // Total number of specimens
+	Duration    int      `json:"duration"`     // Wave duration (0-255)
+	Difficulty  float64  `json:"difficulty"`   // Wave difficulty
+}
+
+// CustomWaves is the declarative spec of the custom game length (KFGameLength=3).
+type CustomWaves struct {
+	Monsters map[string]string `json:"monsters,omitempty"` // Monster ID -> class, defaults to DefaultMonsterClasses
+	Waves    []CustomWave      `json:"waves"`
+}
+
+// ParseWavesFile reads and validates a custom waves JSON file.
+func ParseWavesFile(filePath string) (*CustomWaves, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var cw CustomWaves
+	if err := json.Unmarshal(data, &cw); err != nil {
+		return nil, err
+	}
+	if len(cw.Monsters) == 0 {
+		cw.Monsters = DefaultMonsterClasses
+	}
+	if err := cw.Validate(); err != nil {
+		return nil, err
+	}
+	return &cw, nil
+}
+
+// Validate checks the waves against the limits of KFGameType.
+func (cw *CustomWaves) Validate() error {
+	if len(cw.Waves) == 0 || len(cw.Waves) > maxCustomWaves {
+		return fmt.Errorf("custom waves: between 1 and %d waves are required, got %d", maxCustomWaves, len(cw.Waves))
+	}
+
+	for id, class := range cw.Monsters {
+		if len(id) != 1 || id[0] < 'A' || id[0] > 'Z' {
+			return fmt.Errorf("custom waves: invalid monster ID '%s', a single uppercase letter is expected", id)
+		}
+		if class == "" {
+			return fmt.Errorf("custom waves: monster %s has no class", id)
+		}
+	}
+
+	for i, w := range cw.Waves {
+		if len(w.Squads) == 0 {
+			return fmt.Errorf("custom waves: wave %d has no squad", i+1)
+		}
+		for _, squad := range w.Squads {
+			if !squadPattern.MatchString(squad) {
+				return fmt.Errorf("custom waves: wave %d: invalid squad '%s'", i+1, squad)
+			}
+			for _, m := range squadPartPattern.FindAllStringSubmatch(squad, -1) {
+				if _, ok := cw.Monsters[m[2]]; !ok {
+					return fmt.Errorf("custom waves: wave %d: squad '%s' uses the undefined monster %s", i+1, squad, m[2])
+				}
+			}
+		}
+		if w.MaxMonsters < 1 || w.MaxMonsters > maxWaveByte {
+			return fmt.Errorf("custom waves: wave %d: max_monsters must be between 1 and %d", i+1, maxWaveByte)
+		}
+		if w.Duration < 0 || w.Duration > maxWaveByte {
+			return fmt.Errorf("custom waves: wave %d: duration must be between 0 and %d", i+1, maxWaveByte)
+		}
+		if w.Difficulty < 0 {
+			return fmt.Errorf("custom waves: wave %d: difficulty can't be negative", i+1)
+		}
+	}
+
+	if squads := cw.Squads(); len(squads) > maxCustomSquads {
+		return fmt.Errorf("custom waves: at most %d distinct squads can be used, got %d", maxCustomSquads, len(squads))
+	}
+	return nil
+}
+
+// Squads returns the distinct squads of all waves, in order of appearance.
+func (cw *CustomWaves) Squads() []string {
+	var squads []string
+	seen := map[string]bool{}
+	for _, w := range cw.Waves {
+		for _, squad := range w.Squads {
+			if !seen[squad] {
+				seen[squad] = true
+				squads = append(squads, squad)
+			}
+		}
+	}
+	return squads
+}
+
+// monsterClasses returns the MonsterClasses entries, sorted by monster ID.
+func (cw *CustomWaves) monsterClasses() []string {
+	ids := make([]string, 0, len(cw.Monsters))
+	for id := range cw.Monsters {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	values := make([]string, 0, len(ids))
+	for _, id := range ids {
+		values = append(values, fmt.Sprintf(`(MClassName="%s",Mid="%s")`, cw.Monsters[id], id))
+	}
+	return values
+}
+
+// waves returns the Waves entries, each selecting its squads through WaveMask.
+func (cw *CustomWaves) waves() []string {
+	squadIndex := map[string]int{}
+	for i, squad := range cw.Squads() {
+		squadIndex[squad] = i
+	}
+
+	values := make([]string, 0, len(cw.Waves))
+	for _, w := range cw.Waves {
+		var mask uint32
+		for _, squad := range w.Squads {
+			mask |= 1 << squadIndex[squad]
+		}
+		values = append(values, fmt.Sprintf("(WaveMask=%d,WaveMaxMonsters=%d,WaveDuration=%d,WaveDifficulty=%s)",
+			int32(mask), w.MaxMonsters, w.Duration, strconv.FormatFloat(w.Difficulty, 'f', 6, 64)))
+	}
+	return values
+}
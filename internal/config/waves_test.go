@@ -0,0 +1,131 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseWavesFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "valid", content: `{"waves": [{"squads": ["4A", "2B1G"], "max_monsters": 30, "duration": 255, "difficulty": 0.5}]}`},
+		{name: "custom monsters", content: `{"monsters": {"Z": "MyZeds.Zed"}, "waves": [{"squads": ["1Z"], "max_monsters": 1}]}`},
+		{name: "invalid json", content: `{"waves": [`, wantErr: "unexpected end"},
+		{name: "no waves", content: `{"waves": []}`, wantErr: "between 1 and 16 waves"},
+		{name: "no squad", content: `{"waves": [{"squads": [], "max_monsters": 1}]}`, wantErr: "wave 1 has no squad"},
+		{name: "invalid squad", content: `{"waves": [{"squads": ["A4"], "max_monsters": 1}]}`, wantErr: "invalid squad 'A4'"},
+		{name: "undefined monster", content: `{"waves": [{"squads": ["1Z"], "max_monsters": 1}]}`, wantErr: "undefined monster Z"},
+		{name: "invalid monster ID", content: `{"monsters": {"ab": "X.Y"}, "waves": [{"squads": ["1A"], "max_monsters": 1}]}`, wantErr: "invalid monster ID 'ab'"},
+		{name: "monster without class", content: `{"monsters": {"A": ""}, "waves": [{"squads": ["1A"], "max_monsters": 1}]}`, wantErr: "monster A has no class"},
+		{name: "no max monsters", content: `{"waves": [{"squads": ["1A"]}]}`, wantErr: "max_monsters must be between 1 and 255"},
+		{name: "too many monsters", content: `{"waves": [{"squads": ["1A"], "max_monsters": 256}]}`, wantErr: "max_monsters must be between 1 and 255"},
+		{name: "duration", content: `{"waves": [{"squads": ["1A"], "max_monsters": 1, "duration": 256}]}`, wantErr: "duration must be between 0 and 255"},
+		{name: "difficulty", content: `{"waves": [{"squads": ["1A"], "max_monsters": 1, "difficulty": -1}]}`, wantErr: "difficulty can't be negative"},
+		{name: "too many waves", content: wavesJSON(17, 1), wantErr: "between 1 and 16 waves"},
+		{name: "too many squads", content: wavesJSON(1, 33), wantErr: "at most 32 distinct squads"},
+		{name: "max squads", content: wavesJSON(16, 32)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "waves.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			cw, err := ParseWavesFile(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseWavesFile() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseWavesFile() error = %v", err)
+			}
+			if len(cw.Monsters) == 0 {
+				t.Error("ParseWavesFile() returned no monsters")
+			}
+		})
+	}
+}
+
+// wavesJSON returns a waves file of n waves, using squads distinct squads
+// in total.
+func wavesJSON(n int, squads int) string {
+	var waves []string
+	for i := 0; i < n; i++ {
+		var names []string
+		for s := i; s < squads; s += n {
+			names = append(names, fmt.Sprintf(`"%dA"`, s+1))
+		}
+		if len(names) == 0 {
+			names = append(names, `"1A"`)
+		}
+		waves = append(waves, fmt.Sprintf(`{"squads": [%s], "max_monsters": 10}`, strings.Join(names, ",")))
+	}
+	return fmt.Sprintf(`{"waves": [%s]}`, strings.Join(waves, ","))
+}
+
+func TestCustomWavesEntries(t *testing.T) {
+	cw := &CustomWaves{
+		Monsters: map[string]string{"B": "KFChar.ZombieCrawler", "A": "KFChar.ZombieClot"},
+		Waves: []CustomWave{
+			{Squads: []string{"4A", "2B"}, MaxMonsters: 20, Duration: 255, Difficulty: 0.5},
+			{Squads: []string{"2B", "1A1B"}, MaxMonsters: 30, Duration: 10, Difficulty: 1},
+		},
+	}
+
+	if got, want := cw.Squads(), []string{"4A", "2B", "1A1B"}; !slices.Equal(got, want) {
+		t.Errorf("Squads() = %q, want %q", got, want)
+	}
+
+	wantClasses := []string{
+		`(MClassName="KFChar.ZombieClot",Mid="A")`,
+		`(MClassName="KFChar.ZombieCrawler",Mid="B")`,
+	}
+	if got := cw.monsterClasses(); !slices.Equal(got, wantClasses) {
+		t.Errorf("monsterClasses() = %q, want %q", got, wantClasses)
+	}
+
+	wantWaves := []string{
+		"(WaveMask=3,WaveMaxMonsters=20,WaveDuration=255,WaveDifficulty=0.500000)",
+		"(WaveMask=6,WaveMaxMonsters=30,WaveDuration=10,WaveDifficulty=1.000000)",
+	}
+	if got := cw.waves(); !slices.Equal(got, wantWaves) {
+		t.Errorf("waves() = %q, want %q", got, wantWaves)
+	}
+}
+
+func TestCustomWavesMaskSign(t *testing.T) {
+	// WaveMask is a signed int in UnrealScript, the 32nd squad sets the sign bit
+	var squads []string
+	for i := 1; i <= maxCustomSquads; i++ {
+		squads = append(squads, fmt.Sprintf("%dA", i))
+	}
+	cw := &CustomWaves{
+		Monsters: DefaultMonsterClasses,
+		Waves: []CustomWave{
+			{Squads: squads[:maxCustomSquads-1], MaxMonsters: 1},
+			{Squads: squads[maxCustomSquads-1:], MaxMonsters: 1},
+		},
+	}
+	if err := cw.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	waves := cw.waves()
+	if !strings.HasPrefix(waves[0], "(WaveMask=2147483647,") {
+		t.Errorf("waves()[0] = %s, want WaveMask=2147483647", waves[0])
+	}
+	if !strings.HasPrefix(waves[1], "(WaveMask=-2147483648,") {
+		t.Errorf("waves()[1] = %s, want WaveMask=-2147483648", waves[1])
+	}
+}
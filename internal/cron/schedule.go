@@ -0,0 +1,194 @@
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed cron expression: minute, hour, day of month, month and
+// day of week. Like Vixie cron, a day matches when either its day of month
+// or its day of week matches, unless one of these fields is a *.
+type Schedule struct {
+	expr    string
+	minute  uint64
+	hour    uint64
+	dom     uint64
+	month   uint64
+	dow     uint64
+	domStar bool
+	dowStar bool
+}
+
+// fieldSpec describes a field of a cron expression.
+type fieldSpec struct {
+	name  string
+	min   int
+	max   int
+	names []string // Names of the values, starting at min
+}
+
+var fieldSpecs = [5]fieldSpec{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}},
+	{name: "day of week", min: 0, max: 7, names: []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}},
+}
+
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// horizon bounds the search of Next, for expressions that never match
+// (e.g. February 30th).
+const horizon = 5 // Years
+
+// Parse parses a 5-field cron expression, such as "0 5 * * *" or
+// "*/15 8-23 * * mon-fri", or one of the @daily, @hourly... macros.
+func Parse(expr string) (*Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	spec := expr
+	if macro, ok := macros[strings.ToLower(expr)]; ok {
+		spec = macro
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != len(fieldSpecs) {
+		return nil, fmt.Errorf("invalid cron expression '%s': %d fields expected", expr, len(fieldSpecs))
+	}
+
+	var bits [len(fieldSpecs)]uint64
+	for i, field := range fields {
+		b, err := fieldSpecs[i].parse(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron expression '%s': %w", expr, err)
+		}
+		bits[i] = b
+	}
+
+	// Sunday is either 0 or 7
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+
+	return &Schedule{
+		expr:    expr,
+		minute:  bits[0],
+		hour:    bits[1],
+		dom:     bits[2],
+		month:   bits[3],
+		dow:     bits[4],
+		domStar: strings.HasPrefix(fields[2], "*"),
+		dowStar: strings.HasPrefix(fields[4], "*"),
+	}, nil
+}
+
+// parse parses a comma-separated list of values, ranges and steps.
+func (f fieldSpec) parse(field string) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepStr); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid %s step: %s", f.name, part)
+			}
+		}
+
+		lo, hi := f.min, f.max
+		if rng != "*" {
+			loStr, hiStr, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = f.value(loStr); err != nil {
+				return 0, err
+			}
+			switch {
+			case isRange:
+				if hi, err = f.value(hiStr); err != nil {
+					return 0, err
+				}
+			case !hasStep:
+				hi = lo
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("invalid %s range: %s", f.name, rng)
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// value parses a single value, either a number or a name.
+func (f fieldSpec) value(s string) (int, error) {
+	for i, name := range f.names {
+		if strings.EqualFold(s, name) {
+			return f.min + i, nil
+		}
+	}
+
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("invalid %s: '%s', expected %d-%d", f.name, s, f.min, f.max)
+	}
+	return v, nil
+}
+
+// Next returns the first time matching c after t, in the location of t.
+// It returns the zero time when c never matches. The times skipped when the
+// clocks go forward never match, and the ones repeated when they go back
+// match once.
+func (c *Schedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(horizon, 0, 0)
+
+	for t.Before(limit) {
+		year, month, day := t.Date()
+		switch {
+		case c.month&(1<<uint(month)) == 0:
+			t = time.Date(year, month+1, 1, 0, 0, 0, 0, loc)
+		case !c.matchDay(t):
+			t = time.Date(year, month, day+1, 0, 0, 0, 0, loc)
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(year, month, day, t.Hour()+1, 0, 0, 0, loc)
+		case c.minute&(1<<uint(t.Minute())) == 0, repeatedTime(t):
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// repeatedTime reports whether the wall clock time of t already occurred an
+// hour earlier, when the clocks went back.
+func repeatedTime(t time.Time) bool {
+	earlier := t.Add(-time.Hour)
+	return earlier.Day() == t.Day() && earlier.Hour() == t.Hour() && earlier.Minute() == t.Minute()
+}
+
+func (c *Schedule) matchDay(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domStar || c.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+func (c *Schedule) String() string {
+	return c.expr
+}
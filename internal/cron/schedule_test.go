@@ -0,0 +1,94 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr bool
+	}{
+		{"0 5 * * *", false},
+		{"*/15 8-23 * * mon-fri", false},
+		{"0 0 1,15 jan-mar sun", false},
+		{"0 0 * * 7", false},
+		{"@daily", false},
+		{"@HOURLY", false},
+		{"", true},
+		{"* * * *", true},
+		{"* * * * * *", true},
+		{"60 * * * *", true},
+		{"* 24 * * *", true},
+		{"* * 0 * *", true},
+		{"* * * 13 *", true},
+		{"* * * * 8", true},
+		{"5-1 * * * *", true},
+		{"*/0 * * * *", true},
+		{"* * * foo *", true},
+		{"@weekdays", true},
+	}
+
+	for _, tt := range tests {
+		_, err := Parse(tt.expr)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Parse(%q) error = %v, wantErr %v", tt.expr, err, tt.wantErr)
+		}
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skipf("time zone database unavailable: %v", err)
+	}
+	utc := func(month time.Month, day, hour, min int) time.Time {
+		return time.Date(2026, month, day, hour, min, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name string
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{"same day", "0 5 * * *", utc(1, 1, 4, 59), utc(1, 1, 5, 0)},
+		{"after the match", "0 5 * * *", utc(1, 1, 5, 0), utc(1, 2, 5, 0)},
+		{"seconds are truncated", "0 5 * * *", utc(1, 1, 4, 59).Add(30 * time.Second), utc(1, 1, 5, 0)},
+		{"steps and weekdays", "*/15 8-23 * * mon-fri", utc(1, 3, 12, 0), utc(1, 5, 8, 0)},
+		{"step within the hour", "*/15 8-23 * * mon-fri", utc(1, 5, 8, 1), utc(1, 5, 8, 15)},
+		{"sunday as 7", "0 0 * * 7", utc(1, 1, 0, 0), utc(1, 4, 0, 0)},
+		{"month names", "0 0 1 mar *", utc(1, 1, 0, 0), utc(3, 1, 0, 0)},
+		{"macro", "@hourly", utc(1, 1, 10, 15), utc(1, 1, 11, 0)},
+		{"leap day", "0 0 29 2 *", utc(1, 1, 0, 0), time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"never", "0 0 30 2 *", utc(1, 1, 0, 0), time.Time{}},
+
+		// The day matches on either its day of month or its day of week,
+		// unless one of them is a *. Jan 2nd 2026 is a Friday.
+		{"dom or dow, dow first", "0 0 13 * fri", utc(1, 1, 0, 0), utc(1, 2, 0, 0)},
+		{"dom or dow, dom first", "0 0 13 * fri", utc(1, 10, 0, 0), utc(1, 13, 0, 0)},
+		{"dom and any dow", "0 0 13 * *", utc(1, 1, 0, 0), utc(1, 13, 0, 0)},
+		{"dow and any dom", "0 0 * * fri", utc(1, 3, 0, 0), utc(1, 9, 0, 0)},
+		{"starred range dom", "0 0 */2 * fri", utc(1, 2, 0, 0), utc(1, 9, 0, 0)},
+
+		// The clocks go forward on Mar 29th and back on Oct 25th 2026 in Paris
+		{"skipped time", "30 2 * * *", time.Date(2026, 3, 28, 12, 0, 0, 0, paris), time.Date(2026, 3, 30, 2, 30, 0, 0, paris)},
+		{"after the skipped hour", "30 3 * * *", time.Date(2026, 3, 29, 0, 0, 0, 0, paris), time.Date(2026, 3, 29, 3, 30, 0, 0, paris)},
+		{"repeated time", "30 2 * * *", utc(10, 25, 0, 10).In(paris), utc(10, 25, 0, 30)},
+		{"repeated time once", "30 2 * * *", utc(10, 25, 0, 30).In(paris), time.Date(2026, 10, 26, 2, 30, 0, 0, paris)},
+		{"after the repeated hour", "0 3 * * *", utc(10, 25, 0, 30).In(paris), time.Date(2026, 10, 25, 3, 0, 0, 0, paris)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.expr, err)
+			}
+			if got := s.Next(tt.from); !got.Equal(tt.want) {
+				t.Errorf("Next(%v) = %v, want %v", tt.from, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package cron
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// TaskType is the action run by a scheduled task.
+type TaskType string
+
+const (
+	TaskRestart TaskType = "restart" // Restart the server
+	TaskUpdate  TaskType = "update"  // Update the server files with SteamCMD
+	TaskMods    TaskType = "mods"    // Install the mods again
+	TaskSay     TaskType = "say"     // Broadcast a message to the players
+	TaskMap     TaskType = "map"     // Change the map
+)
+
+var taskTypes = []TaskType{TaskRestart, TaskUpdate, TaskMods, TaskSay, TaskMap}
+
+// Task is a task of the launcher schedule.
+type Task struct {
+	Name            string
+	Cron            string
+	Type            TaskType
+	Message         string        // Message broadcast by a say task
+	Map             string        // Map of a map task, the next map of the map list if empty
+	OnlyIfEmpty     bool          // Skip the task when players are connected
+	DeferUntilEmpty time.Duration // Max time to wait for an empty server
+	Instances       []string      // Instances the task runs on, all if empty
+}
+
+// Tasks is the launcher schedule.
+type Tasks []Task
+
+// Validate checks the cron expression and the options of the task.
+func (t Task) Validate() error {
+	if _, err := Parse(t.Cron); err != nil {
+		return fmt.Errorf("task %s: %w", t.Name, err)
+	}
+	if !slices.Contains(taskTypes, t.Type) {
+		return fmt.Errorf("task %s: unknown task type '%s'", t.Name, t.Type)
+	}
+	if t.Type == TaskSay && strings.TrimSpace(t.Message) == "" {
+		return fmt.Errorf("task %s: a message is required", t.Name)
+	}
+	if t.DeferUntilEmpty < 0 {
+		return fmt.Errorf("task %s: negative defer_until_empty", t.Name)
+	}
+	return nil
+}
+
+// RunsOn reports whether the task runs on the instance. Tasks run on the
+// single server, whose instance name is empty.
+func (t Task) RunsOn(instance string) bool {
+	return instance == "" || len(t.Instances) == 0 || slices.Contains(t.Instances, instance)
+}
+
+// Disruptive reports whether the task stops the server or changes the map.
+func (t Task) Disruptive() bool {
+	return t.Type != TaskSay
+}
@@ -0,0 +1,245 @@
+package launcher
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/K4rian/kfdsl/internal/log"
+	"github.com/K4rian/kfdsl/internal/services/base"
+	"github.com/K4rian/kfdsl/internal/services/kfserver"
+)
+
+const apiShutdownTimeout = 5 * time.Second
+
+// instanceStatus is the status of a server returned by the API.
+type instanceStatus struct {
+	Instance string      `json:"instance,omitempty"`
+	Running  bool        `json:"running"`
+	Ready    bool        `json:"ready"`
+	Pid      int         `json:"pid,omitempty"`
+	Map      string      `json:"map,omitempty"`
+	Usage    *base.Usage `json:"usage,omitempty"` // Last resource usage sample
+}
+
+// usageMetrics are the resource usage metrics exported by the API.
+var usageMetrics = []struct {
+	name  string
+	help  string
+	value func(u base.Usage) float64
+}{
+	{"kfdsl_server_rss_bytes", "Resident memory of the server process.", func(u base.Usage) float64 { return float64(u.RSS) }},
+	{"kfdsl_server_vm_bytes", "Virtual memory size of the server process.", func(u base.Usage) float64 { return float64(u.VMSize) }},
+	{"kfdsl_server_cpu_percent", "CPU usage of the server process, in percent of a core.", func(u base.Usage) float64 { return u.CPU }},
+	{"kfdsl_server_threads", "Number of threads of the server process.", func(u base.Usage) float64 { return float64(u.Threads) }},
+}
+
+// serveAPI serves the launcher HTTP API on the API address until ctx is done.
+// It does nothing when no address is set. A stop request of every server is
+// sent to stop like a SIGTERM.
+func (l *Launcher) serveAPI(ctx context.Context, instances []*Launcher, servers []*kfserver.KFServer, stop chan<- os.Signal) {
+	sett := l.currentSettings()
+	addr := sett.APIAddr.Value()
+	if addr == "" {
+		return
+	}
+	token := sett.APIToken.Value()
+	if token == "" {
+		log.Logger.Warn("No API token set, the launcher HTTP API is open to anyone reaching it", "address", addr)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/reload", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeAPIError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		name := r.URL.Query().Get("instance")
+		reloaded := 0
+		for i, inst := range instances {
+			if name != "" && inst.name != name {
+				continue
+			}
+			if servers[i] != nil {
+				go inst.reload(ctx, servers[i])
+				reloaded++
+			}
+		}
+		if name != "" && reloaded == 0 {
+			writeAPIError(w, http.StatusNotFound, "unknown or stopped instance: "+name)
+			return
+		}
+		log.Logger.Info("Configuration reload requested through the API", "remote", r.RemoteAddr)
+		writeAPIJSON(w, http.StatusAccepted, map[string]int{"reloading": reloaded})
+	})
+
+	mux.HandleFunc("/stop", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeAPIError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		// Stopping every server stops the launcher, after the players are warned
+		name := r.URL.Query().Get("instance")
+		if name == "" {
+			select {
+			case stop <- syscall.SIGTERM:
+			default:
+			}
+			running := 0
+			for _, server := range servers {
+				if server != nil && server.IsRunning() {
+					running++
+				}
+			}
+			log.Logger.Info("Shutdown requested through the API", "remote", r.RemoteAddr)
+			writeAPIJSON(w, http.StatusAccepted, map[string]int{"stopping": running})
+			return
+		}
+
+		// A single instance is stopped until the launcher restarts
+		for i, inst := range instances {
+			if inst.name != name || servers[i] == nil || !servers[i].IsRunning() {
+				continue
+			}
+			server := servers[i]
+			go func() {
+				serversMu.Lock()
+				defer serversMu.Unlock()
+				if err := server.Shutdown(ctx); err != nil {
+					log.Logger.Error("Failed to stop the KF Dedicated Server", inst.logAttrs("error", err)...)
+				}
+			}()
+			log.Logger.Info("Server shutdown requested through the API", inst.logAttrs("remote", r.RemoteAddr)...)
+			writeAPIJSON(w, http.StatusAccepted, map[string]int{"stopping": 1})
+			return
+		}
+		writeAPIError(w, http.StatusNotFound, "unknown or stopped instance: "+name)
+	})
+
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			writeAPIError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		writeAPIJSON(w, http.StatusOK, serversStatus(instances, servers))
+	})
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			writeAPIError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		writeMetrics(w, serversStatus(instances, servers))
+	})
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Logger.Error("Failed to start the launcher HTTP API", "address", addr, "error", err)
+		return
+	}
+	srv := &http.Server{
+		Handler:           requireToken(token, mux),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), apiShutdownTimeout)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	log.Logger.Info("Launcher HTTP API started", "address", listener.Addr().String())
+	if err := srv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Logger.Error("Launcher HTTP API raised an error", "error", err)
+	}
+}
+
+// serversStatus returns the status of the servers of the instances.
+func serversStatus(instances []*Launcher, servers []*kfserver.KFServer) []instanceStatus {
+	status := make([]instanceStatus, len(instances))
+	for i, inst := range instances {
+		status[i].Instance = inst.name
+		server := servers[i]
+		if server == nil {
+			continue
+		}
+		status[i].Running = server.IsRunning()
+		status[i].Ready = server.IsReady()
+		status[i].Pid = server.Pid()
+		status[i].Map = server.CurrentMap()
+		if usage, ok := server.Usage(); ok && status[i].Running {
+			status[i].Usage = &usage
+		}
+	}
+	return status
+}
+
+// writeMetrics writes the status of the servers in the Prometheus text
+// format. The usage metrics are left out until a sample is taken.
+func writeMetrics(w io.Writer, status []instanceStatus) {
+	labels := func(s instanceStatus) string {
+		if s.Instance == "" {
+			return ""
+		}
+		return fmt.Sprintf("{instance=%q}", s.Instance)
+	}
+
+	fmt.Fprintln(w, "# HELP kfdsl_server_up Whether the server process is running.")
+	fmt.Fprintln(w, "# TYPE kfdsl_server_up gauge")
+	for _, s := range status {
+		up := 0
+		if s.Running {
+			up = 1
+		}
+		fmt.Fprintf(w, "kfdsl_server_up%s %d\n", labels(s), up)
+	}
+	for _, m := range usageMetrics {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", m.name, m.help, m.name)
+		for _, s := range status {
+			if s.Usage != nil {
+				fmt.Fprintf(w, "%s%s %g\n", m.name, labels(s), m.value(*s.Usage))
+			}
+		}
+	}
+}
+
+// requireToken rejects the requests without the given bearer token. Every
+// request is accepted when token is empty.
+func requireToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token != "" {
+			given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+				writeAPIError(w, http.StatusUnauthorized, "unauthorized")
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeAPIJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeAPIError(w http.ResponseWriter, status int, message string) {
+	writeAPIJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,60 @@
+package launcher
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/K4rian/kfdsl/internal/services/base"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	usage := &base.Usage{Time: time.Now(), RSS: 512 << 20, VMSize: 1 << 30, CPU: 12.5, Threads: 9}
+
+	tests := []struct {
+		name   string
+		status []instanceStatus
+		want   []string
+		absent []string
+	}{
+		{
+			name:   "single server",
+			status: []instanceStatus{{Running: true, Usage: usage}},
+			want: []string{
+				"kfdsl_server_up 1\n",
+				"kfdsl_server_rss_bytes 5.36870912e+08\n",
+				"kfdsl_server_vm_bytes 1.073741824e+09\n",
+				"kfdsl_server_cpu_percent 12.5\n",
+				"kfdsl_server_threads 9\n",
+			},
+		},
+		{
+			name:   "instances",
+			status: []instanceStatus{{Instance: "a", Running: true, Usage: usage}, {Instance: "b"}},
+			want: []string{
+				`kfdsl_server_up{instance="a"} 1` + "\n",
+				`kfdsl_server_up{instance="b"} 0` + "\n",
+				`kfdsl_server_threads{instance="a"} 9` + "\n",
+			},
+			absent: []string{`kfdsl_server_threads{instance="b"}`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sb strings.Builder
+			writeMetrics(&sb, tt.status)
+			got := sb.String()
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("writeMetrics() missing %q in:\n%s", want, got)
+				}
+			}
+			for _, absent := range tt.absent {
+				if strings.Contains(got, absent) {
+					t.Errorf("writeMetrics() unexpected %q in:\n%s", absent, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package launcher
+
+import (
+	"fmt"
+
+	"github.com/K4rian/kfdsl/internal/log"
+)
+
+// runCommand executes the subcommand selected on the command-line.
+func (l *Launcher) runCommand() error {
+	log.Logger.Debug("Running command",
+		"function", "runCommand", "command", l.command.Name, "args", l.command.Args)
+
+	switch l.command.Name {
+	case "mods rollback":
+		return l.rollbackMods()
+	case "mods sign":
+		return l.signMods()
+	case "mods keygen":
+		return l.generateModsKey()
+	case "config lint":
+		return l.lintConfigFile()
+	case "config history":
+		return l.printConfigHistory()
+	case "config restore":
+		return l.restoreConfigBackup()
+	case "config export":
+		return l.exportConfig()
+	case "config import":
+		return l.importConfig()
+	case "doctor":
+		return l.runDoctor()
+	default:
+		return fmt.Errorf("unknown command: %s", l.command.Name)
+	}
+}
@@ -1,12 +1,16 @@
 package launcher
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/K4rian/kfdsl/embed"
 	"github.com/K4rian/kfdsl/internal/config"
+	"github.com/K4rian/kfdsl/internal/config/ini"
 	"github.com/K4rian/kfdsl/internal/log"
 	"github.com/K4rian/kfdsl/internal/services/kfserver"
 	"github.com/K4rian/kfdsl/internal/settings"
@@ -33,15 +37,13 @@ func (l *Launcher) updateConfigFile() error {
 	kfiFileName := l.settings.ConfigFile.Value()
 	kfiFilePath := filepath.Join(l.settings.ServerInstallDir.Value(), "System", kfiFileName)
 
-	useObjectiveMode := strings.Contains(strings.ToLower(l.settings.GameMode.Value()), "storygameinfo")
-	useToyMasterMode := strings.Contains(strings.ToLower(l.settings.GameMode.Value()), "toygameinfo")
-
 	log.Logger.Debug("Starting server configuration file update",
 		"function", "updateConfigFile", "file", kfiFilePath)
 
 	// If the specified configuration file doesn't exists,
 	// let's extract the corresponding default file
-	if !utils.FileExists(kfiFilePath) {
+	fresh := !utils.FileExists(kfiFilePath)
+	if fresh {
 		defaultIniFileName := "KillingFloor.ini"
 
 		log.Logger.Debug("Missing server configuration file. Extracting the default one...",
@@ -57,19 +59,7 @@ func (l *Launcher) updateConfigFile() error {
 	}
 
 	// Read the ini file
-	var kfi config.ServerIniFile
-	var err error
-
-	// Objective
-	if useObjectiveMode {
-		kfi, err = config.NewKFObjectiveIniFile(kfiFilePath)
-	} else if useToyMasterMode {
-		// Toy Master
-		kfi, err = config.NewKFTGIniFile(kfiFilePath)
-	} else {
-		// Survival
-		kfi, err = config.NewKFIniFile(kfiFilePath)
-	}
+	kfi, err := l.loadServerIniFile(kfiFilePath)
 	if err != nil {
 		log.Logger.Warn("Failed to read the server configuration file",
 			"function", "updateConfigFile", "file", kfiFilePath, "error", err)
@@ -79,30 +69,67 @@ func (l *Launcher) updateConfigFile() error {
 	log.Logger.Debug("Server configuration file successfully loaded",
 		"function", "updateConfigFile", "file", kfiFilePath)
 
-	// Generics
+	// The ports and the max players are always written, the launcher checks
+	// and queries the ports and passes the max players on the command line
 	cuList := []configUpdater[any]{
-		newConfigUpdater(l.settings.ServerName.Name(), func() any { return kfi.GetServerName() }, func(v any) bool { return kfi.SetServerName(v.(string)) }, l.settings.ServerName.Value()),
-		newConfigUpdater(l.settings.ShortName.Name(), func() any { return kfi.GetShortName() }, func(v any) bool { return kfi.SetShortName(v.(string)) }, l.settings.ShortName.Value()),
 		newConfigUpdater(l.settings.GamePort.Name(), func() any { return kfi.GetGamePort() }, func(v any) bool { return kfi.SetGamePort(v.(int)) }, l.settings.GamePort.Value()),
 		newConfigUpdater(l.settings.WebAdminPort.Name(), func() any { return kfi.GetWebAdminPort() }, func(v any) bool { return kfi.SetWebAdminPort(v.(int)) }, l.settings.WebAdminPort.Value()),
 		newConfigUpdater(l.settings.GameSpyPort.Name(), func() any { return kfi.GetGameSpyPort() }, func(v any) bool { return kfi.SetGameSpyPort(v.(int)) }, l.settings.GameSpyPort.Value()),
-		newConfigUpdater(l.settings.GameDifficulty.Name(), func() any { return kfi.GetGameDifficulty() }, func(v any) bool { return kfi.SetGameDifficulty(v.(int)) }, l.settings.GameDifficulty.Value()),
-		newConfigUpdater(l.settings.GameLength.Name(), func() any { return kfi.GetGameLength() }, func(v any) bool { return kfi.SetGameLength(v.(int)) }, l.settings.GameLength.Value()),
-		newConfigUpdater(l.settings.FriendlyFire.Name(), func() any { return kfi.GetFriendlyFireRate() }, func(v any) bool { return kfi.SetFriendlyFireRate(v.(float64)) }, l.settings.FriendlyFire.Value()),
 		newConfigUpdater(l.settings.MaxPlayers.Name(), func() any { return kfi.GetMaxPlayers() }, func(v any) bool { return kfi.SetMaxPlayers(v.(int)) }, l.settings.MaxPlayers.Value()),
-		newConfigUpdater(l.settings.MaxSpectators.Name(), func() any { return kfi.GetMaxSpectators() }, func(v any) bool { return kfi.SetMaxSpectators(v.(int)) }, l.settings.MaxSpectators.Value()),
-		newConfigUpdater(l.settings.Password.Name(), func() any { return kfi.GetPassword() }, func(v any) bool { return kfi.SetPassword(v.(string)) }, l.settings.Password.Value()),
-		newConfigUpdater(l.settings.Region.Name(), func() any { return kfi.GetRegion() }, func(v any) bool { return kfi.SetRegion(v.(int)) }, l.settings.Region.Value()),
-		newConfigUpdater(l.settings.AdminName.Name(), func() any { return kfi.GetAdminName() }, func(v any) bool { return kfi.SetAdminName(v.(string)) }, l.settings.AdminName.Value()),
-		newConfigUpdater(l.settings.AdminMail.Name(), func() any { return kfi.GetAdminMail() }, func(v any) bool { return kfi.SetAdminMail(v.(string)) }, l.settings.AdminMail.Value()),
-		newConfigUpdater(l.settings.AdminPassword.Name(), func() any { return kfi.GetAdminPassword() }, func(v any) bool { return kfi.SetAdminPassword(v.(string)) }, l.settings.AdminPassword.Value()),
-		newConfigUpdater(l.settings.MOTD.Name(), func() any { return kfi.GetMOTD() }, func(v any) bool { return kfi.SetMOTD(v.(string)) }, l.settings.MOTD.Value()),
-		newConfigUpdater(l.settings.SpecimenType.Name(), func() any { return kfi.GetSpecimenType() }, func(v any) bool { return kfi.SetSpecimenType(v.(string)) }, l.settings.SpecimenType.Value()),
-		newConfigUpdater(l.settings.RedirectURL.Name(), func() any { return kfi.GetRedirectURL() }, func(v any) bool { return kfi.SetRedirectURL(v.(string)) }, l.settings.RedirectURL.Value()),
-		newConfigUpdater(l.settings.EnableWebAdmin.Name(), func() any { return kfi.IsWebAdminEnabled() }, func(v any) bool { return kfi.SetWebAdminEnabled(v.(bool)) }, l.settings.EnableWebAdmin.Value()),
-		newConfigUpdater(l.settings.EnableMapVote.Name(), func() any { return kfi.IsMapVoteEnabled() }, func(v any) bool { return kfi.SetMapVoteEnabled(v.(bool)) == nil }, l.settings.EnableMapVote.Value()),
-		newConfigUpdater(l.settings.MapVoteRepeatLimit.Name(), func() any { return kfi.GetMapVoteRepeatLimit() }, func(v any) bool { return kfi.SetMapVoteRepeatLimit(v.(int)) }, l.settings.MapVoteRepeatLimit.Value()),
 	}
+	// The other generics are written when given or to a default file just
+	// extracted, so the values set in the file (or imported) are kept otherwise
+	generics := []struct {
+		explicit bool
+		updater  configUpdater[any]
+	}{
+		{l.settings.ServerName.IsExplicit(), newConfigUpdater(l.settings.ServerName.Name(), func() any { return kfi.GetServerName() }, func(v any) bool { return kfi.SetServerName(v.(string)) }, l.settings.ServerName.Value())},
+		{l.settings.ShortName.IsExplicit(), newConfigUpdater(l.settings.ShortName.Name(), func() any { return kfi.GetShortName() }, func(v any) bool { return kfi.SetShortName(v.(string)) }, l.settings.ShortName.Value())},
+		{l.settings.GameDifficulty.IsExplicit(), newConfigUpdater(l.settings.GameDifficulty.Name(), func() any { return kfi.GetGameDifficulty() }, func(v any) bool { return kfi.SetGameDifficulty(v.(int)) }, l.settings.GameDifficulty.Value())},
+		{l.settings.GameLength.IsExplicit(), newConfigUpdater(l.settings.GameLength.Name(), func() any { return kfi.GetGameLength() }, func(v any) bool { return kfi.SetGameLength(v.(int)) }, l.settings.GameLength.Value())},
+		{l.settings.FriendlyFire.IsExplicit(), newConfigUpdater(l.settings.FriendlyFire.Name(), func() any { return kfi.GetFriendlyFireRate() }, func(v any) bool { return kfi.SetFriendlyFireRate(v.(float64)) }, l.settings.FriendlyFire.Value())},
+		{l.settings.MaxSpectators.IsExplicit(), newConfigUpdater(l.settings.MaxSpectators.Name(), func() any { return kfi.GetMaxSpectators() }, func(v any) bool { return kfi.SetMaxSpectators(v.(int)) }, l.settings.MaxSpectators.Value())},
+		{l.settings.Password.IsExplicit(), newConfigUpdater(l.settings.Password.Name(), func() any { return kfi.GetPassword() }, func(v any) bool { return kfi.SetPassword(v.(string)) }, l.settings.Password.Value())},
+		{l.settings.Region.IsExplicit(), newConfigUpdater(l.settings.Region.Name(), func() any { return kfi.GetRegion() }, func(v any) bool { return kfi.SetRegion(v.(int)) }, l.settings.Region.Value())},
+		{l.settings.AdminName.IsExplicit(), newConfigUpdater(l.settings.AdminName.Name(), func() any { return kfi.GetAdminName() }, func(v any) bool { return kfi.SetAdminName(v.(string)) }, l.settings.AdminName.Value())},
+		{l.settings.AdminMail.IsExplicit(), newConfigUpdater(l.settings.AdminMail.Name(), func() any { return kfi.GetAdminMail() }, func(v any) bool { return kfi.SetAdminMail(v.(string)) }, l.settings.AdminMail.Value())},
+		{l.settings.AdminPassword.IsExplicit(), newConfigUpdater(l.settings.AdminPassword.Name(), func() any { return kfi.GetAdminPassword() }, func(v any) bool { return kfi.SetAdminPassword(v.(string)) }, l.settings.AdminPassword.Value())},
+		{l.settings.MOTD.IsExplicit(), newConfigUpdater(l.settings.MOTD.Name(), func() any { return kfi.GetMOTD() }, func(v any) bool { return kfi.SetMOTD(v.(string)) }, l.settings.MOTD.Value())},
+		{l.settings.SpecimenType.IsExplicit(), newConfigUpdater(l.settings.SpecimenType.Name(), func() any { return kfi.GetSpecimenType() }, func(v any) bool { return kfi.SetSpecimenType(v.(string)) }, l.settings.SpecimenType.Value())},
+		{l.settings.RedirectURL.IsExplicit(), newConfigUpdater(l.settings.RedirectURL.Name(), func() any { return kfi.GetRedirectURL() }, func(v any) bool { return kfi.SetRedirectURL(v.(string)) }, l.settings.RedirectURL.Value())},
+		{l.settings.EnableWebAdmin.IsExplicit(), newConfigUpdater(l.settings.EnableWebAdmin.Name(), func() any { return kfi.IsWebAdminEnabled() }, func(v any) bool { return kfi.SetWebAdminEnabled(v.(bool)) }, l.settings.EnableWebAdmin.Value())},
+		{l.settings.EnableMapVote.IsExplicit(), newConfigUpdater(l.settings.EnableMapVote.Name(), func() any { return kfi.IsMapVoteEnabled() }, func(v any) bool { return kfi.SetMapVoteEnabled(v.(bool)) == nil }, l.settings.EnableMapVote.Value())},
+		{l.settings.MapVoteRepeatLimit.IsExplicit(), newConfigUpdater(l.settings.MapVoteRepeatLimit.Name(), func() any { return kfi.GetMapVoteRepeatLimit() }, func(v any) bool { return kfi.SetMapVoteRepeatLimit(v.(int)) }, l.settings.MapVoteRepeatLimit.Value())},
+	}
+	for _, g := range generics {
+		if fresh || g.explicit {
+			cuList = append(cuList, g.updater)
+		}
+	}
+	// The WebAdmin address is written only when given, so the ServerName set
+	// in the file is kept otherwise
+	if ip := webAdminIP(l.settings); ip != "" {
+		cuList = append(cuList, newConfigUpdater(l.settings.WebAdminIP.Name(), func() any { return kfi.GetWebAdminAddress() }, func(v any) bool { return kfi.SetWebAdminAddress(v.(string)) }, ip))
+	}
+	// The gameplay settings are written only when given, so the values tuned
+	// in the file are kept
+	gameplay := []struct {
+		explicit bool
+		updater  configUpdater[any]
+	}{
+		{l.settings.StartingCash.IsExplicit(), newConfigUpdater(l.settings.StartingCash.Name(), func() any { return kfi.GetStartingCash() }, func(v any) bool { return kfi.SetStartingCash(v.(int)) }, l.settings.StartingCash.Value())},
+		{l.settings.MinRespawnCash.IsExplicit(), newConfigUpdater(l.settings.MinRespawnCash.Name(), func() any { return kfi.GetMinRespawnCash() }, func(v any) bool { return kfi.SetMinRespawnCash(v.(int)) }, l.settings.MinRespawnCash.Value())},
+		{l.settings.TimeBetweenWaves.IsExplicit(), newConfigUpdater(l.settings.TimeBetweenWaves.Name(), func() any { return kfi.GetTimeBetweenWaves() }, func(v any) bool { return kfi.SetTimeBetweenWaves(v.(int)) }, l.settings.TimeBetweenWaves.Value())},
+		{l.settings.DisableWaveFunding.IsExplicit(), newConfigUpdater(l.settings.DisableWaveFunding.Name(), func() any { return !kfi.IsWaveFundingEnabled() }, func(v any) bool { return kfi.SetWaveFundingEnabled(!v.(bool)) }, l.settings.DisableWaveFunding.Value())},
+		{l.settings.MaxZombiesOnce.IsExplicit(), newConfigUpdater(l.settings.MaxZombiesOnce.Name(), func() any { return kfi.GetMaxZombiesOnce() }, func(v any) bool { return kfi.SetMaxZombiesOnce(v.(int)) }, l.settings.MaxZombiesOnce.Value())},
+		{l.settings.DisableEndGameBoss.IsExplicit(), newConfigUpdater(l.settings.DisableEndGameBoss.Name(), func() any { return !kfi.IsEndGameBossEnabled() }, func(v any) bool { return kfi.SetEndGameBossEnabled(!v.(bool)) }, l.settings.DisableEndGameBoss.Value())},
+	}
+	for _, g := range gameplay {
+		if g.explicit {
+			cuList = append(cuList, g.updater)
+		}
+	}
+
 	for _, conf := range cuList {
 		currentValue := conf.gv()
 		if currentValue != conf.nv {
@@ -122,22 +149,31 @@ func (l *Launcher) updateConfigFile() error {
 	if l.settings.Uncap.Value() {
 		newClientRate = 15000
 	}
-	if currentClientRate != newClientRate && !kfi.SetMaxInternetClientRate(newClientRate) {
+	if (fresh || l.settings.Uncap.IsExplicit()) && currentClientRate != newClientRate && !kfi.SetMaxInternetClientRate(newClientRate) {
 		log.Logger.Warn("Failed to update the server MaxInternetClientRate configuration",
 			"function", "updateConfigFile", "file", kfiFilePath, "confName", "MaxInternetClientRate", "confOldValue", currentClientRate, "confNewValue", newClientRate)
 		return fmt.Errorf("[MaxInternetClientRate]: failed to set the new value: %d", newClientRate)
 	}
 
-	if err := l.updateConfigFileServerMutators(kfi); err != nil {
+	if err := l.updateConfigFileServerMutators(kfi, fresh); err != nil {
 		return fmt.Errorf("[ServerMutators]: %w", err)
 	}
 
-	if err := l.updateConfigFileMaplist(kfi); err != nil {
+	if err := l.updateConfigFileMaplist(kfi, fresh); err != nil {
 		return fmt.Errorf("[Maplist]: %w", err)
 	}
 
+	if err := l.updateConfigFileCustomWaves(kfi); err != nil {
+		return fmt.Errorf("[Custom Waves]: %w", err)
+	}
+
+	// Overrides are applied last so they win over the typed settings
+	if err := l.updateConfigFileOverrides(kfi); err != nil {
+		return fmt.Errorf("[Overrides]: %w", err)
+	}
+
 	// Save the ini file
-	err = kfi.Save(kfiFilePath)
+	err = l.saveConfigFile(kfi, kfiFilePath)
 	if err == nil {
 		log.Logger.Debug("Server configuration file successfully saved",
 			"function", "updateConfigFile", "file", kfiFilePath)
@@ -148,9 +184,31 @@ func (l *Launcher) updateConfigFile() error {
 	return err
 }
 
-func (l *Launcher) updateConfigFileServerMutators(iniFile config.ServerIniFile) error {
+// loadServerIniFile reads the server configuration file matching the game mode.
+func (l *Launcher) loadServerIniFile(filePath string) (config.ServerIniFile, error) {
+	gameMode := strings.ToLower(l.settings.GameMode.Value())
+
+	// Objective
+	if strings.Contains(gameMode, "storygameinfo") {
+		return config.NewKFObjectiveIniFile(filePath)
+	}
+	// Toy Master
+	if strings.Contains(gameMode, "toygameinfo") {
+		return config.NewKFTGIniFile(filePath)
+	}
+	// Survival
+	return config.NewKFIniFile(filePath)
+}
+
+// updateConfigFileServerMutators writes the server mutators. When they aren't
+// given and the file isn't fresh, the mutators of the file are kept.
+func (l *Launcher) updateConfigFileServerMutators(iniFile config.ServerIniFile, fresh bool) error {
 	mutatorsStr := l.settings.ServerMutators.Value()
 	mutatorsList := strings.FieldsFunc(mutatorsStr, func(r rune) bool { return r == ',' })
+	if !fresh && !l.settings.ServerMutators.IsExplicit() {
+		mutatorsList = iniFile.GetServerMutators()
+		mutatorsStr = strings.Join(mutatorsList, ",")
+	}
 
 	log.Logger.Debug("Starting server configuration file mutators update",
 		"function", "updateConfigFileServerMutators", "file", iniFile.FilePath(), "mutators", mutatorsList)
@@ -183,7 +241,13 @@ func (l *Launcher) updateConfigFileServerMutators(iniFile config.ServerIniFile)
 	return nil
 }
 
-func (l *Launcher) updateConfigFileMaplist(iniFile config.ServerIniFile) error {
+// updateConfigFileMaplist writes the maplist of the game mode. When it isn't
+// given and the file isn't fresh, the maplist of the file is kept.
+func (l *Launcher) updateConfigFileMaplist(iniFile config.ServerIniFile, fresh bool) error {
+	if !fresh && !l.settings.Maplist.IsExplicit() {
+		return nil
+	}
+
 	gameMode := l.settings.GameMode.RawValue()
 
 	log.Logger.Debug("Starting server configuration file maplist update",
@@ -243,6 +307,68 @@ func (l *Launcher) updateConfigFileMaplist(iniFile config.ServerIniFile) error {
 	return nil
 }
 
+func (l *Launcher) updateConfigFileCustomWaves(iniFile config.ServerIniFile) error {
+	// Waves are only used by the custom game length
+	if iniFile.GetGameLength() != 3 {
+		return nil
+	}
+
+	wavesFile := l.settings.CustomWavesFile.Value()
+	if wavesFile == "" {
+		log.Logger.Warn("Custom game length without waves file, the waves of the configuration file will be used",
+			"function", "updateConfigFileCustomWaves", "file", iniFile.FilePath())
+		return nil
+	}
+
+	log.Logger.Debug("Starting server configuration file custom waves update",
+		"function", "updateConfigFileCustomWaves", "file", iniFile.FilePath(), "wavesFile", wavesFile)
+
+	waves, err := config.ParseWavesFile(wavesFile)
+	if err != nil {
+		log.Logger.Warn("Failed to read the custom waves file",
+			"function", "updateConfigFileCustomWaves", "file", iniFile.FilePath(), "wavesFile", wavesFile, "error", err)
+		return fmt.Errorf("invalid waves file %s: %w", wavesFile, err)
+	}
+
+	if err := iniFile.SetCustomWaves(waves); err != nil {
+		log.Logger.Warn("Failed to set the custom waves",
+			"function", "updateConfigFileCustomWaves", "file", iniFile.FilePath(), "wavesFile", wavesFile, "error", err)
+		return err
+	}
+	log.Logger.Debug("Custom waves successfully updated",
+		"function", "updateConfigFileCustomWaves", "file", iniFile.FilePath(), "waves", len(waves.Waves), "squads", len(waves.Squads()))
+	return nil
+}
+
+func (l *Launcher) updateConfigFileOverrides(iniFile config.ServerIniFile) error {
+	specs := l.settings.IniOverrides.Value()
+	if len(specs) == 0 {
+		return nil
+	}
+
+	log.Logger.Debug("Starting server configuration file overrides update",
+		"function", "updateConfigFileOverrides", "file", iniFile.FilePath(), "overrides", len(specs))
+
+	for _, spec := range specs {
+		o, err := ini.ParseOverride(spec)
+		if err != nil {
+			return err
+		}
+
+		changed, err := iniFile.ApplyOverride(o)
+		if err != nil {
+			log.Logger.Warn("Failed to apply the server configuration override",
+				"function", "updateConfigFileOverrides", "file", iniFile.FilePath(), "section", o.Section, "key", o.Key, "op", o.Op, "error", err)
+			return err
+		}
+		if changed {
+			log.Logger.Debug("Applied server configuration override",
+				"function", "updateConfigFileOverrides", "file", iniFile.FilePath(), "section", o.Section, "key", o.Key, "op", o.Op, "value", o.Value)
+		}
+	}
+	return nil
+}
+
 func (l *Launcher) updateKFPatcherConfigFile() error {
 	kfpiFilePath := filepath.Join(l.settings.ServerInstallDir.Value(), "System", "KFPatcherSettings.ini")
 
@@ -260,12 +386,23 @@ func (l *Launcher) updateKFPatcherConfigFile() error {
 	log.Logger.Debug("KFPatcher configuration file successfully loaded",
 		"function", "updateKFPatcherConfigFile", "file", kfpiFilePath)
 
-	cuList := []configUpdater[any]{
-		newConfigUpdater(l.settings.KFPHidePerks.Name(), func() any { return kfpi.IsShowPerksEnabled() }, func(v any) bool { return kfpi.SetShowPerksEnabled(v.(bool)) }, !l.settings.KFPHidePerks.Value()),
-		newConfigUpdater(l.settings.KFPDisableZedTime.Name(), func() any { return kfpi.IsZEDTimeEnabled() }, func(v any) bool { return kfpi.SetZEDTimeEnabled(v.(bool)) }, !l.settings.KFPDisableZedTime.Value()),
-		newConfigUpdater(l.settings.KFPEnableAllTraders.Name(), func() any { return kfpi.IsAllTradersOpenEnabled() }, func(v any) bool { return kfpi.SetAllTradersOpenEnabled(v.(bool)) }, l.settings.KFPEnableAllTraders.Value()),
-		newConfigUpdater(l.settings.KFPAllTradersMessage.Name(), func() any { return kfpi.GetAllTradersMessage() }, func(v any) bool { return kfpi.SetAllTradersMessage(v.(string)) }, l.settings.KFPAllTradersMessage.Value()),
-		newConfigUpdater(l.settings.KFPBuyEverywhere.Name(), func() any { return kfpi.IsBuyEverywhereEnabled() }, func(v any) bool { return kfpi.SetBuyEverywhereEnabled(v.(bool)) }, l.settings.KFPBuyEverywhere.Value()),
+	// The settings are written only when given, so the values set in the
+	// file (or imported) are kept otherwise
+	updaters := []struct {
+		explicit bool
+		updater  configUpdater[any]
+	}{
+		{l.settings.KFPHidePerks.IsExplicit(), newConfigUpdater(l.settings.KFPHidePerks.Name(), func() any { return kfpi.IsShowPerksEnabled() }, func(v any) bool { return kfpi.SetShowPerksEnabled(v.(bool)) }, !l.settings.KFPHidePerks.Value())},
+		{l.settings.KFPDisableZedTime.IsExplicit(), newConfigUpdater(l.settings.KFPDisableZedTime.Name(), func() any { return kfpi.IsZEDTimeEnabled() }, func(v any) bool { return kfpi.SetZEDTimeEnabled(v.(bool)) }, !l.settings.KFPDisableZedTime.Value())},
+		{l.settings.KFPEnableAllTraders.IsExplicit(), newConfigUpdater(l.settings.KFPEnableAllTraders.Name(), func() any { return kfpi.IsAllTradersOpenEnabled() }, func(v any) bool { return kfpi.SetAllTradersOpenEnabled(v.(bool)) }, l.settings.KFPEnableAllTraders.Value())},
+		{l.settings.KFPAllTradersMessage.IsExplicit(), newConfigUpdater(l.settings.KFPAllTradersMessage.Name(), func() any { return kfpi.GetAllTradersMessage() }, func(v any) bool { return kfpi.SetAllTradersMessage(v.(string)) }, l.settings.KFPAllTradersMessage.Value())},
+		{l.settings.KFPBuyEverywhere.IsExplicit(), newConfigUpdater(l.settings.KFPBuyEverywhere.Name(), func() any { return kfpi.IsBuyEverywhereEnabled() }, func(v any) bool { return kfpi.SetBuyEverywhereEnabled(v.(bool)) }, l.settings.KFPBuyEverywhere.Value())},
+	}
+	cuList := []configUpdater[any]{}
+	for _, u := range updaters {
+		if u.explicit {
+			cuList = append(cuList, u.updater)
+		}
 	}
 	for _, conf := range cuList {
 		currentValue := conf.gv()
@@ -281,7 +418,7 @@ func (l *Launcher) updateKFPatcherConfigFile() error {
 	}
 
 	// Save the ini file
-	err = kfpi.Save(kfpiFilePath)
+	err = l.saveConfigFile(kfpi, kfpiFilePath)
 	if err == nil {
 		log.Logger.Debug("KFPatcher configuration file successfully saved",
 			"function", "updateKFPatcherConfigFile", "file", kfpiFilePath)
@@ -292,6 +429,23 @@ func (l *Launcher) updateKFPatcherConfigFile() error {
 	return err
 }
 
+// saveConfigFile backs up the current configuration file, then replaces it.
+func (l *Launcher) saveConfigFile(iniFile interface{ Save(string) error }, filePath string) error {
+	backupsDir := config.BackupsDir(l.settings.ServerInstallDir.Value())
+
+	backup, err := config.BackupFile(backupsDir, filePath, l.settings.ConfigBackups.Value())
+	if err != nil {
+		log.Logger.Warn("Failed to back up the configuration file",
+			"function", "saveConfigFile", "file", filePath, "backupsDir", backupsDir, "error", err)
+		return fmt.Errorf("failed to back up %s: %w", filePath, err)
+	}
+	if backup != nil {
+		log.Logger.Debug("Configuration file backed up",
+			"function", "saveConfigFile", "file", filePath, "backup", backup.ID)
+	}
+	return iniFile.Save(filePath)
+}
+
 func (l *Launcher) extractDefaultConfigFile(filename string, filePath string) error {
 	defaultIniFilePath := filepath.Join("assets/configs", filename)
 
@@ -303,3 +457,247 @@ func (l *Launcher) extractDefaultConfigFile(filename string, filePath string) er
 	}
 	return nil
 }
+
+func (l *Launcher) lintConfigFile() error {
+	kfiFilePath := filepath.Join(l.settings.ServerInstallDir.Value(), "System", l.settings.ConfigFile.Value())
+	format, _ := l.command.Flags.GetString("format")
+
+	log.Logger.Debug("Starting server configuration file lint",
+		"function", "lintConfigFile", "file", kfiFilePath, "format", format)
+
+	kfi, err := l.loadServerIniFile(kfiFilePath)
+	if err != nil {
+		return fmt.Errorf("failed to read the server configuration file %s: %w", kfiFilePath, err)
+	}
+
+	report := config.Lint(kfi, filepath.Dir(kfiFilePath), l.lintMutators())
+
+	switch format {
+	case "json":
+		data, err := json.MarshalIndent(report, "", "    ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	case "text":
+		for _, issue := range report.Issues {
+			location := ""
+			if issue.Section != "" {
+				location = fmt.Sprintf("[%s] ", issue.Section)
+			}
+			if issue.Key != "" {
+				location += issue.Key
+				if issue.Value != "" {
+					location += "=" + issue.Value
+				}
+				location += ": "
+			}
+			fmt.Printf("%-7s %s%s\n", strings.ToUpper(string(issue.Severity)), location, issue.Message)
+		}
+		fmt.Printf("%s: %d error(s), %d warning(s)\n", report.File, report.Errors, report.Warnings)
+	default:
+		return fmt.Errorf("unknown report format: %s", format)
+	}
+
+	if report.Errors > 0 {
+		return fmt.Errorf("server configuration file %s has %d error(s)", kfiFilePath, report.Errors)
+	}
+	return nil
+}
+
+// lintMutators returns the command-line and server mutators checked by the lint.
+func (l *Launcher) lintMutators() []string {
+	splitList := func(s string) []string {
+		return strings.FieldsFunc(s, func(r rune) bool { return r == ',' })
+	}
+	return append(splitList(l.settings.Mutators.Value()), splitList(l.settings.ServerMutators.Value())...)
+}
+
+func (l *Launcher) printConfigHistory() error {
+	backupsDir := config.BackupsDir(l.settings.ServerInstallDir.Value())
+
+	fileName := ""
+	if len(l.command.Args) > 0 {
+		fileName = l.command.Args[0]
+	}
+
+	backups, err := config.ListBackups(backupsDir, fileName)
+	if err != nil {
+		return fmt.Errorf("failed to list the configuration backups: %w", err)
+	}
+	if len(backups) == 0 {
+		log.Logger.Info("No configuration backup found", "dir", backupsDir)
+		return nil
+	}
+
+	fmt.Printf("%-30s %-28s %-20s %s\n", "ID", "FILE", "DATE (UTC)", "SIZE")
+	for _, b := range backups {
+		fmt.Printf("%-30s %-28s %-20s %d\n", b.ID, b.File, b.Date.Format("2006-01-02 15:04:05"), b.Size)
+	}
+	return nil
+}
+
+func (l *Launcher) restoreConfigBackup() error {
+	id := l.command.Args[0]
+	backupsDir := config.BackupsDir(l.settings.ServerInstallDir.Value())
+	systemDir := filepath.Join(l.settings.ServerInstallDir.Value(), "System")
+
+	log.Logger.Info("Restoring configuration backup...", "backup", id)
+	backup, err := config.RestoreBackup(backupsDir, id, systemDir, l.settings.ConfigBackups.Value())
+	if err != nil {
+		return fmt.Errorf("failed to restore configuration backup %s: %w", id, err)
+	}
+
+	log.Logger.Info("Configuration backup successfully restored", "backup", backup.ID, "file", filepath.Join(systemDir, backup.File))
+	// Only the settings given are written again on start, the ports and the
+	// max players always are
+	log.Logger.Info("The imported values are kept on the next start, except the ports, the max players and the launcher settings given")
+	return nil
+}
+
+func (l *Launcher) exportConfig() error {
+	systemDir := filepath.Join(l.settings.ServerInstallDir.Value(), "System")
+	kfiFilePath := filepath.Join(systemDir, l.settings.ConfigFile.Value())
+	format, _ := l.command.Flags.GetString("format")
+	output, _ := l.command.Flags.GetString("output")
+	secrets, _ := l.command.Flags.GetBool("secrets")
+
+	log.Logger.Debug("Starting configuration export",
+		"function", "exportConfig", "file", kfiFilePath, "format", format, "output", output)
+
+	kfi, err := l.loadServerIniFile(kfiFilePath)
+	if err != nil {
+		return fmt.Errorf("failed to read the server configuration file %s: %w", kfiFilePath, err)
+	}
+
+	doc := config.NewConfigDocument()
+	doc.Launcher = l.settings.Values()
+	doc.Server = config.ExportServerSettings(kfi, secrets)
+	doc.ServerMutators = kfi.GetServerMutators()
+	if sectionName := kfserver.GetGameModeMaplistName(l.settings.GameMode.RawValue()); sectionName != "" {
+		doc.Maplist = kfi.GetMaplist(sectionName)
+	}
+
+	kfpiFilePath := filepath.Join(systemDir, "KFPatcherSettings.ini")
+	if utils.FileExists(kfpiFilePath) {
+		kfpi, err := config.NewKFPIniFile(kfpiFilePath)
+		if err != nil {
+			return fmt.Errorf("failed to read the KFPatcher configuration file %s: %w", kfpiFilePath, err)
+		}
+		doc.KFPatcher = config.ExportKFPatcherSettings(kfpi)
+	}
+
+	data, err := doc.Marshal(format)
+	if err != nil {
+		return err
+	}
+
+	if output == "" {
+		fmt.Print(string(data))
+		return nil
+	}
+
+	// Exports holding secrets are only readable by the owner
+	perm := os.FileMode(0644)
+	if secrets {
+		perm = 0600
+	}
+	if err := os.WriteFile(output, data, perm); err != nil {
+		return fmt.Errorf("failed to write the configuration export %s: %w", output, err)
+	}
+	log.Logger.Info("Configuration successfully exported", "file", output, "format", format)
+	return nil
+}
+
+func (l *Launcher) importConfig() error {
+	docFilePath := l.command.Args[0]
+	systemDir := filepath.Join(l.settings.ServerInstallDir.Value(), "System")
+	kfiFilePath := filepath.Join(systemDir, l.settings.ConfigFile.Value())
+
+	log.Logger.Debug("Starting configuration import",
+		"function", "importConfig", "document", docFilePath, "file", kfiFilePath)
+
+	doc, err := config.ReadConfigDocument(docFilePath)
+	if err != nil {
+		return err
+	}
+
+	kfi, err := l.loadServerIniFile(kfiFilePath)
+	if err != nil {
+		return fmt.Errorf("failed to read the server configuration file %s: %w", kfiFilePath, err)
+	}
+
+	changed := 0
+	if doc.Server != nil {
+		n, err := doc.Server.Apply(kfi)
+		if err != nil {
+			return err
+		}
+		changed += n
+	}
+
+	if doc.ServerMutators != nil && !slices.Equal(kfi.GetServerMutators(), doc.ServerMutators) {
+		if err := kfi.ClearServerMutators(); err != nil {
+			return fmt.Errorf("[ServerMutators]: %w", err)
+		}
+		if err := kfi.SetServerMutators(doc.ServerMutators); err != nil {
+			return fmt.Errorf("[ServerMutators]: %w", err)
+		}
+		changed++
+	}
+
+	if doc.Maplist != nil {
+		gameMode := l.settings.GameMode.RawValue()
+		sectionName := kfserver.GetGameModeMaplistName(gameMode)
+		if sectionName == "" {
+			return fmt.Errorf("[Maplist]: undefined section name for game mode: %s", gameMode)
+		}
+
+		if !slices.Equal(kfi.GetMaplist(sectionName), doc.Maplist) {
+			if len(doc.Maplist) > 0 {
+				err = kfi.SetMaplist(sectionName, doc.Maplist)
+			} else {
+				err = kfi.ClearMaplist(sectionName)
+			}
+			if err != nil {
+				return fmt.Errorf("[Maplist]: %w", err)
+			}
+			changed++
+		}
+	}
+
+	if changed > 0 {
+		if err := l.saveConfigFile(kfi, kfiFilePath); err != nil {
+			return fmt.Errorf("failed to save the server configuration file %s: %w", kfiFilePath, err)
+		}
+	}
+	log.Logger.Info("Server configuration imported", "file", kfiFilePath, "changes", changed)
+
+	if doc.KFPatcher != nil {
+		kfpiFilePath := filepath.Join(systemDir, "KFPatcherSettings.ini")
+		kfpi, err := config.NewKFPIniFile(kfpiFilePath)
+		if err != nil {
+			return fmt.Errorf("failed to read the KFPatcher configuration file %s: %w", kfpiFilePath, err)
+		}
+
+		n, err := doc.KFPatcher.Apply(kfpi)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			if err := l.saveConfigFile(kfpi, kfpiFilePath); err != nil {
+				return fmt.Errorf("failed to save the KFPatcher configuration file %s: %w", kfpiFilePath, err)
+			}
+		}
+		log.Logger.Info("KFPatcher configuration imported", "file", kfpiFilePath, "changes", n)
+	}
+
+	if len(doc.Launcher) > 0 {
+		log.Logger.Debug("Launcher settings of the document are not imported",
+			"function", "importConfig", "document", docFilePath)
+	}
+	// Only the settings given are written again on start, the ports and the
+	// max players always are
+	log.Logger.Info("The imported values are kept on the next start, except the ports, the max players and the launcher settings given")
+	return nil
+}
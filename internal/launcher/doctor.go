@@ -0,0 +1,465 @@
+package launcher
+
+import (
+	"bufio"
+	"context"
+	"debug/elf"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/K4rian/kfdsl/internal/config"
+	"github.com/K4rian/kfdsl/internal/log"
+	"github.com/K4rian/kfdsl/internal/mods"
+	"github.com/K4rian/kfdsl/internal/services/steamcmd"
+	"github.com/K4rian/kfdsl/internal/utils"
+)
+
+type checkStatus string
+
+const (
+	checkPass checkStatus = "pass"
+	checkWarn checkStatus = "warn"
+	checkFail checkStatus = "fail"
+)
+
+// Free disk space thresholds of the server install directory
+const (
+	diskSpaceFail = 512 << 20
+	diskSpaceWarn = 2 << 30
+)
+
+// Directories searched for the shared libraries, after the RPATH/RUNPATH of
+// the executable and LD_LIBRARY_PATH. The ld.so.conf entries come first.
+var defaultLibDirs = []string{
+	"/lib/i386-linux-gnu",
+	"/usr/lib/i386-linux-gnu",
+	"/lib32",
+	"/usr/lib32",
+	"/lib",
+	"/usr/lib",
+}
+
+type checkResult struct {
+	Name    string      `json:"name"`
+	Status  checkStatus `json:"status"`
+	Message string      `json:"message"`
+}
+
+type doctorReport struct {
+	Checks   []checkResult `json:"checks"`
+	Warnings int           `json:"warnings"`
+	Failures int           `json:"failures"`
+}
+
+func (r *doctorReport) add(name string, status checkStatus, format string, args ...any) {
+	r.Checks = append(r.Checks, checkResult{Name: name, Status: status, Message: fmt.Sprintf(format, args...)})
+	switch status {
+	case checkWarn:
+		r.Warnings++
+	case checkFail:
+		r.Failures++
+	}
+}
+
+// runDoctor checks the install and prints the results.
+func (l *Launcher) runDoctor() error {
+	format, _ := l.command.Flags.GetString("format")
+	if format != "text" && format != "json" {
+		return fmt.Errorf("unknown report format: %s", format)
+	}
+
+	log.Logger.Debug("Starting install diagnosis",
+		"function", "runDoctor", "format", format)
+
+	report := &doctorReport{Checks: []checkResult{}}
+	l.checkSteamCMD(report)
+	executable := l.checkServerExecutable(report)
+	l.checkServerLibraries(report, executable)
+	l.checkSteamLibraries(report)
+	l.checkDiskSpace(report)
+	l.checkPermissions(report)
+	l.checkModsFile(report)
+	l.checkServerConfigFile(report)
+
+	switch format {
+	case "json":
+		data, err := json.MarshalIndent(report, "", "    ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	case "text":
+		fmt.Printf("%-6s %-24s %s\n", "STATUS", "CHECK", "MESSAGE")
+		for _, c := range report.Checks {
+			fmt.Printf("%-6s %-24s %s\n", strings.ToUpper(string(c.Status)), c.Name, c.Message)
+		}
+		fmt.Printf("%d check(s): %d warning(s), %d failure(s)\n", len(report.Checks), report.Warnings, report.Failures)
+	}
+
+	if report.Failures > 0 {
+		return fmt.Errorf("%d check(s) failed", report.Failures)
+	}
+	return nil
+}
+
+func (l *Launcher) checkSteamCMD(report *doctorReport) {
+	const name = "SteamCMD"
+
+	steamCMD := steamcmd.New(context.Background(), l.settings.SteamCMDRoot.Value())
+	rootDir := steamCMD.Options().RootDirectory
+	if !steamCMD.IsInstalled() {
+		if l.settings.NoSteam.Value() {
+			report.add(name, checkWarn, "not found in %s, only needed without --nosteam", rootDir)
+			return
+		}
+		report.add(name, checkFail, "not found in %s", rootDir)
+		return
+	}
+
+	script := filepath.Join(rootDir, "steamcmd.sh")
+	if err := unix.Access(script, unix.X_OK); err != nil {
+		report.add(name, checkFail, "%s is not executable: %v", script, err)
+		return
+	}
+	report.add(name, checkPass, "found in %s", rootDir)
+}
+
+// checkServerExecutable returns the path of ucc-bin if it is usable.
+func (l *Launcher) checkServerExecutable(report *doctorReport) string {
+	const name = "Server executable"
+
+	executable := filepath.Join(l.settings.ServerInstallDir.Value(), "System", "ucc-bin")
+	info, err := os.Stat(executable)
+	if err != nil {
+		report.add(name, checkFail, "%s not found, please install using SteamCMD", executable)
+		return ""
+	}
+	if info.Mode()&0111 == 0 {
+		report.add(name, checkFail, "%s is not executable (chmod +x)", executable)
+		return ""
+	}
+	if err := unix.Access(executable, unix.X_OK); err != nil {
+		report.add(name, checkFail, "%s is not executable by the current user: %v", executable, err)
+		return ""
+	}
+	report.add(name, checkPass, "%s", executable)
+	return executable
+}
+
+func (l *Launcher) checkServerLibraries(report *doctorReport, executable string) {
+	const name = "Server libraries"
+
+	if executable == "" {
+		report.add(name, checkWarn, "skipped, the server executable is unusable")
+		return
+	}
+
+	exe, err := elf.Open(executable)
+	if err != nil {
+		report.add(name, checkFail, "failed to read %s: %v", executable, err)
+		return
+	}
+	defer exe.Close()
+
+	var missing []string
+
+	// Program interpreter (dynamic loader)
+	for _, prog := range exe.Progs {
+		if prog.Type != elf.PT_INTERP {
+			continue
+		}
+		data := make([]byte, prog.Filesz)
+		if _, err := prog.ReadAt(data, 0); err != nil {
+			report.add(name, checkFail, "failed to read the %s interpreter: %v", executable, err)
+			return
+		}
+		interp := strings.TrimRight(string(data), "\x00")
+		if !utils.FileExists(interp) {
+			missing = append(missing, interp)
+		}
+	}
+
+	resolved, unresolved := resolveLibraries(exe, filepath.Dir(executable))
+	missing = append(missing, unresolved...)
+	if len(missing) > 0 {
+		report.add(name, checkFail, "missing %s libraries: %s", exe.Class, strings.Join(missing, ", "))
+		return
+	}
+	report.add(name, checkPass, "%d %s libraries resolved", resolved, exe.Class)
+}
+
+// resolveLibraries follows the DT_NEEDED entries of exe, and the ones of
+// its libraries, and returns the number of libraries found and the missing
+// ones. Relative RPATH entries are resolved against workingDir.
+func resolveLibraries(exe *elf.File, workingDir string) (int, []string) {
+	var searchDirs []string
+	for _, tag := range []elf.DynTag{elf.DT_RPATH, elf.DT_RUNPATH} {
+		paths, _ := exe.DynString(tag)
+		for _, p := range paths {
+			for _, dir := range filepath.SplitList(p) {
+				dir = strings.ReplaceAll(dir, "$ORIGIN", workingDir)
+				if !filepath.IsAbs(dir) {
+					dir = filepath.Join(workingDir, dir)
+				}
+				searchDirs = append(searchDirs, dir)
+			}
+		}
+	}
+	searchDirs = append(searchDirs, filepath.SplitList(os.Getenv("LD_LIBRARY_PATH"))...)
+	searchDirs = append(searchDirs, readLdSoConf("/etc/ld.so.conf")...)
+	searchDirs = append(searchDirs, defaultLibDirs...)
+
+	found := map[string]bool{}
+	var missing []string
+
+	queue, _ := exe.ImportedLibraries()
+	for len(queue) > 0 {
+		lib := queue[0]
+		queue = queue[1:]
+		if _, seen := found[lib]; seen {
+			continue
+		}
+
+		path := findLibrary(lib, searchDirs, exe.Class, exe.Machine)
+		found[lib] = path != ""
+		if path == "" {
+			missing = append(missing, lib)
+			continue
+		}
+
+		if f, err := elf.Open(path); err == nil {
+			deps, _ := f.ImportedLibraries()
+			queue = append(queue, deps...)
+			f.Close()
+		}
+	}
+
+	sort.Strings(missing)
+	return len(found) - len(missing), missing
+}
+
+// findLibrary returns the path of the first lib matching class and machine.
+func findLibrary(lib string, dirs []string, class elf.Class, machine elf.Machine) string {
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		path := filepath.Join(dir, lib)
+		f, err := elf.Open(path)
+		if err != nil {
+			continue
+		}
+		match := f.Class == class && f.Machine == machine
+		f.Close()
+		if match {
+			return path
+		}
+	}
+	return ""
+}
+
+// readLdSoConf returns the directories listed in an ld.so.conf file,
+// following its include directives.
+func readLdSoConf(filename string) []string {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var dirs []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if pattern, ok := strings.CutPrefix(line, "include "); ok {
+			pattern = strings.TrimSpace(pattern)
+			if !filepath.IsAbs(pattern) {
+				pattern = filepath.Join(filepath.Dir(filename), pattern)
+			}
+			matches, _ := filepath.Glob(pattern)
+			sort.Strings(matches)
+			for _, m := range matches {
+				dirs = append(dirs, readLdSoConf(m)...)
+			}
+			continue
+		}
+		dirs = append(dirs, line)
+	}
+	return dirs
+}
+
+func (l *Launcher) checkSteamLibraries(report *doctorReport) {
+	const name = "Steam libraries"
+
+	libs := l.steamLibs()
+	srcFiles := make([]string, 0, len(libs))
+	for src := range libs {
+		srcFiles = append(srcFiles, src)
+	}
+	sort.Strings(srcFiles)
+
+	status := checkPass
+	var problems []string
+	for _, src := range srcFiles {
+		dst := libs[src]
+		switch {
+		case !utils.FileExists(src):
+			status = checkFail
+			problems = append(problems, fmt.Sprintf("%s not found", src))
+		case !utils.FileExists(dst):
+			if status == checkPass {
+				status = checkWarn
+			}
+			problems = append(problems, fmt.Sprintf("%s not found, copied on start", dst))
+		default:
+			identical, err := utils.SHA1Compare(src, dst)
+			if err != nil {
+				status = checkFail
+				problems = append(problems, err.Error())
+			} else if !identical {
+				if status == checkPass {
+					status = checkWarn
+				}
+				problems = append(problems, fmt.Sprintf("%s is outdated, updated on start", filepath.Base(dst)))
+			}
+		}
+	}
+
+	if status == checkFail && l.settings.NoSteam.Value() {
+		status = checkWarn
+	}
+	if len(problems) == 0 {
+		report.add(name, checkPass, "%d libraries up-to-date", len(libs))
+		return
+	}
+	report.add(name, status, "%s", strings.Join(problems, "; "))
+}
+
+func (l *Launcher) checkDiskSpace(report *doctorReport) {
+	const name = "Disk space"
+
+	dir := existingParent(l.settings.ServerInstallDir.Value())
+	var stat unix.Statfs_t
+	if err := unix.Statfs(dir, &stat); err != nil {
+		report.add(name, checkWarn, "unable to read the free space of %s: %v", dir, err)
+		return
+	}
+
+	free := stat.Bavail * uint64(stat.Bsize)
+	freeMB := free >> 20
+	switch {
+	case free < diskSpaceFail:
+		report.add(name, checkFail, "%d MB free on %s", freeMB, dir)
+	case free < diskSpaceWarn:
+		report.add(name, checkWarn, "%d MB free on %s, updates may fail", freeMB, dir)
+	default:
+		report.add(name, checkPass, "%d MB free on %s", freeMB, dir)
+	}
+}
+
+func (l *Launcher) checkPermissions(report *doctorReport) {
+	const name = "Permissions"
+
+	installDir := l.settings.ServerInstallDir.Value()
+	dirs := []string{installDir, filepath.Join(installDir, "System")}
+	if !l.settings.NoSteam.Value() {
+		dirs = append(dirs, l.settings.SteamCMDRoot.Value())
+	}
+
+	var problems []string
+	for _, dir := range dirs {
+		// Missing directories are created by SteamCMD
+		target := existingParent(dir)
+		if err := unix.Access(target, unix.W_OK|unix.X_OK); err != nil {
+			problems = append(problems, fmt.Sprintf("%s is not writable: %v", target, err))
+		}
+	}
+	if len(problems) > 0 {
+		report.add(name, checkFail, "%s", strings.Join(problems, "; "))
+		return
+	}
+	report.add(name, checkPass, "install directories are writable")
+}
+
+func (l *Launcher) checkModsFile(report *doctorReport) {
+	const name = "Mods file"
+
+	filename := l.settings.ModsFile.Value()
+	if filename == "" {
+		report.add(name, checkPass, "no mods file specified")
+		return
+	}
+	if !utils.FileExists(filename) {
+		report.add(name, checkWarn, "%s not found, mods installation is skipped", filename)
+		return
+	}
+
+	m, err := mods.ParseModsFile(filename)
+	if err != nil {
+		report.add(name, checkFail, "failed to parse %s: %v", filename, err)
+		return
+	}
+
+	problems := mods.Check(l.settings.ServerInstallDir.Value(), m)
+	status := checkPass
+	messages := make([]string, 0, len(problems))
+	for _, p := range problems {
+		if p.Fatal {
+			status = checkFail
+		} else if status == checkPass {
+			status = checkWarn
+		}
+		messages = append(messages, fmt.Sprintf("%s: %s", p.Mod, p.Message))
+	}
+	if len(messages) > 0 {
+		report.add(name, status, "%s", strings.Join(messages, "; "))
+		return
+	}
+	report.add(name, checkPass, "%s: %d mod(s) defined", filename, len(m))
+}
+
+func (l *Launcher) checkServerConfigFile(report *doctorReport) {
+	const name = "Server configuration"
+
+	kfiFilePath := filepath.Join(l.settings.ServerInstallDir.Value(), "System", l.settings.ConfigFile.Value())
+	if !utils.FileExists(kfiFilePath) {
+		report.add(name, checkFail, "%s not found", kfiFilePath)
+		return
+	}
+
+	kfi, err := l.loadServerIniFile(kfiFilePath)
+	if err != nil {
+		report.add(name, checkFail, "failed to read %s: %v", kfiFilePath, err)
+		return
+	}
+
+	lint := config.Lint(kfi, filepath.Dir(kfiFilePath), l.lintMutators())
+	if lint.Errors > 0 {
+		report.add(name, checkWarn, "%s has %d lint error(s), see kfdsl config lint", kfiFilePath, lint.Errors)
+		return
+	}
+	report.add(name, checkPass, "%s", kfiFilePath)
+}
+
+// existingParent returns dir, or its closest existing parent.
+func existingParent(dir string) string {
+	dir = filepath.Clean(dir)
+	for !utils.FileExists(dir) {
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	return dir
+}
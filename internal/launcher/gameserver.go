@@ -3,7 +3,10 @@ package launcher
 import (
 	"context"
 	"fmt"
+	"os"
 	"path/filepath"
+	"slices"
+	"syscall"
 
 	"github.com/K4rian/kfdsl/internal/log"
 	"github.com/K4rian/kfdsl/internal/services/kfserver"
@@ -18,7 +21,16 @@ func (l *Launcher) startGameServer(ctx context.Context) (*kfserver.KFServer, err
 
 	rootDir := l.settings.ServerInstallDir.Value()
 	configFileName := l.settings.ConfigFile.Value()
-	gameServer := kfserver.New(ctx, l.settings)
+
+	var gameServer *kfserver.KFServer
+	if l.name != "" {
+		gameServer = kfserver.NewInstance(ctx, l.name, l.settings)
+	} else {
+		gameServer = kfserver.New(ctx, l.settings)
+	}
+	// The idle restarts also bring back the configured game settings
+	gameServer.SetIdlePrepare(func() error { return l.withSettings(l.updateServerConfigFiles) })
+	gameServer.SetRestartLock(&serversMu)
 
 	log.Logger.Debug("Initializing KF Dedicated Server",
 		"function", "startGameServer",
@@ -35,6 +47,17 @@ func (l *Launcher) startGameServer(ctx context.Context) (*kfserver.KFServer, err
 		return nil, fmt.Errorf("unable to locate the KF Dedicated Server files in '%s', please install using SteamCMD", gameServer.Options().RootDirectory)
 	}
 
+	log.Logger.Info("Checking the KF Dedicated Server ports...")
+	if err := l.checkPorts(); err != nil {
+		return nil, err
+	}
+
+	if cred := gameServer.Options().Credential; cred != nil {
+		if err := checkRunAs(cred, gameServer.Options().WorkingDirectory); err != nil {
+			return nil, fmt.Errorf("unable to run the server as %s: %w", l.settings.RunAs.Value(), err)
+		}
+	}
+
 	log.Logger.Info("Updating the KF Dedicated Server configuration file...", "file", configFileName)
 	if err := l.updateConfigFile(); err != nil {
 		return nil, fmt.Errorf("failed to update the KF Dedicated Server configuration file %s: %w", configFileName, err)
@@ -76,6 +99,34 @@ func (l *Launcher) startGameServer(ctx context.Context) (*kfserver.KFServer, err
 	return gameServer, nil
 }
 
+// updateServerConfigFiles rewrites the server configuration files from the
+// current settings.
+func (l *Launcher) updateServerConfigFiles() error {
+	configFileName := l.settings.ConfigFile.Value()
+	if err := l.updateConfigFile(); err != nil {
+		return fmt.Errorf("failed to update the KF Dedicated Server configuration file %s: %w", configFileName, err)
+	}
+
+	if l.settings.EnableKFPatcher.Value() {
+		if err := l.updateKFPatcherConfigFile(); err != nil {
+			return fmt.Errorf("failed to update the KFPatcher configuration file: %w", err)
+		}
+	}
+	return nil
+}
+
+// steamLibs maps the SteamCMD libraries to their copy in the server System directory.
+func (l *Launcher) steamLibs() map[string]string {
+	systemDir := filepath.Join(l.settings.ServerInstallDir.Value(), "System")
+	libsDir := filepath.Join(l.settings.SteamCMDRoot.Value(), "linux32")
+
+	return map[string]string{
+		filepath.Join(libsDir, "steamclient.so"):  filepath.Join(systemDir, "steamclient.so"),
+		filepath.Join(libsDir, "libtier0_s.so"):   filepath.Join(systemDir, "libtier0_s.so"),
+		filepath.Join(libsDir, "libvstdlib_s.so"): filepath.Join(systemDir, "libvstdlib_s.so"),
+	}
+}
+
 func (l *Launcher) updateGameServerSteamLibs() ([]string, error) {
 	ret := []string{}
 	rootDir := l.settings.ServerInstallDir.Value()
@@ -85,13 +136,7 @@ func (l *Launcher) updateGameServerSteamLibs() ([]string, error) {
 	log.Logger.Debug("Starting server Steam libraries update",
 		"function", "updateGameServerSteamLibs", "rootDir", rootDir, "systemDir", systemDir, "libsDir", libsDir)
 
-	libs := map[string]string{
-		filepath.Join(libsDir, "steamclient.so"):  filepath.Join(systemDir, "steamclient.so"),
-		filepath.Join(libsDir, "libtier0_s.so"):   filepath.Join(systemDir, "libtier0_s.so"),
-		filepath.Join(libsDir, "libvstdlib_s.so"): filepath.Join(systemDir, "libvstdlib_s.so"),
-	}
-
-	for srcFile, dstFile := range libs {
+	for srcFile, dstFile := range l.steamLibs() {
 		identical, err := utils.SHA1Compare(srcFile, dstFile)
 		if err != nil {
 			log.Logger.Warn("Error comparing file checksums",
@@ -118,3 +163,30 @@ func (l *Launcher) updateGameServerSteamLibs() ([]string, error) {
 		"function", "updateGameServerSteamLibs", "updatedFilesCount", len(ret))
 	return ret, nil
 }
+
+// checkRunAs makes sure the launcher can switch to cred, and warns when the
+// server directory isn't writable by it.
+func checkRunAs(cred *syscall.Credential, dir string) error {
+	if euid := os.Geteuid(); euid != 0 && uint32(euid) != cred.Uid {
+		return fmt.Errorf("the launcher must run as root")
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+
+	mode := info.Mode().Perm()
+	writable := mode&0002 != 0 ||
+		(stat.Uid == cred.Uid && mode&0200 != 0) ||
+		(stat.Gid == cred.Gid || slices.Contains(cred.Groups, stat.Gid)) && mode&0020 != 0
+	if !writable {
+		log.Logger.Warn("The server directory is not writable by the server user, the server won't be able to save its configuration",
+			"function", "checkRunAs", "dir", dir, "uid", cred.Uid, "gid", cred.Gid)
+	}
+	return nil
+}
@@ -5,28 +5,42 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/K4rian/kfdsl/cmd"
 	"github.com/K4rian/kfdsl/internal/log"
+	"github.com/K4rian/kfdsl/internal/services/base"
 	"github.com/K4rian/kfdsl/internal/services/kfserver"
 	"github.com/K4rian/kfdsl/internal/settings"
 )
 
 type Launcher struct {
-	settings *settings.Settings
+	settings   *settings.Settings
+	settingsMu sync.RWMutex // Guards settings against the reloads, see withSettings
+	command    *cmd.Command
+	name       string      // Instance name, empty when a single server is run
+	instances  []*Launcher // Instances defined in the launcher config file
+	reloading  atomic.Bool
 }
 
+// serversMu is held while the scheduled tasks, updates, reloads and the
+// restarts of the servers on their own stop the servers or change the map.
+// The instances share it, an update stops all of them.
+var serversMu sync.Mutex
+
 func New() *Launcher {
 	return &Launcher{
 		settings: &settings.Settings{},
+		command:  &cmd.Command{},
 	}
 }
 
 func (l *Launcher) Run() error {
 	// Build the root command and execute it
-	rootCmd := cmd.BuildRootCommand(l.settings)
+	rootCmd := cmd.BuildRootCommand(l.settings, l.command)
 	if err := rootCmd.Execute(); err != nil {
 		return err
 	}
@@ -46,13 +60,33 @@ func (l *Launcher) Run() error {
 	log.Logger.Debug("Log system initialized",
 		"function", "Run")
 
+	// Run the subcommand, if any, instead of the server
+	if l.command.Name != "" {
+		return l.runCommand()
+	}
+
+	// Load the server instances, if any
+	if err := l.loadInstances(); err != nil {
+		return err
+	}
+
 	// Create a cancel context
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// Orphaned processes are reparented to the launcher when it runs as PID 1
+	if err := base.StartReaper(ctx, l.settings.Subreaper.Value()); err != nil {
+		log.Logger.Error("Failed to start the orphaned process reaper", "error", err)
+	}
+
 	signalChan := make(chan os.Signal, 1)
 	signal.Notify(signalChan, os.Interrupt, syscall.SIGTERM)
 
+	// SIGHUP reloads the launcher configuration, so does the HTTP API
+	reloadChan := make(chan os.Signal, 1)
+	signal.Notify(reloadChan, syscall.SIGHUP)
+	defer signal.Stop(reloadChan)
+
 	// Print all settings
 	l.settings.Print()
 
@@ -70,17 +104,24 @@ func (l *Launcher) Run() error {
 			"function", "Run")
 	}
 
-	var server *kfserver.KFServer
+	// Each instance runs its own server, a single server is run otherwise
+	instances := l.instances
+	if len(instances) == 0 {
+		instances = []*Launcher{l}
+	}
+	servers := make([]*kfserver.KFServer, len(instances))
 	var startTime time.Time
 
 	defer func() {
 		log.Logger.Info("Shutting down the KF Dedicated Server...")
 
-		if server != nil && server.IsRunning() {
-			log.Logger.Debug("Waiting for the KF Dedicated Server to stop...",
-				"function", "Run")
-			if err := server.Wait(); err != nil {
-				log.Logger.Error("KF Dedicated Server raised an error during shutdown", "error", err)
+		for _, server := range servers {
+			if server != nil && server.IsRunning() {
+				log.Logger.Debug("Waiting for the KF Dedicated Server to stop...",
+					"function", "Run", "service", server.Name())
+				if err := server.Wait(); err != nil {
+					log.Logger.Error("KF Dedicated Server raised an error during shutdown", "service", server.Name(), "error", err)
+				}
 			}
 		}
 		log.Logger.Info("KF Dedicated Server has been stopped.")
@@ -88,14 +129,42 @@ func (l *Launcher) Run() error {
 			"function", "Run", "elapsedTime", time.Since(startTime))
 	}()
 
-	// Start the Killing Floor Dedicated Server
+	// Start the Killing Floor Dedicated Server(s)
 	startTime = time.Now()
-	server, err := l.startGameServer(ctx)
-	if err != nil {
-		log.Logger.Error("KF Dedicated Server raised an error", "error", err)
+	for i, inst := range instances {
+		if inst.name != "" {
+			log.Logger.Info("Starting instance...", "instance", inst.name)
+		}
+
+		server, err := inst.startGameServer(ctx)
+		if err != nil {
+			log.Logger.Error("KF Dedicated Server raised an error", inst.logAttrs("error", err)...)
+			continue
+		}
+		servers[i] = server
 	}
 
-	<-signalChan
+	// Run the scheduled tasks and watch for server updates
+	l.runSchedule(ctx, instances, servers)
+	go l.watchUpdates(ctx, instances, servers)
+	go l.serveAPI(ctx, instances, servers, signalChan)
+
+	for running := true; running; {
+		select {
+		case sig := <-signalChan:
+			running = false
+			// The players are warned on SIGTERM, SIGINT stops the servers right away
+			if sig == syscall.SIGTERM {
+				shutdownServers(servers, signalChan)
+			}
+		case <-reloadChan:
+			for i, inst := range instances {
+				if servers[i] != nil {
+					go inst.reload(ctx, servers[i])
+				}
+			}
+		}
+	}
 	signal.Stop(signalChan)
 	cancel()
 
@@ -104,3 +173,93 @@ func (l *Launcher) Run() error {
 
 	return nil
 }
+
+// shutdownServers warns the players of the running servers, then stops them.
+// Another signal received meanwhile stops them right away.
+func shutdownServers(servers []*kfserver.KFServer, signalChan <-chan os.Signal) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		select {
+		case <-signalChan:
+			log.Logger.Info("Signal received again, stopping the KF Dedicated Server now...")
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for _, server := range servers {
+		if server == nil || !server.IsRunning() {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := server.Shutdown(ctx); err != nil {
+				log.Logger.Error("Failed to stop the KF Dedicated Server", "service", server.Name(), "error", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// loadInstances parses the settings of the instances defined in the launcher
+// config file and checks that they don't conflict with each other.
+func (l *Launcher) loadInstances() error {
+	names := cmd.InstanceNames()
+	if len(names) == 0 {
+		return nil
+	}
+
+	for _, name := range names {
+		sett := &settings.Settings{}
+		if err := cmd.InstanceSettings(name, sett); err != nil {
+			return err
+		}
+		l.instances = append(l.instances, &Launcher{
+			settings: sett,
+			command:  l.command,
+			name:     name,
+		})
+	}
+
+	if err := checkInstances(l.instances); err != nil {
+		return err
+	}
+	log.Logger.Info("Running in multi-instance mode", "instances", names)
+	return nil
+}
+
+// withSettings runs fn while the settings can't be swapped by a reload. The
+// goroutines running alongside the reloads, such as the scheduled tasks,
+// read the settings through it. fn must not call withSettings again.
+func (l *Launcher) withSettings(fn func() error) error {
+	l.settingsMu.RLock()
+	defer l.settingsMu.RUnlock()
+	return fn()
+}
+
+// currentSettings returns the settings, for the goroutines running alongside
+// the reloads. A reload swaps the settings rather than updating them.
+func (l *Launcher) currentSettings() *settings.Settings {
+	l.settingsMu.RLock()
+	defer l.settingsMu.RUnlock()
+	return l.settings
+}
+
+// setSettings swaps the settings, only the reloads do.
+func (l *Launcher) setSettings(sett *settings.Settings) {
+	l.settingsMu.Lock()
+	defer l.settingsMu.Unlock()
+	l.settings = sett
+}
+
+// logAttrs prepends the instance name, if any, to the log key/values.
+func (l *Launcher) logAttrs(args ...any) []any {
+	if l.name != "" {
+		return append([]any{"instance", l.name}, args...)
+	}
+	return args
+}
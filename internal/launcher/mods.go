@@ -1,15 +1,27 @@
 package launcher
 
 import (
+	"errors"
 	"fmt"
+	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 
+	"github.com/K4rian/kfdsl/internal/config/ini"
 	"github.com/K4rian/kfdsl/internal/log"
 	"github.com/K4rian/kfdsl/internal/mods"
 	"github.com/K4rian/kfdsl/internal/utils"
 )
 
+// modsMu serializes the mods installations, the instances share the server
+// System directory and the mods lock state.
+var modsMu sync.Mutex
+
 func (l *Launcher) installMods() error {
+	modsMu.Lock()
+	defer modsMu.Unlock()
+
 	filename := l.settings.ModsFile.Value()
 
 	if filename == "" {
@@ -27,11 +39,275 @@ func (l *Launcher) installMods() error {
 		return fmt.Errorf("failed to parse mods file %s: %w", filename, err)
 	}
 
+	if err := l.verifyMods(filename, m); err != nil {
+		return fmt.Errorf("mods file %s verification failed: %w", filename, err)
+	}
+
 	installed := make([]string, 0)
-	mods.InstallMods(l.settings.ServerInstallDir.Value(), m, &installed)
+	if err := mods.InstallMods(l.settings.ServerInstallDir.Value(), m, &installed); err != nil {
+		return err
+	}
 
 	log.Logger.Debug("Completed mods installation process")
 	log.Logger.Info("The following mods were installed:", "mods", strings.Join(installed, " / "))
 
+	for _, name := range installed {
+		mod := m[name]
+		if len(mod.Config) == 0 {
+			continue
+		}
+
+		log.Logger.Info("Updating mod configuration files...", "mod", name)
+		if err := l.updateModConfigFiles(name, mod); err != nil {
+			return fmt.Errorf("failed to update %s configuration files: %w", name, err)
+		}
+		log.Logger.Info("Mod configuration files successfully updated", "mod", name)
+	}
+	return nil
+}
+
+// modsConflicts reports the files installed differently by the mods files of
+// the instances sharing a server directory, each instance would replace them
+// on its start.
+func modsConflicts(instances []*Launcher) []string {
+	type owner struct {
+		instance string
+		file     mods.ModFile
+	}
+
+	var conflicts []string
+	owners := map[string]owner{} // By file path
+	for _, inst := range instances {
+		filename := inst.settings.ModsFile.Value()
+		if filename == "" || !utils.FileExists(filename) {
+			continue
+		}
+		// An invalid mods file is reported by the installation
+		modList, err := mods.ParseModsFile(filename)
+		if err != nil {
+			continue
+		}
+
+		dir := filepath.Clean(inst.settings.ServerInstallDir.Value())
+		files := mods.InstallFiles(modList)
+		paths := make([]string, 0, len(files))
+		for path := range files {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+
+		for _, path := range paths {
+			file := files[path]
+			prev, found := owners[filepath.Join(dir, path)]
+			if !found {
+				owners[filepath.Join(dir, path)] = owner{inst.name, file}
+				continue
+			}
+			if !prev.file.Same(file) {
+				conflicts = append(conflicts, fmt.Sprintf("%s of mod %s (instance %s) differs from mod %s (instance %s)",
+					path, file.Mod, inst.name, prev.file.Mod, prev.instance))
+			}
+		}
+	}
+	return conflicts
+}
+
+func (l *Launcher) verifyMods(filename string, m map[string]*mods.Mod) error {
+	trustStoreDir := l.settings.ModsTrustStore.Value()
+	requireSignature := l.settings.ModsRequireSignature.Value()
+
+	trustStore, err := mods.LoadTrustStore(trustStoreDir)
+	if err != nil {
+		return err
+	}
+	log.Logger.Debug("Mods trust store loaded",
+		"function", "verifyMods", "dir", trustStoreDir, "keys", trustStore.Len(), "requireSignature", requireSignature)
+
+	if requireSignature && trustStore.Len() == 0 {
+		return fmt.Errorf("signatures are required but the trust store %s has no publisher key", trustStoreDir)
+	}
+
+	// A valid detached signature covers every mod of the file
+	indexSigned, err := mods.VerifyIndexSignature(filename, trustStore)
+	if errors.Is(err, mods.ErrUnknownKey) && !requireSignature {
+		log.Logger.Warn("Mods file is signed by an unknown publisher", "file", filename, "error", err)
+	} else if err != nil {
+		return err
+	}
+	if indexSigned {
+		log.Logger.Info("Mods file signature verified", "file", filename)
+		return mods.VerifyPinnedMods(m, requireSignature)
+	}
+
+	return mods.VerifyMods(m, trustStore, requireSignature)
+}
+
+func (l *Launcher) signMods() error {
+	filename := l.settings.ModsFile.Value()
+	flags := l.command.Flags
+	keyFile, _ := flags.GetString("key")
+	keyID, _ := flags.GetString("key-id")
+	detached, _ := flags.GetBool("detached")
+
+	if keyID == "" {
+		keyID = strings.TrimSuffix(filepath.Base(keyFile), filepath.Ext(keyFile))
+	}
+
+	key, err := mods.ReadPrivateKey(keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to read private key %s: %w", keyFile, err)
+	}
+
+	if detached {
+		sigFile, err := mods.SignIndex(filename, keyID, key)
+		if err != nil {
+			return fmt.Errorf("failed to sign mods file %s: %w", filename, err)
+		}
+		log.Logger.Info("Mods file successfully signed", "file", filename, "signature", sigFile, "keyID", keyID)
+		return nil
+	}
+
+	m, err := mods.ParseModsFile(filename)
+	if err != nil {
+		return fmt.Errorf("failed to parse mods file %s: %w", filename, err)
+	}
+
+	names := l.command.Args
+	if len(names) == 0 {
+		for name := range m {
+			names = append(names, name)
+		}
+	}
+
+	for _, name := range names {
+		mod, ok := m[name]
+		if !ok {
+			return fmt.Errorf("mod %s not found in %s", name, filename)
+		}
+		if err := mod.Sign(name, keyID, key); err != nil {
+			return fmt.Errorf("failed to sign mod %s: %w", name, err)
+		}
+		log.Logger.Info("Mod successfully signed", "mod", name, "keyID", keyID)
+	}
+
+	if err := mods.WriteModsFile(filename, m); err != nil {
+		return fmt.Errorf("failed to write mods file %s: %w", filename, err)
+	}
+	return nil
+}
+
+func (l *Launcher) generateModsKey() error {
+	keyID := l.command.Args[0]
+	dir, _ := l.command.Flags.GetString("dir")
+
+	privFile, pubFile, err := mods.GenerateKey(dir, keyID)
+	if err != nil {
+		return fmt.Errorf("failed to generate key %s: %w", keyID, err)
+	}
+	log.Logger.Info("Publisher key pair successfully generated", "keyID", keyID, "privateKey", privFile, "publicKey", pubFile)
+	log.Logger.Info("Copy the public key to the trust store of your servers", "trustStore", l.settings.ModsTrustStore.Value())
+	return nil
+}
+
+func (l *Launcher) rollbackMods() error {
+	dir := l.settings.ServerInstallDir.Value()
+
+	log.Logger.Info("Rolling back the last mods installation...", "dir", dir)
+	lock, err := mods.RollbackMods(dir)
+	if err != nil {
+		return fmt.Errorf("failed to roll back mods: %w", err)
+	}
+
+	names := make([]string, 0, len(lock.Mods))
+	for name, mod := range lock.Mods {
+		names = append(names, fmt.Sprintf("%s (%s)", name, mod.Version))
+	}
+	log.Logger.Info("Mods successfully rolled back", "generation", lock.Generation, "mods", strings.Join(names, " / "))
+	log.Logger.Warn("Update the mods file accordingly, or the rolled back mods will be reinstalled on next start", "file", l.settings.ModsFile.Value())
+	return nil
+}
+
+func (l *Launcher) updateModConfigFiles(name string, mod *mods.Mod) error {
+	data := mods.NewConfigTemplateData(name, mod, l.settings)
+
+	for _, cf := range mod.Config {
+		filePath, err := cf.FilePath(l.settings.ServerInstallDir.Value())
+		if err != nil {
+			return err
+		}
+
+		log.Logger.Debug("Starting mod configuration file update",
+			"function", "updateModConfigFiles", "mod", name, "file", filePath)
+
+		keys, err := cf.Render(data)
+		if err != nil {
+			log.Logger.Warn("Failed to render the mod configuration values",
+				"function", "updateModConfigFiles", "mod", name, "file", filePath, "error", err)
+			return err
+		}
+
+		// Read the ini file, if any. Missing files are created on save
+		iniFile := ini.NewGenericIniFile(name)
+		if utils.FileExists(filePath) {
+			if err := iniFile.Load(filePath); err != nil {
+				log.Logger.Warn("Failed to read the mod configuration file",
+					"function", "updateModConfigFiles", "mod", name, "file", filePath, "error", err)
+				return err
+			}
+			log.Logger.Debug("Mod configuration file successfully loaded",
+				"function", "updateModConfigFiles", "mod", name, "file", filePath)
+		} else {
+			log.Logger.Debug("Missing mod configuration file, a new one will be created",
+				"function", "updateModConfigFiles", "mod", name, "file", filePath)
+		}
+
+		cuList := make([]configUpdater[any], 0, len(keys))
+		for _, k := range keys {
+			cuList = append(cuList, newConfigUpdater(
+				fmt.Sprintf("[%s] %s", k.Section, k.Key),
+				func() any {
+					if !iniFile.HasKey(k.Section, k.Key) {
+						return nil
+					}
+					return iniFile.GetKey(k.Section, k.Key, "")
+				},
+				func(v any) bool { return iniFile.SetKey(k.Section, k.Key, v.(string), true) },
+				k.Value,
+			))
+		}
+
+		changed := false
+		for _, conf := range cuList {
+			currentValue := conf.gv()
+			if currentValue != conf.nv {
+				if !conf.sv(conf.nv) {
+					log.Logger.Warn(fmt.Sprintf("Failed to update %s %s configuration", name, conf.name),
+						"function", "updateModConfigFiles", "file", filePath, "confName", conf.name, "confOldValue", currentValue, "confNewValue", conf.nv)
+					return fmt.Errorf("%s: failed to set the new value: %v", conf.name, conf.nv)
+				}
+				log.Logger.Debug(fmt.Sprintf("Updated %s %s configuration", name, conf.name),
+					"function", "updateModConfigFiles", "file", filePath, "confName", conf.name, "confOldValue", currentValue, "confNewValue", conf.nv)
+				changed = true
+			}
+		}
+
+		if !changed {
+			log.Logger.Debug("Mod configuration file is already up-to-date",
+				"function", "updateModConfigFiles", "mod", name, "file", filePath)
+			continue
+		}
+
+		// Save the ini file
+		if _, err := utils.CreateDirIfNotExists(filepath.Dir(filePath)); err != nil {
+			return err
+		}
+		if err := iniFile.Save(filePath); err != nil {
+			log.Logger.Error("Failed to save the mod configuration file",
+				"function", "updateModConfigFiles", "mod", name, "file", filePath, "error", err)
+			return err
+		}
+		log.Logger.Debug("Mod configuration file successfully saved",
+			"function", "updateModConfigFiles", "mod", name, "file", filePath)
+	}
 	return nil
 }
@@ -0,0 +1,70 @@
+package launcher
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/K4rian/kfdsl/internal/arguments"
+	"github.com/K4rian/kfdsl/internal/settings"
+)
+
+func TestModsConflicts(t *testing.T) {
+	dir := t.TempDir()
+	writeMods := func(name, content string) string {
+		filename := filepath.Join(dir, name)
+		if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return filename
+	}
+	perks := `"ServerPerks": {"version": "1.0", "download_url": "https://example.com/perks.zip", "enabled": true,
+		"install": [{"name": "ServerPerks.u", "path": "System", "type": "file"}]}`
+	perks2 := `"ServerPerks": {"version": "2.0", "download_url": "https://example.com/perks.zip", "enabled": true,
+		"install": [{"name": "ServerPerks.u", "path": "System", "type": "file"}]}`
+	other := `"Other": {"version": "1.0", "download_url": "https://example.com/other.zip", "enabled": true,
+		"install": [{"name": "Other.u", "path": "System", "type": "file"}]}`
+	disabled := `"ServerPerks": {"version": "2.0", "download_url": "https://example.com/perks.zip",
+		"install": [{"name": "ServerPerks.u", "path": "System", "type": "file"}]}`
+
+	perksFile := writeMods("perks.json", "{"+perks+"}")
+	perks2File := writeMods("perks2.json", "{"+perks2+"}")
+	otherFile := writeMods("other.json", "{"+other+"}")
+	bothFile := writeMods("both.json", "{"+perks+", "+other+"}")
+	disabledFile := writeMods("disabled.json", "{"+disabled+"}")
+
+	newInstance := func(name, serverDir, modsFile string) *Launcher {
+		sett := &settings.Settings{
+			ServerInstallDir: arguments.New("Server Install Dir", serverDir, nil, nil, false),
+			ModsFile:         arguments.New("Mods File", modsFile, nil, nil, false),
+		}
+		for _, arg := range []interface{ Parse() error }{sett.ServerInstallDir, sett.ModsFile} {
+			if err := arg.Parse(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return &Launcher{settings: sett, name: name}
+	}
+
+	tests := []struct {
+		name      string
+		instances []*Launcher
+		want      int
+	}{
+		{"same mods file", []*Launcher{newInstance("kf1", "/srv/kf", perksFile), newInstance("kf2", "/srv/kf", perksFile)}, 0},
+		{"different mods", []*Launcher{newInstance("kf1", "/srv/kf", perksFile), newInstance("kf2", "/srv/kf", otherFile)}, 0},
+		{"shared mod", []*Launcher{newInstance("kf1", "/srv/kf", perksFile), newInstance("kf2", "/srv/kf", bothFile)}, 0},
+		{"other version", []*Launcher{newInstance("kf1", "/srv/kf", perksFile), newInstance("kf2", "/srv/kf", perks2File)}, 1},
+		{"other server directory", []*Launcher{newInstance("kf1", "/srv/kf1", perksFile), newInstance("kf2", "/srv/kf2", perks2File)}, 0},
+		{"disabled mod", []*Launcher{newInstance("kf1", "/srv/kf", perksFile), newInstance("kf2", "/srv/kf", disabledFile)}, 0},
+		{"no mods file", []*Launcher{newInstance("kf1", "/srv/kf", perksFile), newInstance("kf2", "/srv/kf", "")}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := modsConflicts(tt.instances); len(got) != tt.want {
+				t.Errorf("modsConflicts() = %q, want %d conflict(s)", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package launcher
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/K4rian/kfdsl/internal/arguments"
+	"github.com/K4rian/kfdsl/internal/log"
+	"github.com/K4rian/kfdsl/internal/settings"
+)
+
+// serverPort is a port bound by the game server.
+type serverPort struct {
+	name     string
+	ip       string // Empty for all the addresses
+	port     int
+	protocol string // udp or tcp
+}
+
+func (p serverPort) String() string {
+	return fmt.Sprintf("%s port %s", p.name, p.address())
+}
+
+// address returns the port, protocol and IP address if any, e.g. 7707/udp.
+func (p serverPort) address() string {
+	addr := fmt.Sprintf("%d/%s", p.port, p.protocol)
+	if p.ip != "" {
+		addr = p.ip + ":" + addr
+	}
+	return addr
+}
+
+// overlaps returns true when p and o can't be bound at the same time.
+func (p serverPort) overlaps(o serverPort) bool {
+	return p.port == o.port && p.protocol == o.protocol &&
+		(p.ip == "" || o.ip == "" || p.ip == o.ip)
+}
+
+// steamPorts are used by the Steam client of the server. Their number can't
+// be configured, so they are only checked against the configured ports.
+var steamPorts = []serverPort{
+	{"Steam master server", "", 28852, "udp"},
+	{"Steam master server", "", 28852, "tcp"},
+	{"Steam", "", 20560, "udp"},
+	{"Steam", "", 20560, "tcp"},
+}
+
+// serverPorts returns the ports bound by a server using sett. UE2 also
+// binds the port following the game port to answer the server queries.
+// Every port, the WebAdmin one included, is bound on the multihome address.
+func serverPorts(sett *settings.Settings) []serverPort {
+	ip := sett.IP.Value()
+	gamePort := sett.GamePort.Value()
+	ports := []serverPort{
+		{"game", ip, gamePort, "udp"},
+		{"query", ip, gamePort + 1, "udp"},
+		{"GameSpy", ip, sett.GameSpyPort.Value(), "udp"},
+	}
+	if sett.EnableWebAdmin.Value() {
+		ports = append(ports, serverPort{"WebAdmin", ip, sett.WebAdminPort.Value(), "tcp"})
+	}
+	return ports
+}
+
+// webAdminIP returns the address of the WebAdmin panel URL, which defaults
+// to the server one.
+func webAdminIP(sett *settings.Settings) string {
+	if ip := sett.WebAdminIP.Value(); ip != "" {
+		return ip
+	}
+	return sett.IP.Value()
+}
+
+// portOwner is a port bound by a given instance.
+type portOwner struct {
+	instance string
+	port     serverPort
+}
+
+func (o portOwner) String() string {
+	if o.instance == "" {
+		return o.port.name + " port"
+	}
+	return fmt.Sprintf("%s port of instance %s", o.port.name, o.instance)
+}
+
+// portConflicts returns the ports used more than once by the instances,
+// or overlapping with the Steam ports. The same port can be used on
+// different IP addresses.
+func portConflicts(instances []*Launcher) []string {
+	var conflicts []string
+
+	var owners []portOwner
+	for _, p := range steamPorts {
+		owners = append(owners, portOwner{port: p})
+	}
+
+	for _, inst := range instances {
+	ports:
+		for _, p := range serverPorts(inst.settings) {
+			owner := portOwner{inst.name, p}
+			if p.port > 65535 {
+				conflicts = append(conflicts, fmt.Sprintf("%s is out of range, the game port must be lower than 65535", owner))
+				continue
+			}
+
+			for _, prev := range owners {
+				if p.overlaps(prev.port) {
+					conflicts = append(conflicts, fmt.Sprintf("%s: the %s conflicts with the %s", p.address(), owner, prev))
+					continue ports
+				}
+			}
+			owners = append(owners, owner)
+		}
+	}
+	return conflicts
+}
+
+// sharedSettings are the settings, by field name, applied to the files the
+// instances sharing a server directory have in common: the KFPatcher mod and
+// its configuration file. They must be the same for all these instances.
+// Each instance installs its own mods file, see modsConflicts.
+var sharedSettings = []string{
+	"EnableKFPatcher",
+	"KFPHidePerks",
+	"KFPDisableZedTime",
+	"KFPBuyEverywhere",
+	"KFPEnableAllTraders",
+	"KFPAllTradersMessage",
+}
+
+// checkInstances reports the ports and configuration files used by more
+// than one instance, and the shared settings and mod files differing between
+// instances of the same server directory.
+func checkInstances(instances []*Launcher) error {
+	errs := portConflicts(instances)
+	errs = append(errs, modsConflicts(instances)...)
+
+	configFiles := map[string]string{}
+	for _, inst := range instances {
+		configFile := strings.ToLower(inst.settings.ConfigFile.Value())
+		if owner, used := configFiles[configFile]; used {
+			errs = append(errs, fmt.Sprintf("configuration file %s of instance %s is already used by instance %s",
+				inst.settings.ConfigFile.Value(), inst.name, owner))
+			continue
+		}
+		configFiles[configFile] = inst.name
+	}
+
+	firsts := map[string]*Launcher{} // First instance of each server directory
+	for _, inst := range instances {
+		dir := filepath.Clean(inst.settings.ServerInstallDir.Value())
+		first, found := firsts[dir]
+		if !found {
+			firsts[dir] = inst
+			continue
+		}
+		for _, name := range sharedSettingsDiff(first.settings, inst.settings) {
+			errs = append(errs, fmt.Sprintf("%s of instance %s differs from instance %s, it must be the same for the instances sharing a server directory",
+				name, inst.name, first.name))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("instances conflict:\n  %s", strings.Join(errs, "\n  "))
+	}
+	return nil
+}
+
+// sharedSettingsDiff returns the names of the shared settings differing
+// between a and b.
+func sharedSettingsDiff(a *settings.Settings, b *settings.Settings) []string {
+	var names []string
+	av := reflect.ValueOf(a).Elem()
+	bv := reflect.ValueOf(b).Elem()
+	for _, field := range sharedSettings {
+		aArg := av.FieldByName(field).Interface().(arguments.ParsableArgument)
+		bArg := bv.FieldByName(field).Interface().(arguments.ParsableArgument)
+		if aArg.AnyValue() != bArg.AnyValue() {
+			names = append(names, aArg.Name())
+		}
+	}
+	return names
+}
+
+// checkPorts makes sure the server ports don't overlap and can be bound.
+func (l *Launcher) checkPorts() error {
+	if conflicts := portConflicts([]*Launcher{l}); len(conflicts) > 0 {
+		return fmt.Errorf("port conflict:\n  %s", strings.Join(conflicts, "\n  "))
+	}
+
+	var errs []string
+	for _, p := range serverPorts(l.settings) {
+		if err := testBindPort(p); err != nil {
+			msg := fmt.Sprintf("%s is not available: %v", p, err)
+			if errors.Is(err, syscall.EADDRINUSE) {
+				msg += " (is another server running?)"
+			}
+			errs = append(errs, msg)
+			continue
+		}
+		log.Logger.Debug("Port available",
+			"function", "checkPorts", "address", p.address(), "name", p.name)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("unavailable port:\n  %s", strings.Join(errs, "\n  "))
+	}
+	return nil
+}
+
+// testBindPort binds p on its IP address, then releases it right away.
+func testBindPort(p serverPort) error {
+	addr := net.JoinHostPort(p.ip, strconv.Itoa(p.port))
+
+	if p.protocol == "tcp" {
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			return err
+		}
+		return ln.Close()
+	}
+
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
@@ -0,0 +1,121 @@
+package launcher
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/K4rian/kfdsl/internal/arguments"
+	"github.com/K4rian/kfdsl/internal/settings"
+)
+
+// testPorts are the ports of a test instance, an empty webAdmin disabling
+// the WebAdmin panel.
+type testPorts struct {
+	ip       string
+	game     int
+	gameSpy  int
+	webAdmin int
+	adminIP  string // WebAdmin URL address
+}
+
+func newTestInstance(t *testing.T, name string, p testPorts) *Launcher {
+	t.Helper()
+	sett := &settings.Settings{
+		IP:             arguments.New("IP", p.ip, nil, nil, false),
+		GamePort:       arguments.New("Game Port", p.game, nil, nil, false),
+		GameSpyPort:    arguments.New("GameSpy Port", p.gameSpy, nil, nil, false),
+		EnableWebAdmin: arguments.New("Enable WebAdmin", p.webAdmin != 0, nil, nil, false),
+		WebAdminPort:   arguments.New("WebAdmin Port", p.webAdmin, nil, nil, false),
+		WebAdminIP:     arguments.New("WebAdmin IP", p.adminIP, nil, nil, false),
+	}
+	for _, arg := range []interface{ Parse() error }{sett.IP, sett.GamePort, sett.GameSpyPort, sett.EnableWebAdmin, sett.WebAdminPort, sett.WebAdminIP} {
+		if err := arg.Parse(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &Launcher{settings: sett, name: name}
+}
+
+func TestPortConflicts(t *testing.T) {
+	tests := []struct {
+		name  string
+		a, b  testPorts
+		wants []string // Substrings of the expected conflicts
+	}{
+		{
+			name: "distinct ports",
+			a:    testPorts{game: 7707, gameSpy: 7717, webAdmin: 8075},
+			b:    testPorts{game: 7727, gameSpy: 7737, webAdmin: 8076},
+		},
+		{
+			name:  "same game port",
+			a:     testPorts{game: 7707, gameSpy: 7717},
+			b:     testPorts{game: 7707, gameSpy: 7737},
+			wants: []string{"7707/udp: the game port of instance b conflicts with the game port of instance a", "7708/udp: the query port of instance b"},
+		},
+		{
+			name:  "game port on the query port",
+			a:     testPorts{game: 7707, gameSpy: 7717},
+			b:     testPorts{game: 7708, gameSpy: 7737},
+			wants: []string{"7708/udp: the game port of instance b conflicts with the query port of instance a"},
+		},
+		{
+			name:  "same WebAdmin port",
+			a:     testPorts{game: 7707, gameSpy: 7717, webAdmin: 8075},
+			b:     testPorts{game: 7727, gameSpy: 7737, webAdmin: 8075},
+			wants: []string{"8075/tcp: the WebAdmin port of instance b"},
+		},
+		{
+			name: "disabled WebAdmin",
+			a:    testPorts{game: 7707, gameSpy: 7717, webAdmin: 8075},
+			b:    testPorts{game: 7727, gameSpy: 7737},
+		},
+		{
+			name: "other protocol",
+			a:    testPorts{game: 7707, gameSpy: 7717},
+			b:    testPorts{game: 7727, gameSpy: 7737, webAdmin: 7707},
+		},
+		{
+			name: "WebAdmin URL address",
+			a:    testPorts{ip: "10.0.0.1", game: 7707, gameSpy: 7717, webAdmin: 8075, adminIP: "203.0.113.1"},
+			b:    testPorts{ip: "10.0.0.2", game: 7707, gameSpy: 7717, webAdmin: 8075, adminIP: "203.0.113.1"},
+		},
+		{
+			name: "different IP addresses",
+			a:    testPorts{ip: "10.0.0.1", game: 7707, gameSpy: 7717},
+			b:    testPorts{ip: "10.0.0.2", game: 7707, gameSpy: 7717},
+		},
+		{
+			name:  "all the IP addresses",
+			a:     testPorts{game: 7707, gameSpy: 7717},
+			b:     testPorts{ip: "10.0.0.2", game: 7707, gameSpy: 7737},
+			wants: []string{"10.0.0.2:7707/udp", "10.0.0.2:7708/udp"},
+		},
+		{
+			name:  "Steam port",
+			a:     testPorts{game: 7707, gameSpy: 20560},
+			b:     testPorts{game: 7727, gameSpy: 7737},
+			wants: []string{"20560/udp: the GameSpy port of instance a conflicts with the Steam port"},
+		},
+		{
+			name:  "query port out of range",
+			a:     testPorts{game: 7707, gameSpy: 7717},
+			b:     testPorts{game: 65535, gameSpy: 7737},
+			wants: []string{"query port of instance b is out of range"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := portConflicts([]*Launcher{newTestInstance(t, "a", tt.a), newTestInstance(t, "b", tt.b)})
+			if len(got) != len(tt.wants) {
+				t.Fatalf("portConflicts() = %q, want %d conflicts", got, len(tt.wants))
+			}
+			for i, want := range tt.wants {
+				if !strings.Contains(got[i], want) {
+					t.Errorf("portConflicts()[%d] = %q, want %q", i, got[i], want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,381 @@
+package launcher
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/K4rian/kfdsl/cmd"
+	"github.com/K4rian/kfdsl/internal/arguments"
+	"github.com/K4rian/kfdsl/internal/config/ini"
+	"github.com/K4rian/kfdsl/internal/log"
+	"github.com/K4rian/kfdsl/internal/services/kfserver"
+	"github.com/K4rian/kfdsl/internal/settings"
+)
+
+// reloadScope tells how a changed setting is applied on reload.
+type reloadScope int
+
+const (
+	reloadConfig      reloadScope = iota // Written in the configuration files, applied on a map change when possible
+	reloadCommandLine                    // Passed on the server command-line, needs a server restart
+	reloadImmediate                      // Used by the launcher only, applied right away
+	reloadLauncher                       // Needs the launcher to be restarted
+)
+
+func (s reloadScope) String() string {
+	switch s {
+	case reloadCommandLine:
+		return "command-line"
+	case reloadImmediate:
+		return "immediate"
+	case reloadLauncher:
+		return "launcher"
+	}
+	return "config"
+}
+
+const reloadPollInterval = 15 * time.Second
+
+// reloadScopes lists the settings, by field name, not applied through the
+// configuration files.
+var reloadScopes = map[string]reloadScope{
+	"ConfigFile":           reloadCommandLine,
+	"IP":                   reloadCommandLine,
+	"GameMode":             reloadCommandLine,
+	"StartupMap":           reloadCommandLine,
+	"Mutators":             reloadCommandLine,
+	"EnableMutLoader":      reloadCommandLine,
+	"Unsecure":             reloadCommandLine,
+	"ExtraArgs":            reloadCommandLine,
+	"ConfigBackups":        reloadImmediate,
+	"ReloadWait":           reloadImmediate,
+	"Schedule":             reloadLauncher,
+	"APIAddr":              reloadLauncher,
+	"APIToken":             reloadLauncher,
+	"LauncherConfigFile":   reloadLauncher,
+	"ModsFile":             reloadLauncher,
+	"ModsTrustStore":       reloadLauncher,
+	"ModsRequireSignature": reloadLauncher,
+	"NoSteam":              reloadLauncher,
+	"NoValidate":           reloadLauncher,
+	"AutoRestart":          reloadLauncher,
+	"MaxRestarts":          reloadLauncher,
+	"RestartDelay":         reloadLauncher,
+	"ShutdownTimeout":      reloadLauncher,
+	"KillTimeout":          reloadLauncher,
+	"CrashReports":         reloadLauncher,
+	"CrashCoreDump":        reloadLauncher,
+	"HangTimeout":          reloadLauncher,
+	"IdleRestart":          reloadLauncher,
+	"IdleRestartUptime":    reloadLauncher,
+	"IdleReset":            reloadLauncher,
+	"UpdateCheck":          reloadLauncher,
+	"UsageInterval":        reloadLauncher,
+	"UsageRules":           reloadLauncher,
+	"ShutdownWarnings":     reloadLauncher,
+	"ShutdownWaitWave":     reloadLauncher,
+	"ShutdownGrace":        reloadLauncher,
+	"Nice":                 reloadLauncher,
+	"CPUAffinity":          reloadLauncher,
+	"LimitNoFile":          reloadLauncher,
+	"LimitAS":              reloadLauncher,
+	"OOMScoreAdj":          reloadLauncher,
+	"RunAs":                reloadLauncher,
+	"EnvAllow":             reloadLauncher,
+	"NoNewPrivs":           reloadLauncher,
+	"Subreaper":            reloadLauncher,
+	"LogToFile":            reloadLauncher,
+	"LogLevel":             reloadLauncher,
+	"LogFile":              reloadLauncher,
+	"LogFileFormat":        reloadLauncher,
+	"LogMaxSize":           reloadLauncher,
+	"LogMaxBackups":        reloadLauncher,
+	"LogMaxAge":            reloadLauncher,
+	"SteamCMDRoot":         reloadLauncher,
+	"ServerInstallDir":     reloadLauncher,
+}
+
+// settingsChange is a setting whose value differs after a reload.
+type settingsChange struct {
+	field string
+	name  string
+	scope reloadScope
+}
+
+// diffSettings returns the settings that differ between prev and next.
+func diffSettings(prev *settings.Settings, next *settings.Settings) []settingsChange {
+	var changes []settingsChange
+
+	pv := reflect.ValueOf(prev).Elem()
+	nv := reflect.ValueOf(next).Elem()
+	for i := 0; i < pv.NumField(); i++ {
+		field := pv.Type().Field(i).Name
+		name := field
+
+		var prevValue, nextValue any
+		if pArg, ok := pv.Field(i).Interface().(arguments.ParsableArgument); ok && !pv.Field(i).IsNil() {
+			nArg := nv.Field(i).Interface().(arguments.ParsableArgument)
+			name = pArg.Name()
+			prevValue, nextValue = pArg.AnyValue(), nArg.AnyValue()
+		} else {
+			prevValue, nextValue = pv.Field(i).Interface(), nv.Field(i).Interface()
+		}
+
+		if !reflect.DeepEqual(prevValue, nextValue) {
+			changes = append(changes, settingsChange{field: field, name: name, scope: reloadScopes[field]})
+		}
+	}
+	return changes
+}
+
+// keepLauncherSettings copies the changed settings that need a launcher
+// restart from prev to next, so they keep their current value until then.
+func keepLauncherSettings(prev *settings.Settings, next *settings.Settings, changes []settingsChange) {
+	pv := reflect.ValueOf(prev).Elem()
+	nv := reflect.ValueOf(next).Elem()
+	for _, c := range changes {
+		if c.scope == reloadLauncher {
+			nv.FieldByName(c.field).Set(pv.FieldByName(c.field))
+		}
+	}
+}
+
+// reload reads the launcher settings again and applies the changes. The
+// changes written in the configuration files only are applied on a map
+// change, the others restart the server. Both happen once the server is
+// empty, or after the reload wait.
+func (l *Launcher) reload(ctx context.Context, server *kfserver.KFServer) {
+	if !l.reloading.CompareAndSwap(false, true) {
+		log.Logger.Warn("A configuration reload is already in progress, ignoring", l.logAttrs()...)
+		return
+	}
+	defer l.reloading.Store(false)
+
+	log.Logger.Info("Reloading the launcher configuration...", l.logAttrs()...)
+
+	next := &settings.Settings{}
+	reloadSettings := cmd.ReloadSettings
+	if l.name != "" {
+		reloadSettings = func(sett *settings.Settings) error { return cmd.ReloadInstanceSettings(l.name, sett) }
+	}
+	if err := reloadSettings(next); err != nil {
+		log.Logger.Error("Failed to reload the launcher configuration, keeping the current one", l.logAttrs("error", err)...)
+		return
+	}
+
+	// The Steam credentials are only read at startup
+	next.SteamLogin = l.settings.SteamLogin
+	next.SteamPassword = l.settings.SteamPassword
+
+	changes := diffSettings(l.settings, next)
+	needRestart, needMapChange := false, false
+	var ignored []string
+	for i, c := range changes {
+		// The instances sharing the server directory must keep the same values
+		if l.name != "" && slices.Contains(sharedSettings, c.field) {
+			changes[i].scope, c.scope = reloadLauncher, reloadLauncher
+		}
+		log.Logger.Debug("Setting changed",
+			"function", "reload", "setting", c.name, "scope", c.scope)
+
+		switch c.scope {
+		case reloadConfig:
+			needMapChange = true
+		case reloadCommandLine:
+			needRestart = true
+		case reloadLauncher:
+			ignored = append(ignored, c.name)
+		}
+	}
+	if len(ignored) > 0 {
+		log.Logger.Warn("Some settings can't be reloaded and will be applied on the next launcher start", l.logAttrs("settings", ignored)...)
+	}
+
+	keepLauncherSettings(l.settings, next, changes)
+
+	if !needRestart && !needMapChange {
+		l.setSettings(next)
+		log.Logger.Info("Launcher configuration reloaded, no server restart needed", l.logAttrs("changes", len(changes))...)
+		return
+	}
+
+	// Wait for the players to leave before restarting the server
+	if !l.waitEmptyServer(ctx, server, next.ReloadWait.Value()) {
+		return
+	}
+	serversMu.Lock()
+	defer serversMu.Unlock()
+
+	if !needRestart {
+		commands, ok, err := l.rewriteConfigFiles(next)
+		switch {
+		case err != nil:
+			log.Logger.Error("Failed to apply the reloaded configuration", l.logAttrs("error", err)...)
+			return
+		case ok:
+			server.Countdown(ctx, "changing map")
+			if err := server.ReloadMap(next, commands); err != nil {
+				log.Logger.Error("Failed to apply the reloaded configuration", l.logAttrs("error", err)...)
+				return
+			}
+			log.Logger.Info("Launcher configuration reloaded, map restarted", l.logAttrs("changes", len(changes))...)
+			return
+		}
+		log.Logger.Info("Some changes can't be applied on a map change, restarting the server", l.logAttrs()...)
+	}
+
+	// Warn the players still connected
+	server.Countdown(ctx, "restarting")
+
+	prev := l.settings
+	prepare := func() error {
+		l.setSettings(next)
+		if err := l.updateServerConfigFiles(); err != nil {
+			l.setSettings(prev)
+			return err
+		}
+		return nil
+	}
+
+	if err := server.Reload(next, prepare); err != nil {
+		log.Logger.Error("Failed to apply the reloaded configuration", l.logAttrs("error", err)...)
+		return
+	}
+	log.Logger.Info("Launcher configuration reloaded, server restarted", l.logAttrs("changes", len(changes))...)
+}
+
+// waitEmptyServer waits until no player is connected or maxWait is elapsed.
+// It returns false if ctx is cancelled meanwhile.
+func (l *Launcher) waitEmptyServer(ctx context.Context, server *kfserver.KFServer, maxWait time.Duration) bool {
+	deadline := time.Now().Add(maxWait)
+	ticker := time.NewTicker(reloadPollInterval)
+	defer ticker.Stop()
+
+	for {
+		players, err := server.PlayerCount()
+		switch {
+		case err != nil:
+			log.Logger.Debug("Unable to query the server player count",
+				"function", "waitEmptyServer", "error", err)
+		case players == 0:
+			return true
+		}
+
+		if !time.Now().Before(deadline) {
+			log.Logger.Info("Reload wait elapsed, restarting the server", l.logAttrs("players", players)...)
+			return true
+		}
+		log.Logger.Info(fmt.Sprintf("Waiting for the server to be empty before restarting (%s left)", time.Until(deadline).Round(time.Second)),
+			l.logAttrs("players", players)...)
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+	}
+}
+
+// rewriteConfigFiles writes the configuration files with next and returns the
+// console commands setting the changed keys on the running server, e.g.
+// "set KFmod.KFGameType StartingCash 500". It returns false, with the files
+// and settings left unchanged, when a change can't be applied this way: the
+// server must be restarted then.
+func (l *Launcher) rewriteConfigFiles(next *settings.Settings) ([]string, bool, error) {
+	systemDir := filepath.Join(l.settings.ServerInstallDir.Value(), "System")
+	files := []string{filepath.Join(systemDir, l.settings.ConfigFile.Value())}
+	if l.settings.EnableKFPatcher.Value() || next.EnableKFPatcher.Value() {
+		files = append(files, filepath.Join(systemDir, "KFPatcherSettings.ini"))
+	}
+
+	data := make([][]byte, len(files))
+	before := make([]*ini.GenericIniFile, len(files))
+	for i, file := range files {
+		var err error
+		// A missing file is written on the next start
+		if data[i], err = os.ReadFile(file); err != nil {
+			return nil, false, nil
+		}
+		before[i] = ini.NewGenericIniFile(filepath.Base(file))
+		if err := before[i].Load(file); err != nil {
+			return nil, false, err
+		}
+	}
+	restore := func() {
+		for i, file := range files {
+			if err := os.WriteFile(file, data[i], 0644); err != nil {
+				log.Logger.Warn("Failed to restore the configuration file", l.logAttrs("file", file, "error", err)...)
+			}
+		}
+	}
+
+	prev := l.settings
+	l.setSettings(next)
+	if err := l.updateServerConfigFiles(); err != nil {
+		l.setSettings(prev)
+		restore()
+		return nil, false, err
+	}
+
+	var commands []string
+	for i, file := range files {
+		cmds, ok := setCommands(before[i], file)
+		if !ok {
+			l.setSettings(prev)
+			restore()
+			return nil, false, nil
+		}
+		commands = append(commands, cmds...)
+	}
+	return commands, true, nil
+}
+
+// setCommands returns the set commands applying the changes of the ini file
+// since it was before. It returns false when a change isn't a single key of a
+// class section, e.g. a multi-value key, or is a password: the commands are
+// echoed in the server console.
+func setCommands(before *ini.GenericIniFile, file string) ([]string, bool) {
+	after := ini.NewGenericIniFile(filepath.Base(file))
+	if err := after.Load(file); err != nil {
+		return nil, false
+	}
+
+	var commands []string
+	for _, section := range after.Sections() {
+		seen := map[string]bool{}
+		for _, key := range section.Keys() {
+			name := strings.ToLower(key.Name)
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+
+			values := section.GetKeys(key.Name)
+			prevValues := before.GetKeys(section.Name(), key.Name)
+			if slices.Equal(values, prevValues) {
+				continue
+			}
+			if len(values) != 1 || len(prevValues) > 1 || strings.ContainsAny(key.Name, "[]") ||
+				!strings.Contains(section.Name(), ".") || strings.Contains(name, "password") {
+				return nil, false
+			}
+			commands = append(commands, fmt.Sprintf("set %s %s %s", section.Name(), key.Name, values[0]))
+		}
+	}
+
+	// Deleted keys can't be set back to their default
+	for _, section := range before.Sections() {
+		for _, key := range section.Keys() {
+			if !after.HasKey(section.Name(), key.Name) {
+				return nil, false
+			}
+		}
+	}
+	return commands, true
+}
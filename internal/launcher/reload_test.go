@@ -0,0 +1,135 @@
+package launcher
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/K4rian/kfdsl/internal/arguments"
+	"github.com/K4rian/kfdsl/internal/config/ini"
+	"github.com/K4rian/kfdsl/internal/log"
+	"github.com/K4rian/kfdsl/internal/settings"
+)
+
+func TestMain(m *testing.M) {
+	if err := log.Init("error", "", "text", 1, 1, 1, false); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+func TestDiffSettings(t *testing.T) {
+	type values struct {
+		serverName string
+		startupMap string
+		logLevel   string
+		extraArgs  []string
+	}
+	newSettings := func(v values) *settings.Settings {
+		sett := &settings.Settings{
+			ServerName: arguments.New("Server Name", v.serverName, nil, nil, false),
+			StartupMap: arguments.New("Startup Map", v.startupMap, nil, nil, false),
+			LogLevel:   arguments.New("Log Level", v.logLevel, nil, nil, false),
+			ExtraArgs:  v.extraArgs,
+		}
+		if err := sett.Parse(); err != nil {
+			t.Fatal(err)
+		}
+		return sett
+	}
+	base := values{"KF Server", "KF-Farm", "info", []string{"-log"}}
+
+	tests := []struct {
+		name string
+		next values
+		want []settingsChange
+	}{
+		{"no change", base, nil},
+		{
+			"config setting",
+			values{"Other Server", "KF-Farm", "info", []string{"-log"}},
+			[]settingsChange{{"ServerName", "Server Name", reloadConfig}},
+		},
+		{
+			"command-line setting",
+			values{"KF Server", "KF-Manor", "info", []string{"-log"}},
+			[]settingsChange{{"StartupMap", "Startup Map", reloadCommandLine}},
+		},
+		{
+			"launcher setting",
+			values{"KF Server", "KF-Farm", "debug", []string{"-log"}},
+			[]settingsChange{{"LogLevel", "Log Level", reloadLauncher}},
+		},
+		{
+			"plain field",
+			values{"KF Server", "KF-Farm", "info", nil},
+			[]settingsChange{{"ExtraArgs", "ExtraArgs", reloadCommandLine}},
+		},
+		{
+			"several settings",
+			values{"Other Server", "KF-Manor", "info", []string{"-log"}},
+			[]settingsChange{{"ServerName", "Server Name", reloadConfig}, {"StartupMap", "Startup Map", reloadCommandLine}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := diffSettings(newSettings(base), newSettings(tt.next)); !slices.Equal(got, tt.want) {
+				t.Errorf("diffSettings() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetCommands(t *testing.T) {
+	before := "[Engine.GameInfo]\nMaxPlayers=6\nServerActors=IpDrv.UdpBeacon\nServerActors=UWeb.WebServer\n" +
+		"[KFmod.KFMaplist]\nMaps[0]=KF-Farm\n[URL]\nPort=7707\n[Engine.AccessControl]\nAdminPassword=secret\n"
+
+	tests := []struct {
+		name  string
+		after string // Replaces the first occurrence of the given before line, as "old|new"
+		want  []string
+		ok    bool
+	}{
+		{"unchanged", "", nil, true},
+		{"changed key", "MaxPlayers=6|MaxPlayers=12", []string{"set Engine.GameInfo MaxPlayers 12"}, true},
+		{"added key", "MaxPlayers=6|MaxPlayers=6\nGameDifficulty=4.0", []string{"set Engine.GameInfo GameDifficulty 4.0"}, true},
+		{"list key", "ServerActors=UWeb.WebServer|ServerActors=UWeb.Other", nil, false},
+		{"indexed key", "Maps[0]=KF-Farm|Maps[0]=KF-Manor", nil, false},
+		{"section without a dot", "Port=7707|Port=7717", nil, false},
+		{"password", "AdminPassword=secret|AdminPassword=other", nil, false},
+		{"deleted key", "MaxPlayers=6\n|", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			beforeFile := filepath.Join(dir, "before.ini")
+			afterFile := filepath.Join(dir, "after.ini")
+			after := before
+			if old, replacement, found := strings.Cut(tt.after, "|"); found {
+				after = strings.Replace(before, old, replacement, 1)
+			}
+			for file, content := range map[string]string{beforeFile: before, afterFile: after} {
+				if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			prev := ini.NewGenericIniFile("before.ini")
+			if err := prev.Load(beforeFile); err != nil {
+				t.Fatal(err)
+			}
+
+			got, ok := setCommands(prev, afterFile)
+			if ok != tt.ok || !slices.Equal(got, tt.want) {
+				t.Errorf("setCommands() = %q, %v, want %q, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+
+	if _, ok := setCommands(ini.NewGenericIniFile("missing.ini"), filepath.Join(t.TempDir(), "missing.ini")); ok {
+		t.Error("setCommands() with a missing file: want false")
+	}
+}
@@ -0,0 +1,275 @@
+package launcher
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/K4rian/kfdsl/internal/cron"
+	"github.com/K4rian/kfdsl/internal/log"
+	"github.com/K4rian/kfdsl/internal/services/base"
+	"github.com/K4rian/kfdsl/internal/services/kfserver"
+)
+
+// scheduledServer is a server a scheduled task runs on.
+type scheduledServer struct {
+	inst   *Launcher
+	server *kfserver.KFServer
+}
+
+// runSchedule runs the tasks of the launcher schedule on the servers of the
+// instances until ctx is done. The tasks stopping the servers or changing the
+// map run one at a time.
+func (l *Launcher) runSchedule(ctx context.Context, instances []*Launcher, servers []*kfserver.KFServer) {
+	for _, task := range l.currentSettings().Schedule.Value() {
+		schedule, _ := cron.Parse(task.Cron) // Validated when parsing the settings
+
+		names := make([]string, 0, len(instances))
+		var targets []scheduledServer
+		for i, inst := range instances {
+			names = append(names, inst.name)
+			// The instances share the server files, they're all updated
+			if servers[i] != nil && (task.Type == cron.TaskUpdate || task.RunsOn(inst.name)) {
+				targets = append(targets, scheduledServer{inst: inst, server: servers[i]})
+			}
+		}
+		for _, name := range task.Instances {
+			if !slices.Contains(names, name) {
+				log.Logger.Warn("Unknown instance in the scheduled task", "task", task.Name, "instance", name)
+			}
+		}
+		if len(targets) == 0 {
+			log.Logger.Warn("No server to run the scheduled task on", "task", task.Name)
+			continue
+		}
+
+		next := schedule.Next(time.Now())
+		if next.IsZero() {
+			log.Logger.Warn("The scheduled task never runs", "task", task.Name, "cron", task.Cron)
+			continue
+		}
+		log.Logger.Info("Task scheduled", "task", task.Name, "type", task.Type, "next", next.Format(time.DateTime))
+
+		go func() {
+			for ; !next.IsZero(); next = schedule.Next(time.Now()) {
+				timer := time.NewTimer(time.Until(next))
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return
+				case <-timer.C:
+				}
+
+				if task.Disruptive() {
+					serversMu.Lock()
+				}
+				l.runTask(ctx, task, targets)
+				if task.Disruptive() {
+					serversMu.Unlock()
+				}
+			}
+		}()
+	}
+}
+
+// runTask runs task on the running targets, once its conditions are met.
+func (l *Launcher) runTask(ctx context.Context, task cron.Task, targets []scheduledServer) {
+	log.Logger.Info("Running the scheduled task", "task", task.Name, "type", task.Type)
+
+	var running []scheduledServer
+	for _, t := range targets {
+		if t.server.IsRunning() {
+			running = append(running, t)
+		}
+	}
+
+	if task.Type == cron.TaskUpdate {
+		if l.currentSettings().NoSteam.Value() {
+			log.Logger.Warn("SteamCMD is disabled, skipping the scheduled update", "task", task.Name)
+			return
+		}
+		// The servers are updated anyway when the check fails
+		available, err := l.updateAvailable(ctx)
+		if err != nil {
+			log.Logger.Warn("Unable to check for a server update", "task", task.Name, "error", err)
+		} else if !available {
+			log.Logger.Info("The server is up-to-date, skipping the scheduled update", "task", task.Name)
+			return
+		}
+		// The update stops all the servers, they must all be ready for it
+		for _, t := range running {
+			if !t.inst.taskReady(ctx, task, t.server) {
+				return
+			}
+		}
+		if err := l.updateServers(ctx, running); err != nil {
+			log.Logger.Error("Scheduled task failed", "task", task.Name, "error", err)
+		}
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, t := range running {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if !t.inst.taskReady(ctx, task, t.server) {
+				return
+			}
+			if err := t.inst.runServerTask(ctx, task, t.server); err != nil {
+				log.Logger.Error("Scheduled task failed", t.inst.logAttrs("task", task.Name, "error", err)...)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// runServerTask runs the task on server, other than an update.
+func (l *Launcher) runServerTask(ctx context.Context, task cron.Task, server *kfserver.KFServer) error {
+	switch task.Type {
+	case cron.TaskRestart:
+		server.Countdown(ctx, "restarting")
+		return server.RestartFor(base.RestartScheduled)
+	case cron.TaskMods:
+		server.Countdown(ctx, "restarting")
+		return server.RestartWith(base.RestartScheduled, func() error { return l.withSettings(l.installMods) })
+	case cron.TaskSay:
+		return server.SendCommand("say " + task.Message)
+	case cron.TaskMap:
+		mapName := task.Map
+		if mapName == "" {
+			maps, err := l.mapRotation()
+			if err != nil {
+				return err
+			}
+			if mapName = nextMap(maps, server.CurrentMap()); mapName == "" {
+				return fmt.Errorf("no map to change to")
+			}
+		}
+		return server.ChangeMap(mapName)
+	}
+	return fmt.Errorf("unsupported task type '%s'", task.Type)
+}
+
+// taskReady waits up to the task defer_until_empty for the server to be
+// empty. It returns false when the task is skipped: players are still
+// connected and the task runs only if empty, or ctx is done.
+func (l *Launcher) taskReady(ctx context.Context, task cron.Task, server *kfserver.KFServer) bool {
+	deadline := time.Now().Add(task.DeferUntilEmpty)
+	ticker := time.NewTicker(reloadPollInterval)
+	defer ticker.Stop()
+
+	for {
+		// A server that can't be queried is considered in use
+		players, err := server.PlayerCount()
+		if err == nil && players == 0 {
+			return true
+		}
+		if err != nil {
+			log.Logger.Debug("Unable to query the server player count",
+				"function", "taskReady", "error", err)
+		}
+
+		if !time.Now().Before(deadline) {
+			if task.OnlyIfEmpty {
+				log.Logger.Info("Players connected, skipping the scheduled task", l.logAttrs("task", task.Name, "players", players)...)
+				return false
+			}
+			return true
+		}
+		log.Logger.Info(fmt.Sprintf("Waiting for the server to be empty before running the scheduled task (%s left)", time.Until(deadline).Round(time.Second)),
+			l.logAttrs("task", task.Name, "players", players)...)
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+	}
+}
+
+// updateServers warns the players and stops the servers, updates the server
+// files with SteamCMD and installs the mods again, then starts the servers.
+func (l *Launcher) updateServers(ctx context.Context, targets []scheduledServer) error {
+	var wg sync.WaitGroup
+	for _, t := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			t.server.Countdown(ctx, "updating")
+			if err := t.server.Stop(); err != nil {
+				log.Logger.Error("Failed to stop the KF Dedicated Server", t.inst.logAttrs("error", err)...)
+			}
+		}()
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	log.Logger.Info("Updating the KF Dedicated Server...")
+	var updatedLibs []string
+	var libsErr error
+	updateErr := l.withSettings(func() error {
+		if err := l.startSteamCMD(ctx); err != nil {
+			return err
+		}
+		updatedLibs, libsErr = l.updateGameServerSteamLibs()
+		return nil
+	})
+	if updateErr != nil {
+		log.Logger.Error("SteamCMD raised an error, starting the servers anyway", "error", updateErr)
+	} else if libsErr != nil {
+		log.Logger.Error("Unable to update the KF Dedicated Server Steam libraries", "error", libsErr)
+	} else {
+		for _, lib := range updatedLibs {
+			log.Logger.Info("Steam library successfully updated", "library", lib)
+		}
+	}
+
+	for _, t := range targets {
+		if err := t.inst.withSettings(t.inst.installMods); err != nil {
+			log.Logger.Error("Failed to install mods", t.inst.logAttrs("file", t.inst.currentSettings().ModsFile.Value(), "error", err)...)
+		}
+		if err := t.server.Start(); err != nil {
+			log.Logger.Error("Failed to start the KF Dedicated Server", t.inst.logAttrs("error", err)...)
+		}
+	}
+	return updateErr
+}
+
+// mapRotation returns the maps of the map list, or the installed maps of the
+// game mode when the map list is empty or "all".
+func (l *Launcher) mapRotation() ([]string, error) {
+	sett := l.currentSettings()
+	maps := strings.FieldsFunc(sett.Maplist.Value(), func(r rune) bool { return r == ',' })
+	if len(maps) > 0 && maps[0] != "all" {
+		return maps, nil
+	}
+
+	gameMode := sett.GameMode.RawValue()
+	mapsDir := filepath.Join(sett.ServerInstallDir.Value(), "Maps")
+	maps, err := kfserver.GetInstalledMaps(mapsDir, kfserver.GetGameModeMapPrefix(gameMode))
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch available maps for game mode '%s': %w", gameMode, err)
+	}
+	return maps, nil
+}
+
+// nextMap returns the map following current in maps, the first map when
+// current isn't in maps.
+func nextMap(maps []string, current string) string {
+	if len(maps) == 0 {
+		return ""
+	}
+	for i, m := range maps {
+		if strings.EqualFold(m, current) {
+			return maps[(i+1)%len(maps)]
+		}
+	}
+	return maps[0]
+}
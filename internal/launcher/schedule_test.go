@@ -0,0 +1,30 @@
+package launcher
+
+import "testing"
+
+func TestNextMap(t *testing.T) {
+	maps := []string{"KF-BioticsLab", "KF-Farm", "KF-Manor"}
+
+	tests := []struct {
+		name    string
+		maps    []string
+		current string
+		want    string
+	}{
+		{"next", maps, "KF-BioticsLab", "KF-Farm"},
+		{"wraps around", maps, "KF-Manor", "KF-BioticsLab"},
+		{"case insensitive", maps, "kf-farm", "KF-Manor"},
+		{"unknown map", maps, "KF-Offices", "KF-BioticsLab"},
+		{"no current map", maps, "", "KF-BioticsLab"},
+		{"single map", []string{"KF-Farm"}, "KF-Farm", "KF-Farm"},
+		{"no maps", nil, "KF-Farm", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextMap(tt.maps, tt.current); got != tt.want {
+				t.Errorf("nextMap(%v, %q) = %q, want %q", tt.maps, tt.current, got, tt.want)
+			}
+		})
+	}
+}
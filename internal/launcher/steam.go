@@ -16,6 +16,11 @@ import (
 )
 
 func (l *Launcher) startSteamCMD(ctx context.Context) error {
+	// SteamCMD runs on its own context, cancelled once it's done so its
+	// cancellation monitor doesn't outlive it
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
 	rootDir := l.settings.SteamCMDRoot.Value()
 	steamCMD := steamcmd.New(ctx, rootDir)
 
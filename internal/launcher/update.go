@@ -0,0 +1,108 @@
+package launcher
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/K4rian/kfdsl/internal/log"
+	"github.com/K4rian/kfdsl/internal/services/kfserver"
+	"github.com/K4rian/kfdsl/internal/services/steamcmd"
+)
+
+const (
+	steamBranch  = "public"
+	updateNotice = "A server update is available, the server will restart to apply it"
+)
+
+// watchUpdates checks for a new build of the server on Steam at each update
+// check interval, and updates the servers when one is found. It returns when
+// ctx is done.
+func (l *Launcher) watchUpdates(ctx context.Context, instances []*Launcher, servers []*kfserver.KFServer) {
+	sett := l.currentSettings()
+	interval := sett.UpdateCheck.Value()
+	if interval == 0 {
+		return
+	}
+	if sett.NoSteam.Value() {
+		log.Logger.Warn("SteamCMD is disabled, the server updates won't be checked")
+		return
+	}
+
+	var targets []scheduledServer
+	for i, inst := range instances {
+		if servers[i] != nil {
+			targets = append(targets, scheduledServer{inst: inst, server: servers[i]})
+		}
+	}
+
+	log.Logger.Info("Checking for server updates periodically", "interval", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// SteamCMD isn't run while a scheduled update is running
+		serversMu.Lock()
+		available, err := l.updateAvailable(ctx)
+		if err != nil {
+			log.Logger.Error("Unable to check for a server update", "error", err)
+		}
+		if !available {
+			serversMu.Unlock()
+			continue
+		}
+
+		var running []scheduledServer
+		for _, t := range targets {
+			if t.server.IsRunning() {
+				running = append(running, t)
+			}
+		}
+		for _, t := range running {
+			if err := t.server.SendCommand("say " + updateNotice); err != nil {
+				log.Logger.Warn("Failed to notify the players of the update", t.inst.logAttrs("error", err)...)
+			}
+		}
+		if err := l.updateServers(ctx, running); err != nil {
+			log.Logger.Error("Failed to update the KF Dedicated Server", "error", err)
+		}
+		serversMu.Unlock()
+	}
+}
+
+// updateAvailable compares the installed build of the server with the latest
+// one on Steam.
+func (l *Launcher) updateAvailable(ctx context.Context) (bool, error) {
+	sett := l.currentSettings()
+	installed, err := steamcmd.InstalledBuildID(sett.ServerInstallDir.Value(), KF_APPID)
+	if err != nil {
+		return false, err
+	}
+
+	// Cancelled once done, so the cancellation monitor of each check doesn't
+	// outlive it
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	steamCMD := steamcmd.New(ctx, sett.SteamCMDRoot.Value())
+	if !steamCMD.IsInstalled() {
+		return false, fmt.Errorf("SteamCMD not found in %s", steamCMD.Options().RootDirectory)
+	}
+	latest, err := steamCMD.AppBuildID(KF_APPID, steamBranch)
+	if err != nil {
+		return false, err
+	}
+
+	if latest == installed {
+		log.Logger.Debug("The server is up-to-date",
+			"function", "updateAvailable", "buildID", installed)
+		return false, nil
+	}
+	log.Logger.Info("A server update is available", "installedBuildID", installed, "latestBuildID", latest)
+	return true, nil
+}
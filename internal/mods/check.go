@@ -0,0 +1,122 @@
+package mods
+
+import (
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/K4rian/kfdsl/internal/utils"
+)
+
+// Problem is an issue found by Check.
+type Problem struct {
+	Mod     string
+	Message string
+	Fatal   bool // The mods can't be installed
+}
+
+// checksumLengths maps the supported checksum types to their hex length.
+var checksumLengths = map[string]int{
+	"md5":    32,
+	"sha1":   40,
+	"sha256": 64,
+	"sha512": 128,
+}
+
+// checkChecksum validates a checksum_type:checksum_string value.
+func checkChecksum(checksum string) error {
+	checksumType, sum, ok := strings.Cut(checksum, ":")
+	if !ok {
+		return fmt.Errorf("invalid checksum format: %s", checksum)
+	}
+	length, known := checksumLengths[checksumType]
+	if !known {
+		return fmt.Errorf("unknown checksum type %s", checksumType)
+	}
+	if _, err := hex.DecodeString(sum); err != nil || len(sum) != length {
+		return fmt.Errorf("invalid %s checksum: %s", checksumType, sum)
+	}
+	return nil
+}
+
+// Check validates the definitions of modList. When they are valid, the
+// installed files of the mods to install are compared with their checksum.
+func Check(dir string, modList map[string]*Mod) []Problem {
+	var problems []Problem
+	addProblem := func(name string, fatal bool, format string, args ...any) {
+		problems = append(problems, Problem{Mod: name, Message: fmt.Sprintf(format, args...), Fatal: fatal})
+	}
+
+	names := make([]string, 0, len(modList))
+	for name := range modList {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	defined := make(map[string]*Mod, len(modList))
+	for _, name := range names {
+		mod := modList[name]
+		if mod == nil {
+			addProblem(name, true, "empty definition")
+			continue
+		}
+		defined[name] = mod
+
+		if mod.DownloadURL == "" {
+			addProblem(name, true, "download_url is undefined")
+		}
+		if len(mod.InstallItems) > 1 && !mod.Extract {
+			addProblem(name, true, "mod contains multiple files but is not marked for extraction")
+		}
+		if mod.Checksum != "" {
+			if err := checkChecksum(mod.Checksum); err != nil {
+				addProblem(name, true, "%v", err)
+			}
+		}
+		for _, item := range mod.InstallItems {
+			if item.Checksum == "" {
+				continue
+			}
+			if err := checkChecksum(item.Checksum); err != nil {
+				addProblem(name, true, "%s: %v", item.Name, err)
+			}
+		}
+		for _, dep := range mod.DependOn {
+			if modList[dep] == nil {
+				addProblem(name, true, "unknown dependency %s", dep)
+			}
+		}
+	}
+
+	// The definitions must be valid to resolve the mods to install
+	if len(problems) > 0 {
+		return problems
+	}
+
+	// Compare the installed files, a mismatch triggers a new download
+	toInstall := resolveModsToInstall(defined)
+	sort.Strings(toInstall)
+	for _, name := range toInstall {
+		mod := defined[name]
+		for _, item := range mod.InstallItems {
+			if item.Checksum == "" || checkChecksum(item.Checksum) != nil {
+				continue
+			}
+
+			itemPath := filepath.Join(dir, item.Path, item.Name)
+			if !utils.FileExists(itemPath) {
+				addProblem(name, false, "%s is not installed", filepath.Join(item.Path, item.Name))
+				continue
+			}
+			match, err := utils.FileMatchesChecksum(itemPath, item.Checksum)
+			if err != nil {
+				addProblem(name, false, "%s: %v", filepath.Join(item.Path, item.Name), err)
+			} else if !match {
+				addProblem(name, false, "%s doesn't match its checksum", filepath.Join(item.Path, item.Name))
+			}
+		}
+	}
+	return problems
+}
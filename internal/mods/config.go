@@ -0,0 +1,126 @@
+package mods
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"text/template"
+
+	"github.com/K4rian/kfdsl/internal/arguments"
+	"github.com/K4rian/kfdsl/internal/settings"
+)
+
+// envPrefix is the prefix of the environment variables readable by the
+// templates, so the launcher secrets such as STEAMACC_PASSWORD can't be.
+const envPrefix = "KF_MOD_"
+
+// templateSettings are the launcher settings readable by the templates. The
+// passwords, admin mail and Steam account aren't.
+var templateSettings = []string{
+	"ServerName", "ShortName", "IP", "GamePort", "WebAdminPort", "GameSpyPort",
+	"GameMode", "StartupMap", "GameDifficulty", "GameLength", "FriendlyFire",
+	"MaxPlayers", "MaxSpectators", "Region", "AdminName", "MOTD", "SpecimenType",
+	"Mutators", "ServerMutators", "RedirectURL", "Maplist", "EnableWebAdmin",
+	"EnableMapVote", "ConfigFile", "ServerInstallDir",
+}
+
+type ConfigKey struct {
+	Section string `json:"section"`
+	Key     string `json:"key"`
+	Value   string `json:"value"`
+}
+
+type ConfigFile struct {
+	File string      `json:"file"`
+	Keys []ConfigKey `json:"keys"`
+}
+
+// ConfigTemplateData is the data made available to the mod config value templates.
+type ConfigTemplateData struct {
+	Name     string
+	Mod      *Mod
+	Settings map[string]arguments.ParsableArgument // The templateSettings, by field name
+}
+
+// NewConfigTemplateData returns the template data of the mod name, with the
+// templateSettings of sett.
+func NewConfigTemplateData(name string, mod *Mod, sett *settings.Settings) ConfigTemplateData {
+	val := reflect.ValueOf(sett).Elem()
+	view := make(map[string]arguments.ParsableArgument, len(templateSettings))
+	for _, key := range templateSettings {
+		field := val.FieldByName(key)
+		if !field.IsValid() || field.Kind() != reflect.Ptr || field.IsNil() {
+			continue
+		}
+		if arg, ok := field.Interface().(arguments.ParsableArgument); ok && !arg.IsSensitive() {
+			view[key] = arg
+		}
+	}
+	return ConfigTemplateData{Name: name, Mod: mod, Settings: view}
+}
+
+// RenderedConfigKey is a mod config key with its template already executed.
+type RenderedConfigKey struct {
+	Section string
+	Key     string
+	Value   string
+}
+
+var configTemplateFuncs = template.FuncMap{
+	"env": func(name string) (string, error) {
+		if !strings.HasPrefix(name, envPrefix) {
+			return "", fmt.Errorf("environment variable %s not readable, only the %s* ones are", name, envPrefix)
+		}
+		return os.Getenv(name), nil
+	},
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	"default": func(def string, val any) string {
+		if s := fmt.Sprintf("%v", val); val != nil && s != "" {
+			return s
+		}
+		return def
+	},
+}
+
+// FilePath returns the absolute path of the config file inside the server directory.
+// Paths escaping the server directory are rejected.
+func (c *ConfigFile) FilePath(dir string) (string, error) {
+	if strings.TrimSpace(c.File) == "" {
+		return "", fmt.Errorf("config file path is empty")
+	}
+
+	path := filepath.Join(dir, c.File)
+	if !strings.HasPrefix(path, filepath.Clean(dir)+string(os.PathSeparator)) {
+		return "", fmt.Errorf("illegal config file path: %s", c.File)
+	}
+	return path, nil
+}
+
+// Render executes the value template of every key.
+func (c *ConfigFile) Render(data ConfigTemplateData) ([]RenderedConfigKey, error) {
+	ret := make([]RenderedConfigKey, 0, len(c.Keys))
+	for _, k := range c.Keys {
+		if k.Section == "" || k.Key == "" {
+			return nil, fmt.Errorf("%s: section and key are required", c.File)
+		}
+
+		tmpl, err := template.New(k.Section + "." + k.Key).
+			Funcs(configTemplateFuncs).
+			Option("missingkey=error").
+			Parse(k.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid template for [%s] %s: %w", c.File, k.Section, k.Key, err)
+		}
+
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("%s: failed to render [%s] %s: %w", c.File, k.Section, k.Key, err)
+		}
+		ret = append(ret, RenderedConfigKey{Section: k.Section, Key: k.Key, Value: buf.String()})
+	}
+	return ret, nil
+}
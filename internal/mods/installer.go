@@ -36,12 +36,15 @@ type Mod struct {
 	Extract      bool          `json:"extract"`
 	InstallItems []InstallItem `json:"install"`
 	DependOn     []string      `json:"depend_on"`
+	Config       []ConfigFile  `json:"config,omitempty"`
+	Signature    *Signature    `json:"signature,omitempty"`
 	Enabled      bool          `json:"enabled,omitempty"`
 }
 
 type installResult struct {
-	name string
-	err  error
+	name  string
+	files []stagedFile
+	err   error
 }
 
 func (m *Mod) isDownloadRequired(dir string) bool {
@@ -80,73 +83,110 @@ func (m *Mod) download(dir, name string) (string, error) {
 	return filename, nil
 }
 
-func (m *Mod) installFile(dir, filename string, item InstallItem) error {
-	log.Logger.Debug("Installing mod file", "name", item.Name, "dir", dir, "path", item.Path, "from", filename)
-	path, err := utils.CreateDirIfNotExists(dir, item.Path)
+func (m *Mod) stageFile(dir, stageDir, filename string, item InstallItem) (*stagedFile, error) {
+	dst := filepath.Join(dir, item.Path, item.Name)
+
+	// Existing files matching their checksum (if any) are left untouched
+	exists, err := utils.FileExistsAndMatchesChecksum(dst, item.Checksum)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("failed to check destination file for existance and checksum: %w", err)
+	}
+	if exists {
+		log.Logger.Debug("Mod file is up-to-date, skipping", "name", item.Name, "path", dst)
+		return nil, nil
+	}
+
+	log.Logger.Debug("Staging mod file", "name", item.Name, "dir", stageDir, "path", item.Path, "from", filename)
+	path, err := utils.CreateDirIfNotExists(stageDir, item.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	src := filepath.Join(path, item.Name)
+	if err := utils.MoveFile(filename, src, ""); err != nil {
+		return nil, err
+	}
+	if item.Checksum != "" {
+		if match, err := utils.FileMatchesChecksum(src, item.Checksum); err != nil || !match {
+			return nil, fmt.Errorf("file %s does not match checksum %s", item.Name, item.Checksum)
+		}
 	}
-	return utils.MoveFile(filename, filepath.Join(path, item.Name), item.Checksum)
+	return &stagedFile{src: src, dst: dst, checksum: item.Checksum}, nil
 }
 
-func (m *Mod) installFiles(dir, filename string) error {
+func (m *Mod) stageFiles(dir, stageDir, filename string) ([]stagedFile, error) {
 	if len(m.InstallItems) > 1 && !m.Extract {
-		return fmt.Errorf("mod contains multiple files but is not marked for extraction")
+		return nil, fmt.Errorf("mod contains multiple files but is not marked for extraction")
 	}
 
-	log.Logger.Debug("Installing mod files")
+	log.Logger.Debug("Staging mod files")
 	if len(m.InstallItems) == 1 {
-		return m.installFile(dir, filename, m.InstallItems[0])
+		f, err := m.stageFile(dir, stageDir, filename, m.InstallItems[0])
+		if err != nil || f == nil {
+			return nil, err
+		}
+		return []stagedFile{*f}, nil
 	}
-	return m.installArchive(dir, filename)
+	return m.stageArchive(dir, stageDir, filename)
 }
 
-func (m *Mod) installArchive(dir, archive string) error {
-	// Unpack item in temporary directory then move them one by one
+func (m *Mod) stageArchive(dir, stageDir, archive string) ([]stagedFile, error) {
+	// Unpack item in temporary directory then stage them one by one
 	tempDir, err := os.MkdirTemp("", "*")
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer os.RemoveAll(tempDir)
 
 	log.Logger.Debug("Extracting mod archive", "archive", archive, "to", tempDir)
 	if err := utils.UnzipFile(archive, tempDir); err != nil {
-		return err
+		return nil, err
 	}
 
+	var files []stagedFile
 	for _, item := range m.InstallItems {
-		if err := m.installFile(dir, filepath.Join(tempDir, item.Name), item); err != nil {
-			return err
+		f, err := m.stageFile(dir, stageDir, filepath.Join(tempDir, item.Name), item)
+		if err != nil {
+			return nil, err
+		}
+		if f != nil {
+			files = append(files, *f)
 		}
 	}
-	return nil
+	return files, nil
 }
 
-func (m *Mod) install(dir string, name string) error {
+// stage downloads the mod and prepares its files in stageDir,
+// without touching the server directory.
+func (m *Mod) stage(dir, stageDir, name string) ([]stagedFile, error) {
 	if !m.Enabled {
 		log.Logger.Debug("Skipping installation of mod, it is disabled", "name", name)
-		return nil
+		return nil, nil
 	}
 
 	if !m.isDownloadRequired(dir) {
 		log.Logger.Debug("Skipping installation of mod, it is already installed", "name", name)
-		return nil
+		return nil, nil
 	}
 
 	log.Logger.Debug("Installing mod", "name", name)
 
 	filename, err := m.download(dir, name)
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	if filename != "" {
-		if err := m.installFiles(dir, filename); err != nil {
-			return err
-		}
+	if filename == "" {
+		return nil, nil
 	}
 
-	return nil
+	files, err := m.stageFiles(dir, filepath.Join(stageDir, name), filename)
+	if err != nil {
+		return nil, err
+	}
+	for i := range files {
+		files[i].mod = name
+	}
+	return files, nil
 }
 
 func (m *Mod) resolveDependencies(allMods map[string]*Mod, visited map[string]bool) []string {
@@ -187,6 +227,40 @@ func resolveModsToInstall(allMods map[string]*Mod) []string {
 	return utils.RemoveDuplicates(m)
 }
 
+// ModFile is a file installed by a mod.
+type ModFile struct {
+	Mod         string // Name of the mod
+	Version     string
+	DownloadURL string
+	Checksum    string // Checksum of the file, if any
+}
+
+// Same reports whether f and o install the same file.
+func (f ModFile) Same(o ModFile) bool {
+	return f.Version == o.Version && f.DownloadURL == o.DownloadURL && f.Checksum == o.Checksum
+}
+
+// InstallFiles returns the files installed by the enabled mods of modList
+// and their dependencies, by path relative to the server directory.
+func InstallFiles(modList map[string]*Mod) map[string]ModFile {
+	files := map[string]ModFile{}
+	for _, name := range resolveModsToInstall(modList) {
+		mod := modList[name]
+		if mod == nil {
+			continue
+		}
+		for _, item := range mod.InstallItems {
+			files[filepath.Join(item.Path, item.Name)] = ModFile{
+				Mod:         name,
+				Version:     mod.Version,
+				DownloadURL: mod.DownloadURL,
+				Checksum:    item.Checksum,
+			}
+		}
+	}
+	return files
+}
+
 // buildInstallWaves groups mods into waves where each wave's mods
 // have all their dependencies satisfied by previous waves.
 func buildInstallWaves(modList map[string]*Mod, toInstall []string) [][]string {
@@ -228,15 +302,35 @@ func buildInstallWaves(modList map[string]*Mod, toInstall []string) [][]string {
 	return waves
 }
 
+// InstallMods installs the enabled mods and their dependencies in dir.
+// Every wave is staged first, then the files are swapped in place at once.
+// If anything fails, the replaced files are restored and nothing is installed.
 func InstallMods(dir string, modList map[string]*Mod, installed *[]string) error {
 	toInstall := resolveModsToInstall(modList)
 	log.Logger.Debug("Mods to install", "mods", strings.Join(toInstall, " / "))
 
+	lock, err := ReadLock(dir)
+	if err != nil {
+		return err
+	}
+
+	stateDir, err := utils.CreateDirIfNotExists(StateDir(dir))
+	if err != nil {
+		return err
+	}
+	stageDir, err := os.MkdirTemp(stateDir, "staging-*")
+	if err != nil {
+		return fmt.Errorf("failed to create the staging directory: %w", err)
+	}
+	defer os.RemoveAll(stageDir)
+
 	waves := buildInstallWaves(modList, toInstall)
 	var allErrs []error
+	var staged []stagedFile
+	var names []string
 
 	for i, wave := range waves {
-		log.Logger.Debug("Installing mod wave", "wave", i+1, "mods", strings.Join(wave, " / "))
+		log.Logger.Debug("Staging mod wave", "wave", i+1, "mods", strings.Join(wave, " / "))
 
 		results := make(chan installResult, len(wave))
 		var wg sync.WaitGroup
@@ -246,7 +340,8 @@ func InstallMods(dir string, modList map[string]*Mod, installed *[]string) error
 			wg.Add(1)
 			go func(name string, mod *Mod) {
 				defer wg.Done()
-				results <- installResult{name, mod.install(dir, name)}
+				files, err := mod.stage(dir, stageDir, name)
+				results <- installResult{name, files, err}
 			}(name, mod)
 		}
 
@@ -258,7 +353,8 @@ func InstallMods(dir string, modList map[string]*Mod, installed *[]string) error
 				log.Logger.Error("Failed to install mod", "name", r.name, "error", r.err)
 				allErrs = append(allErrs, fmt.Errorf("%s: %w", r.name, r.err))
 			} else {
-				*installed = append(*installed, r.name)
+				staged = append(staged, r.files...)
+				names = append(names, r.name)
 			}
 		}
 	}
@@ -266,6 +362,50 @@ func InstallMods(dir string, modList map[string]*Mod, installed *[]string) error
 	if len(allErrs) > 0 {
 		return errors.Join(allErrs...)
 	}
+
+	if len(staged) > 0 {
+		generation := lock.Generation + 1
+		genDir, err := utils.CreateDirIfNotExists(generationDir(dir, generation))
+		if err != nil {
+			return err
+		}
+
+		tx := newTransaction(dir, genDir)
+		if err := tx.snapshot(staged); err != nil {
+			os.RemoveAll(genDir)
+			return fmt.Errorf("failed to snapshot the mod files: %w", err)
+		}
+
+		// The files are restored until the lock of the new generation is
+		// written, so the lock always matches the files on disk
+		abort := func(err error) error {
+			log.Logger.Error("Failed to install mod files, rolling back", "error", err)
+			if rbErr := tx.rollback(); rbErr != nil {
+				return errors.Join(err, fmt.Errorf("rollback failed: %w", rbErr))
+			}
+			os.RemoveAll(genDir)
+			return err
+		}
+
+		log.Logger.Debug("Swapping staged mod files", "generation", generation, "files", len(staged))
+		if err := tx.commit(staged); err != nil {
+			return abort(err)
+		}
+
+		genLock, err := newLock(dir, generation, modList, names)
+		if err != nil {
+			return abort(fmt.Errorf("failed to build the mods lock: %w", err))
+		}
+		if err := writeJSONFile(filepath.Join(genDir, lockFileName), genLock); err != nil {
+			return abort(fmt.Errorf("failed to write the mods lock: %w", err))
+		}
+		if err := writeLock(dir, genLock); err != nil {
+			return abort(fmt.Errorf("failed to write the mods lock: %w", err))
+		}
+		pruneGenerations(dir)
+	}
+
+	*installed = append(*installed, names...)
 	return nil
 }
 
@@ -0,0 +1,172 @@
+package mods
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/K4rian/kfdsl/internal/log"
+	"github.com/K4rian/kfdsl/internal/utils"
+)
+
+const (
+	lockFileName       = "mods.lock"
+	generationsDirName = "generations"
+	maxLockGenerations = 5
+)
+
+type LockedFile struct {
+	Path     string `json:"path"`
+	Checksum string `json:"checksum,omitempty"`
+}
+
+type LockedMod struct {
+	Version string       `json:"version"`
+	Files   []LockedFile `json:"files"`
+}
+
+// Lock records the mods installed by a given install generation.
+type Lock struct {
+	Generation int                  `json:"generation"`
+	Date       time.Time            `json:"date"`
+	Mods       map[string]LockedMod `json:"mods"`
+}
+
+// StateDir returns the directory holding the mods lock and generations.
+func StateDir(dir string) string {
+	return filepath.Join(dir, ".kfdsl", "mods")
+}
+
+func generationDir(dir string, generation int) string {
+	return filepath.Join(StateDir(dir), generationsDirName, strconv.Itoa(generation))
+}
+
+// ReadLock returns the current mods lock of the server directory.
+// An empty lock (generation 0) is returned if no mods were installed yet.
+func ReadLock(dir string) (*Lock, error) {
+	lock := &Lock{Mods: map[string]LockedMod{}}
+	err := readJSONFile(filepath.Join(StateDir(dir), lockFileName), lock)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read the mods lock: %w", err)
+	}
+	return lock, nil
+}
+
+func writeLock(dir string, lock *Lock) error {
+	if _, err := utils.CreateDirIfNotExists(StateDir(dir)); err != nil {
+		return err
+	}
+	return writeJSONFile(filepath.Join(StateDir(dir), lockFileName), lock)
+}
+
+// newLock builds the lock of the mods currently present on disk.
+func newLock(dir string, generation int, modList map[string]*Mod, installed []string) (*Lock, error) {
+	lock := &Lock{
+		Generation: generation,
+		Date:       time.Now().UTC(),
+		Mods:       make(map[string]LockedMod, len(installed)),
+	}
+	for _, name := range installed {
+		mod := modList[name]
+		lm := LockedMod{Version: mod.Version}
+		for _, item := range mod.InstallItems {
+			path := filepath.Join(item.Path, item.Name)
+			checksum, err := lockedChecksum(filepath.Join(dir, path))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			lm.Files = append(lm.Files, LockedFile{Path: path, Checksum: checksum})
+		}
+		lock.Mods[name] = lm
+	}
+	return lock, nil
+}
+
+// lockedChecksum returns the sha256 checksum of an installed mod file.
+func lockedChecksum(filename string) (string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	sum, err := utils.FileChecksum(file, "sha256")
+	if err != nil {
+		return "", fmt.Errorf("failed to hash %s: %w", filename, err)
+	}
+	return "sha256:" + sum, nil
+}
+
+// pruneGenerations removes the oldest generations beyond maxLockGenerations.
+func pruneGenerations(dir string) {
+	root := filepath.Join(StateDir(dir), generationsDirName)
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return
+	}
+
+	var generations []int
+	for _, e := range entries {
+		if n, err := strconv.Atoi(e.Name()); err == nil && e.IsDir() {
+			generations = append(generations, n)
+		}
+	}
+	sort.Ints(generations)
+
+	for len(generations) > maxLockGenerations {
+		path := filepath.Join(root, strconv.Itoa(generations[0]))
+		if err := os.RemoveAll(path); err != nil {
+			log.Logger.Warn("Failed to prune mods generation", "generation", generations[0], "error", err)
+		} else {
+			log.Logger.Debug("Pruned mods generation", "generation", generations[0])
+		}
+		generations = generations[1:]
+	}
+}
+
+// RollbackMods restores the files replaced by the current install generation
+// and makes the previous generation current again.
+func RollbackMods(dir string) (*Lock, error) {
+	current, err := ReadLock(dir)
+	if err != nil {
+		return nil, err
+	}
+	if current.Generation == 0 {
+		return nil, fmt.Errorf("no previous mods generation to roll back to")
+	}
+
+	genDir := generationDir(dir, current.Generation)
+	entries, err := readSnapshot(genDir)
+	if err != nil {
+		return nil, fmt.Errorf("generation %d snapshot is unavailable: %w", current.Generation, err)
+	}
+
+	log.Logger.Debug("Rolling back mods generation",
+		"generation", current.Generation, "files", len(entries))
+	if err := restoreSnapshot(dir, genDir, entries); err != nil {
+		return nil, err
+	}
+
+	// The previous lock may have been pruned already
+	previous := &Lock{Mods: map[string]LockedMod{}}
+	if current.Generation > 1 {
+		prevLockFile := filepath.Join(generationDir(dir, current.Generation-1), lockFileName)
+		if err := readJSONFile(prevLockFile, previous); err != nil {
+			log.Logger.Warn("Previous mods lock not found, using an empty one",
+				"generation", current.Generation-1, "error", err)
+			previous = &Lock{Mods: map[string]LockedMod{}}
+		}
+	}
+	previous.Generation = current.Generation - 1
+
+	if err := writeLock(dir, previous); err != nil {
+		return nil, fmt.Errorf("failed to write the mods lock: %w", err)
+	}
+	if err := os.RemoveAll(genDir); err != nil {
+		log.Logger.Warn("Failed to remove rolled back generation", "generation", current.Generation, "error", err)
+	}
+	return previous, nil
+}
@@ -0,0 +1,440 @@
+package mods
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/K4rian/kfdsl/internal/log"
+)
+
+const (
+	publicKeyExt     = ".pub"
+	privateKeyExt    = ".key"
+	indexSigFileExt  = ".sig"
+	modPayloadHeader = "kfdsl-mod-v1"
+)
+
+var ErrUnknownKey = errors.New("unknown publisher key")
+
+type Signature struct {
+	KeyID string `json:"key_id"`
+	Value string `json:"value"` // Base64-encoded ed25519 signature
+}
+
+// TrustStore holds the trusted publisher public keys, indexed by key ID.
+type TrustStore struct {
+	keys map[string]ed25519.PublicKey
+}
+
+// LoadTrustStore reads every <key_id>.pub file of dir.
+// A missing directory results in an empty trust store.
+func LoadTrustStore(dir string) (*TrustStore, error) {
+	ts := &TrustStore{keys: make(map[string]ed25519.PublicKey)}
+	if dir == "" {
+		return ts, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ts, nil
+		}
+		return nil, fmt.Errorf("failed to read trust store %s: %w", dir, err)
+	}
+
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != publicKeyExt {
+			continue
+		}
+		keyID := strings.TrimSuffix(e.Name(), publicKeyExt)
+		key, err := readKeyFile(filepath.Join(dir, e.Name()), ed25519.PublicKeySize)
+		if err != nil {
+			return nil, fmt.Errorf("invalid publisher key %s: %w", keyID, err)
+		}
+		ts.keys[keyID] = ed25519.PublicKey(key)
+		log.Logger.Debug("Loaded publisher key", "keyID", keyID, "dir", dir)
+	}
+	return ts, nil
+}
+
+// Len returns the number of trusted keys.
+func (ts *TrustStore) Len() int {
+	return len(ts.keys)
+}
+
+// Verify checks the signature of message against the key keyID.
+func (ts *TrustStore) Verify(sig *Signature, message []byte) error {
+	key, ok := ts.keys[sig.KeyID]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownKey, sig.KeyID)
+	}
+
+	value, err := base64.StdEncoding.DecodeString(sig.Value)
+	if err != nil {
+		return fmt.Errorf("malformed signature: %w", err)
+	}
+	if !ed25519.Verify(key, message, value) {
+		return fmt.Errorf("invalid signature for key %s", sig.KeyID)
+	}
+	return nil
+}
+
+// GenerateKey writes a new ed25519 key pair as <dir>/<keyID>.key and <dir>/<keyID>.pub.
+func GenerateKey(dir, keyID string) (string, string, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+
+	privFile := filepath.Join(dir, keyID+privateKeyExt)
+	pubFile := filepath.Join(dir, keyID+publicKeyExt)
+	if _, err := os.Stat(privFile); err == nil {
+		return "", "", fmt.Errorf("private key %s already exists", privFile)
+	}
+
+	if err := os.WriteFile(privFile, []byte(base64.StdEncoding.EncodeToString(priv)+"\n"), 0600); err != nil {
+		return "", "", err
+	}
+	if err := os.WriteFile(pubFile, []byte(base64.StdEncoding.EncodeToString(pub)+"\n"), 0644); err != nil {
+		return "", "", err
+	}
+	return privFile, pubFile, nil
+}
+
+// ReadPrivateKey reads a base64-encoded ed25519 private key file.
+func ReadPrivateKey(filename string) (ed25519.PrivateKey, error) {
+	key, err := readKeyFile(filename, ed25519.PrivateKeySize)
+	if err != nil {
+		return nil, err
+	}
+	return ed25519.PrivateKey(key), nil
+}
+
+func readKeyFile(filename string, size int) ([]byte, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("malformed key: %w", err)
+	}
+	if len(key) != size {
+		return nil, fmt.Errorf("invalid key size %d, expected %d", len(key), size)
+	}
+	return key, nil
+}
+
+// signedPayload returns the canonical representation of the mod that gets signed.
+// The signature and enabled state are local and thus excluded.
+func (m *Mod) signedPayload(name string) ([]byte, error) {
+	c := *m
+	c.Signature = nil
+	c.Enabled = false
+
+	data, err := json.Marshal(&c)
+	if err != nil {
+		return nil, err
+	}
+
+	// Drop empty values so that "[]", null and missing fields are equivalent
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	data, err = json.Marshal(pruneEmpty(v))
+	if err != nil {
+		return nil, err
+	}
+	return []byte(modPayloadHeader + "\n" + name + "\n" + string(data)), nil
+}
+
+func pruneEmpty(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		for k, item := range val {
+			item = pruneEmpty(item)
+			switch i := item.(type) {
+			case nil:
+				delete(val, k)
+				continue
+			case []any:
+				if len(i) == 0 {
+					delete(val, k)
+					continue
+				}
+			case map[string]any:
+				if len(i) == 0 {
+					delete(val, k)
+					continue
+				}
+			}
+			val[k] = item
+		}
+		return val
+	case []any:
+		for i := range val {
+			val[i] = pruneEmpty(val[i])
+		}
+		return val
+	}
+	return v
+}
+
+// pinned reports whether the mod content is pinned by a checksum, either
+// the download checksum or one on every install item. The signature only
+// covers the content of the pinned mods.
+func (m *Mod) pinned() bool {
+	if m.Checksum != "" {
+		return true
+	}
+	for _, item := range m.InstallItems {
+		if item.Checksum == "" {
+			return false
+		}
+	}
+	return len(m.InstallItems) > 0
+}
+
+// Sign signs the mod entry with the given private key.
+// Mods without a checksum can't be signed.
+func (m *Mod) Sign(name, keyID string, key ed25519.PrivateKey) error {
+	if !m.pinned() {
+		return fmt.Errorf("mod has no checksum, the signature wouldn't cover its download")
+	}
+	payload, err := m.signedPayload(name)
+	if err != nil {
+		return err
+	}
+	m.Signature = &Signature{
+		KeyID: keyID,
+		Value: base64.StdEncoding.EncodeToString(ed25519.Sign(key, payload)),
+	}
+	return nil
+}
+
+// VerifySignature checks the mod entry signature against the trust store.
+func (m *Mod) VerifySignature(name string, ts *TrustStore) error {
+	if m.Signature == nil {
+		return fmt.Errorf("mod is not signed")
+	}
+	payload, err := m.signedPayload(name)
+	if err != nil {
+		return err
+	}
+	return ts.Verify(m.Signature, payload)
+}
+
+// SignIndex writes a detached signature of the whole mods file as <filename>.sig.
+func SignIndex(filename, keyID string, key ed25519.PrivateKey) (string, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+	sig := &Signature{
+		KeyID: keyID,
+		Value: base64.StdEncoding.EncodeToString(ed25519.Sign(key, data)),
+	}
+	sigFile := filename + indexSigFileExt
+	return sigFile, writeJSONFile(sigFile, sig)
+}
+
+// VerifyIndexSignature checks the detached signature of the mods file, if any.
+// It returns false when the mods file has no detached signature.
+func VerifyIndexSignature(filename string, ts *TrustStore) (bool, error) {
+	var sig Signature
+	if err := readJSONFile(filename+indexSigFileExt, &sig); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to read the mods file signature: %w", err)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return false, err
+	}
+	if err := ts.Verify(&sig, data); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// VerifyMods checks the signature of every mod to install.
+// Invalid signatures are always rejected; unsigned mods, or mods signed
+// by an unknown publisher, are only rejected when signatures are required.
+func VerifyMods(modList map[string]*Mod, ts *TrustStore, require bool) error {
+	var allErrs []error
+	for _, name := range resolveModsToInstall(modList) {
+		mod := modList[name]
+		if mod.Signature == nil {
+			if require {
+				allErrs = append(allErrs, fmt.Errorf("%s: mod is not signed", name))
+			} else {
+				log.Logger.Debug("Mod is not signed", "name", name)
+			}
+			continue
+		}
+
+		err := mod.VerifySignature(name, ts)
+		if errors.Is(err, ErrUnknownKey) && !require {
+			log.Logger.Warn("Mod is signed by an unknown publisher", "name", name, "keyID", mod.Signature.KeyID)
+			continue
+		}
+		if err != nil {
+			allErrs = append(allErrs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		if err := checkPinned(name, mod, require); err != nil {
+			allErrs = append(allErrs, err)
+			continue
+		}
+		log.Logger.Debug("Mod signature verified", "name", name, "keyID", mod.Signature.KeyID)
+	}
+	return errors.Join(allErrs...)
+}
+
+// VerifyPinnedMods checks that every mod to install has a checksum, once the
+// whole mods file signature is verified. The mods without one are only
+// rejected when signatures are required.
+func VerifyPinnedMods(modList map[string]*Mod, require bool) error {
+	var allErrs []error
+	for _, name := range resolveModsToInstall(modList) {
+		if err := checkPinned(name, modList[name], require); err != nil {
+			allErrs = append(allErrs, err)
+		}
+	}
+	return errors.Join(allErrs...)
+}
+
+// checkPinned rejects a signed mod without a checksum when signatures are
+// required, as its download isn't covered by the signature. It only warns
+// otherwise.
+func checkPinned(name string, mod *Mod, require bool) error {
+	if mod.pinned() {
+		return nil
+	}
+	if require {
+		return fmt.Errorf("%s: mod has no checksum, its download isn't covered by the signature", name)
+	}
+	log.Logger.Warn("Signed mod has no checksum, its download isn't covered by the signature", "name", name)
+	return nil
+}
+
+// WriteModsFile writes the signatures of the mods back to filename. The rest
+// of the document, including the fields unknown to the launcher and the
+// order of the mods, is kept as-is.
+func WriteModsFile(filename string, modList map[string]*Mod) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	entries, err := decodeObject(data)
+	if err != nil {
+		return err
+	}
+	for i, entry := range entries {
+		mod, ok := modList[entry.name]
+		if !ok {
+			continue
+		}
+		fields, err := decodeObject(entry.value)
+		if err != nil {
+			return fmt.Errorf("mod %s: %w", entry.name, err)
+		}
+		pos := slices.IndexFunc(fields, func(f jsonField) bool { return f.name == "signature" })
+		switch {
+		case mod.Signature == nil && pos >= 0:
+			fields = slices.Delete(fields, pos, pos+1)
+		case mod.Signature != nil:
+			sig, err := json.Marshal(mod.Signature)
+			if err != nil {
+				return err
+			}
+			if pos >= 0 {
+				fields[pos].value = sig
+			} else {
+				fields = append(fields, jsonField{name: "signature", value: sig})
+			}
+		}
+		if entries[i].value, err = encodeObject(fields); err != nil {
+			return err
+		}
+	}
+
+	raw, err := encodeObject(entries)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, raw, "", "    "); err != nil {
+		return err
+	}
+	buf.WriteByte('\n')
+
+	tempFilename := filename + ".tmp"
+	if err := os.WriteFile(tempFilename, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tempFilename, filename)
+}
+
+// jsonField is a field of a JSON object, in the order of the document.
+type jsonField struct {
+	name  string
+	value json.RawMessage
+}
+
+// decodeObject returns the fields of the JSON object data, in order.
+func decodeObject(data []byte) ([]jsonField, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil {
+		return nil, err
+	} else if tok != json.Delim('{') {
+		return nil, fmt.Errorf("JSON object expected")
+	}
+
+	var fields []jsonField
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		fields = append(fields, jsonField{name: tok.(string), value: value})
+	}
+	return fields, nil
+}
+
+// encodeObject writes fields as a compact JSON object.
+func encodeObject(fields []jsonField) (json.RawMessage, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range fields {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(f.name)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(f.value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,322 @@
+package mods
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/K4rian/kfdsl/internal/log"
+)
+
+func TestMain(m *testing.M) {
+	if err := log.Init("error", "", "text", 1, 1, 1, false); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// newTestTrustStore generates the key pair keyID in a temporary trust store.
+func newTestTrustStore(t *testing.T, keyID string) (*TrustStore, ed25519.PrivateKey) {
+	t.Helper()
+	dir := t.TempDir()
+	privFile, _, err := GenerateKey(dir, keyID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ReadPrivateKey(privFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts, err := LoadTrustStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ts, key
+}
+
+func newTestMod() *Mod {
+	return &Mod{
+		Version:     "1.0",
+		DownloadURL: "https://example.com/mod.zip",
+		Checksum:    "sha256:abcd",
+		InstallItems: []InstallItem{
+			{Name: "Mod.u", Path: "System", Type: "file"},
+		},
+	}
+}
+
+func TestModVerifySignature(t *testing.T) {
+	ts, key := newTestTrustStore(t, "curator")
+	_, otherKey := newTestTrustStore(t, "other")
+
+	tests := []struct {
+		name    string
+		modify  func(m *Mod)
+		verify  string // Name the mod is verified under
+		wantErr error
+		errText string
+	}{
+		{name: "valid", modify: func(m *Mod) {}},
+		{name: "enabled state is local", modify: func(m *Mod) { m.Enabled = true }},
+		{name: "empty and missing lists", modify: func(m *Mod) { m.DependOn = []string{}; m.Authors = nil }},
+		{name: "changed url", modify: func(m *Mod) { m.DownloadURL = "https://evil.example.com/mod.zip" }, errText: "invalid signature"},
+		{name: "changed item", modify: func(m *Mod) { m.InstallItems[0].Path = "Textures" }, errText: "invalid signature"},
+		{name: "added dependency", modify: func(m *Mod) { m.DependOn = []string{"other"} }, errText: "invalid signature"},
+		{name: "renamed", modify: func(m *Mod) {}, verify: "renamed", errText: "invalid signature"},
+		{name: "unsigned", modify: func(m *Mod) { m.Signature = nil }, errText: "not signed"},
+		{name: "unknown key", modify: func(m *Mod) { m.Signature.KeyID = "unknown" }, wantErr: ErrUnknownKey},
+		{name: "malformed", modify: func(m *Mod) { m.Signature.Value = "not base64!" }, errText: "malformed signature"},
+		{name: "other key", modify: func(m *Mod) {
+			m.Signature.Value = base64.StdEncoding.EncodeToString(ed25519.Sign(otherKey, []byte("x")))
+		}, errText: "invalid signature"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mod := newTestMod()
+			if err := mod.Sign("mod", "curator", key); err != nil {
+				t.Fatal(err)
+			}
+			tt.modify(mod)
+
+			name := "mod"
+			if tt.verify != "" {
+				name = tt.verify
+			}
+			err := mod.VerifySignature(name, ts)
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("VerifySignature() error = %v, want %v", err, tt.wantErr)
+				}
+			case tt.errText != "":
+				if err == nil || !strings.Contains(err.Error(), tt.errText) {
+					t.Errorf("VerifySignature() error = %v, want %q", err, tt.errText)
+				}
+			case err != nil:
+				t.Errorf("VerifySignature() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifyMods(t *testing.T) {
+	ts, key := newTestTrustStore(t, "curator")
+	_, unknownKey := newTestTrustStore(t, "unknown")
+
+	signed := newTestMod()
+	signed.Enabled = true
+	if err := signed.Sign("signed", "curator", key); err != nil {
+		t.Fatal(err)
+	}
+	unknown := newTestMod()
+	unknown.Enabled = true
+	if err := unknown.Sign("unknown", "unknown", unknownKey); err != nil {
+		t.Fatal(err)
+	}
+	tampered := newTestMod()
+	tampered.Enabled = true
+	if err := tampered.Sign("tampered", "curator", key); err != nil {
+		t.Fatal(err)
+	}
+	tampered.Version = "2.0"
+	unsigned := newTestMod()
+	unsigned.Enabled = true
+	// Mods without a checksum can't be signed, sign the payload directly
+	unpinned := newTestMod()
+	unpinned.Enabled = true
+	unpinned.Checksum = ""
+	payload, err := unpinned.signedPayload("unpinned")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unpinned.Signature = &Signature{KeyID: "curator", Value: base64.StdEncoding.EncodeToString(ed25519.Sign(key, payload))}
+
+	tests := []struct {
+		name    string
+		mods    map[string]*Mod
+		require bool
+		wantErr bool
+	}{
+		{"signed", map[string]*Mod{"signed": signed}, true, false},
+		{"unsigned allowed", map[string]*Mod{"unsigned": unsigned}, false, false},
+		{"unsigned required", map[string]*Mod{"unsigned": unsigned}, true, true},
+		{"unknown key allowed", map[string]*Mod{"unknown": unknown}, false, false},
+		{"unknown key required", map[string]*Mod{"unknown": unknown}, true, true},
+		{"tampered", map[string]*Mod{"tampered": tampered}, false, true},
+		{"no checksum allowed", map[string]*Mod{"unpinned": unpinned}, false, false},
+		{"no checksum required", map[string]*Mod{"unpinned": unpinned}, true, true},
+		{"disabled mods are skipped", map[string]*Mod{"tampered": {Version: "1.0"}}, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyMods(tt.mods, ts, tt.require)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("VerifyMods() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestModSignChecksum(t *testing.T) {
+	_, key := newTestTrustStore(t, "curator")
+
+	tests := []struct {
+		name    string
+		modify  func(m *Mod)
+		wantErr bool
+	}{
+		{"download checksum", func(m *Mod) {}, false},
+		{"item checksums", func(m *Mod) {
+			m.Checksum = ""
+			m.InstallItems = []InstallItem{
+				{Name: "Mod.u", Path: "System", Type: "file", Checksum: "sha256:abcd"},
+				{Name: "Mod.int", Path: "System", Type: "file", Checksum: "sha256:ef01"},
+			}
+		}, false},
+		{"no checksum", func(m *Mod) { m.Checksum = "" }, true},
+		{"missing item checksum", func(m *Mod) {
+			m.Checksum = ""
+			m.InstallItems = []InstallItem{
+				{Name: "Mod.u", Path: "System", Type: "file", Checksum: "sha256:abcd"},
+				{Name: "Mod.int", Path: "System", Type: "file"},
+			}
+		}, true},
+		{"no items", func(m *Mod) { m.Checksum = ""; m.InstallItems = nil }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mod := newTestMod()
+			tt.modify(mod)
+			err := mod.Sign("mod", "curator", key)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Sign() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && mod.Signature != nil {
+				t.Errorf("Sign() set a signature on error")
+			}
+		})
+	}
+}
+
+func TestVerifyPinnedMods(t *testing.T) {
+	pinned := newTestMod()
+	pinned.Enabled = true
+	unpinned := newTestMod()
+	unpinned.Enabled = true
+	unpinned.Checksum = ""
+
+	tests := []struct {
+		name    string
+		mods    map[string]*Mod
+		require bool
+		wantErr bool
+	}{
+		{"pinned", map[string]*Mod{"pinned": pinned}, true, false},
+		{"no checksum allowed", map[string]*Mod{"unpinned": unpinned}, false, false},
+		{"no checksum required", map[string]*Mod{"unpinned": unpinned}, true, true},
+		{"disabled mods are skipped", map[string]*Mod{"unpinned": {Version: "1.0"}}, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyPinnedMods(tt.mods, tt.require)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("VerifyPinnedMods() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyIndexSignature(t *testing.T) {
+	ts, key := newTestTrustStore(t, "curator")
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "mods.json")
+	if err := os.WriteFile(filename, []byte(`{"mod": {"version": "1.0"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, err := VerifyIndexSignature(filename, ts); ok || err != nil {
+		t.Errorf("VerifyIndexSignature() without signature = %v, %v, want false, nil", ok, err)
+	}
+
+	if _, err := SignIndex(filename, "curator", key); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := VerifyIndexSignature(filename, ts); !ok || err != nil {
+		t.Errorf("VerifyIndexSignature() = %v, %v, want true, nil", ok, err)
+	}
+
+	if err := os.WriteFile(filename, []byte(`{"mod": {"version": "2.0"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := VerifyIndexSignature(filename, ts); ok || err == nil {
+		t.Errorf("VerifyIndexSignature() of a changed file = %v, %v, want false, error", ok, err)
+	}
+}
+
+func TestWriteModsFile(t *testing.T) {
+	ts, key := newTestTrustStore(t, "curator")
+	filename := filepath.Join(t.TempDir(), "mods.json")
+	content := `{
+    "zmod": {"version": "1.0", "checksum": "sha256:abcd", "x_note": "kept", "signature": {"key_id": "old", "value": "old"}, "enabled": true},
+    "amod": {"version": "1.0", "download_url": "https://example.com/a.zip", "checksum": "sha256:abcd"},
+    "unsigned": {"version": "1.0", "signature": {"key_id": "old", "value": "old"}}
+}`
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	modList, err := ParseModsFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"zmod", "amod"} {
+		if err := modList[name].Sign(name, "curator", key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	modList["unsigned"].Signature = nil
+
+	if err := WriteModsFile(filename, modList); err != nil {
+		t.Fatalf("WriteModsFile() error = %v", err)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(data)
+
+	// The mods and their fields keep their order, the unknown fields are kept
+	wantOrder := []string{`"zmod"`, `"x_note": "kept"`, `"signature"`, `"enabled": true`, `"amod"`, `"download_url"`, `"signature"`, `"unsigned"`}
+	pos := 0
+	for _, want := range wantOrder {
+		i := strings.Index(got[pos:], want)
+		if i < 0 {
+			t.Fatalf("%s not found in order in:\n%s", want, got)
+		}
+		pos += i + len(want)
+	}
+	if strings.Contains(got, `"old"`) {
+		t.Errorf("old signatures kept in:\n%s", got)
+	}
+	if strings.Count(got, `"signature"`) != 2 {
+		t.Errorf("want 2 signatures in:\n%s", got)
+	}
+
+	written, err := ParseModsFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"zmod", "amod"} {
+		if err := written[name].VerifySignature(name, ts); err != nil {
+			t.Errorf("%s: VerifySignature() error = %v", name, err)
+		}
+	}
+}
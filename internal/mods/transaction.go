@@ -0,0 +1,150 @@
+package mods
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/K4rian/kfdsl/internal/log"
+	"github.com/K4rian/kfdsl/internal/utils"
+)
+
+const (
+	snapshotFileName = "snapshot.json"
+	snapshotFilesDir = "files"
+)
+
+// stagedFile is a mod file waiting to be swapped into the server directory.
+type stagedFile struct {
+	mod      string
+	src      string // Staged file path
+	dst      string // Destination path in the server directory
+	checksum string
+}
+
+type snapshotEntry struct {
+	Path    string `json:"path"`    // Relative to the server directory
+	Existed bool   `json:"existed"` // False if the file was created by the install
+}
+
+// transaction swaps staged files into the server directory and keeps a
+// snapshot of the replaced files so the operation can be reverted.
+type transaction struct {
+	dir       string
+	backupDir string
+	entries   []snapshotEntry
+	applied   int
+}
+
+func newTransaction(dir, backupDir string) *transaction {
+	return &transaction{
+		dir:       dir,
+		backupDir: backupDir,
+	}
+}
+
+// snapshot saves a copy of every file about to be replaced.
+func (t *transaction) snapshot(files []stagedFile) error {
+	seen := make(map[string]bool)
+	for _, f := range files {
+		rel, err := filepath.Rel(t.dir, f.dst)
+		if err != nil {
+			return err
+		}
+		if seen[rel] {
+			continue
+		}
+		seen[rel] = true
+
+		entry := snapshotEntry{Path: rel, Existed: utils.FileExists(f.dst)}
+		if entry.Existed {
+			backupPath := filepath.Join(t.backupDir, snapshotFilesDir, rel)
+			if _, err := utils.CreateDirIfNotExists(filepath.Dir(backupPath)); err != nil {
+				return err
+			}
+			if err := utils.CopyAndReplaceFile(f.dst, backupPath); err != nil {
+				return fmt.Errorf("failed to snapshot %s: %w", rel, err)
+			}
+		}
+		log.Logger.Debug("Snapshotted mod file", "path", rel, "existed", entry.Existed)
+		t.entries = append(t.entries, entry)
+	}
+	return writeJSONFile(filepath.Join(t.backupDir, snapshotFileName), t.entries)
+}
+
+// commit moves the staged files in place.
+func (t *transaction) commit(files []stagedFile) error {
+	for _, f := range files {
+		if _, err := utils.CreateDirIfNotExists(filepath.Dir(f.dst)); err != nil {
+			return err
+		}
+		if err := os.Rename(f.src, f.dst); err != nil {
+			return fmt.Errorf("failed to move %s in place: %w", f.dst, err)
+		}
+		t.applied++
+		log.Logger.Debug("Installed mod file", "mod", f.mod, "path", f.dst)
+	}
+	return nil
+}
+
+// rollback restores the snapshotted files.
+func (t *transaction) rollback() error {
+	return restoreSnapshot(t.dir, t.backupDir, t.entries)
+}
+
+// restoreSnapshot puts back the files saved in backupDir, and removes
+// the ones that didn't exist when the snapshot was taken.
+func restoreSnapshot(dir, backupDir string, entries []snapshotEntry) error {
+	var firstErr error
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		path := filepath.Join(dir, entry.Path)
+
+		var err error
+		if entry.Existed {
+			err = utils.CopyAndReplaceFile(filepath.Join(backupDir, snapshotFilesDir, entry.Path), path)
+		} else if err = os.Remove(path); os.IsNotExist(err) {
+			err = nil
+		}
+
+		if err != nil {
+			log.Logger.Error("Failed to restore mod file", "path", entry.Path, "error", err)
+			if firstErr == nil {
+				firstErr = fmt.Errorf("failed to restore %s: %w", entry.Path, err)
+			}
+			continue
+		}
+		log.Logger.Debug("Restored mod file", "path", entry.Path, "existed", entry.Existed)
+	}
+	return firstErr
+}
+
+func readSnapshot(backupDir string) ([]snapshotEntry, error) {
+	var entries []snapshotEntry
+	if err := readJSONFile(filepath.Join(backupDir, snapshotFileName), &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func readJSONFile(filename string, v any) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func writeJSONFile(filename string, v any) error {
+	data, err := json.MarshalIndent(v, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	tempFilename := filename + ".tmp"
+	if err := os.WriteFile(tempFilename, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tempFilename, filename)
+}
@@ -0,0 +1,119 @@
+package mods
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readTestFile returns the content of path, or "" if it doesn't exist.
+func readTestFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return ""
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestTransaction(t *testing.T) {
+	tests := []struct {
+		name       string
+		missingSrc bool // The last staged file is missing, failing the commit
+		wantErr    bool
+	}{
+		{"commit", false, false},
+		{"failed commit", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			stageDir := t.TempDir()
+			backupDir := t.TempDir()
+
+			replaced := filepath.Join(dir, "System", "Replaced.u")
+			created := filepath.Join(dir, "System", "Created.u")
+			writeTestFile(t, replaced, "old")
+			files := []stagedFile{
+				{mod: "mod", src: filepath.Join(stageDir, "Replaced.u"), dst: replaced},
+				{mod: "mod", src: filepath.Join(stageDir, "Created.u"), dst: created},
+			}
+			writeTestFile(t, files[0].src, "new")
+			if !tt.missingSrc {
+				writeTestFile(t, files[1].src, "created")
+			}
+
+			tx := newTransaction(dir, backupDir)
+			if err := tx.snapshot(files); err != nil {
+				t.Fatalf("snapshot() error = %v", err)
+			}
+			entries, err := readSnapshot(backupDir)
+			if err != nil {
+				t.Fatalf("readSnapshot() error = %v", err)
+			}
+			if len(entries) != 2 || !entries[0].Existed || entries[1].Existed {
+				t.Fatalf("readSnapshot() = %+v, want the replaced file then the created one", entries)
+			}
+
+			err = tx.commit(files)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("commit() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := readTestFile(t, replaced); got != "new" {
+				t.Errorf("replaced file after commit = %q, want %q", got, "new")
+			}
+			if !tt.wantErr {
+				if got := readTestFile(t, created); got != "created" {
+					t.Errorf("created file after commit = %q, want %q", got, "created")
+				}
+			}
+
+			if err := tx.rollback(); err != nil {
+				t.Fatalf("rollback() error = %v", err)
+			}
+			if got := readTestFile(t, replaced); got != "old" {
+				t.Errorf("replaced file after rollback = %q, want %q", got, "old")
+			}
+			if got := readTestFile(t, created); got != "" {
+				t.Errorf("created file after rollback = %q, want it removed", got)
+			}
+		})
+	}
+}
+
+func TestNewLock(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "System", "Mod.u"), "mod")
+	modList := map[string]*Mod{
+		"installed": {Version: "1.0", InstallItems: []InstallItem{{Name: "Mod.u", Path: "System", Type: "file"}}},
+		"missing":   {Version: "1.0", InstallItems: []InstallItem{{Name: "Missing.u", Path: "System", Type: "file"}}},
+	}
+
+	lock, err := newLock(dir, 2, modList, []string{"installed"})
+	if err != nil {
+		t.Fatalf("newLock() error = %v", err)
+	}
+	files := lock.Mods["installed"].Files
+	if lock.Generation != 2 || len(files) != 1 || !strings.HasPrefix(files[0].Checksum, "sha256:") {
+		t.Errorf("newLock() = %+v, want generation 2 and the checksum of Mod.u", lock)
+	}
+
+	if _, err := newLock(dir, 2, modList, []string{"installed", "missing"}); err == nil {
+		t.Error("newLock() with a missing file: want an error")
+	}
+}
@@ -0,0 +1,65 @@
+package monitor
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Metric is a resource usage metric of the server process.
+type Metric string
+
+const (
+	MetricRSS     Metric = "rss"     // Resident memory, in MB
+	MetricVM      Metric = "vm"      // Virtual memory size, in MB
+	MetricCPU     Metric = "cpu"     // CPU usage, in percent of a core
+	MetricThreads Metric = "threads" // Number of threads
+)
+
+// Action is run when a rule threshold is exceeded.
+type Action string
+
+const (
+	ActionWarn    Action = "warn"    // Log a warning
+	ActionRestart Action = "restart" // Restart the server
+)
+
+var (
+	metrics = []Metric{MetricRSS, MetricVM, MetricCPU, MetricThreads}
+	actions = []Action{ActionWarn, ActionRestart}
+)
+
+// Rule is a resource usage threshold of the server process.
+type Rule struct {
+	Name            string
+	Metric          Metric
+	Above           float64
+	For             time.Duration // Time the metric must stay above the threshold
+	Action          Action
+	DeferUntilEmpty time.Duration // Max time to wait for an empty server before a restart
+	OnlyIfEmpty     bool          // Skip the restart when players are still connected
+}
+
+// Rules are the resource usage rules of a server.
+type Rules []Rule
+
+// Validate checks the metric, threshold and action of the rule.
+func (r Rule) Validate() error {
+	if !slices.Contains(metrics, r.Metric) {
+		return fmt.Errorf("rule %s: unknown metric '%s'", r.Name, r.Metric)
+	}
+	if r.Above <= 0 {
+		return fmt.Errorf("rule %s: the threshold must be positive", r.Name)
+	}
+	if !slices.Contains(actions, r.Action) {
+		return fmt.Errorf("rule %s: unknown action '%s'", r.Name, r.Action)
+	}
+	if r.For < 0 || r.DeferUntilEmpty < 0 {
+		return fmt.Errorf("rule %s: negative duration", r.Name)
+	}
+	return nil
+}
+
+func (r Rule) String() string {
+	return fmt.Sprintf("%s > %g for %s", r.Metric, r.Above, r.For)
+}
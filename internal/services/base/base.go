@@ -8,12 +8,14 @@ import (
 	"os"
 	"os/exec"
 	"os/signal"
+	"runtime"
 	"sync"
 	"syscall"
 	"time"
 
 	"github.com/K4rian/dslogger"
 	"github.com/creack/pty"
+	"golang.org/x/sys/unix"
 
 	"github.com/K4rian/kfdsl/internal/log"
 )
@@ -34,11 +36,16 @@ type BaseService struct {
 	startOnce    sync.Once
 	restartCount int
 	logHandlers  []ServiceLogHandler
+	lastOutput   time.Time
+	startTime    time.Time
+	usage        *Usage // Last resource usage sample
 
 	// preRestartHook is called before the process is stopped during a restart.
 	preRestartHook func()
 	// postRestartHook is called after the process has been successfully restarted.
 	postRestartHook func()
+	// exitHook is called when the process exits.
+	exitHook func(state *os.ProcessState, requested bool)
 }
 
 // NewBaseService constructs a BaseService with the given name, parent context,
@@ -97,6 +104,16 @@ func (bs *BaseService) SetPostRestartHook(fn func()) {
 	bs.postRestartHook = fn
 }
 
+// SetExitHook registers a function to be called when the process exits,
+// before Wait returns. requested is true when the exit follows a Stop or a
+// restart cycle.
+// Only one hook is supported.
+func (bs *BaseService) SetExitHook(fn func(state *os.ProcessState, requested bool)) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	bs.exitHook = fn
+}
+
 // AddLogHandler registers a log handler callback that is called for every
 // line of output produced by the process.
 func (bs *BaseService) AddLogHandler(h ServiceLogHandler) {
@@ -123,21 +140,35 @@ func (bs *BaseService) Start(args []string) error {
 	// Reset the execution error variable
 	bs.execErr = nil
 
+	bs.startTime = time.Now()
+	bs.lastOutput = bs.startTime
+	bs.usage = nil
+
 	// Set up the command
 	cmd := exec.Command(args[0], args[1:]...)
 	cmd.Dir = bs.Options().WorkingDirectory
+	cmd.Env = bs.opts.Env
+	if bs.opts.Credential != nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{Credential: bs.opts.Credential}
+	}
 	bs.cmd = cmd
 
 	// Start the process with a pseudo-terminal
-	ptmx, err := pty.Start(bs.cmd)
+	ptmx, err := startChild(cmd, bs.startProcess)
 	if err != nil {
 		return fmt.Errorf("failed to start pty: %v", err)
 	}
 	bs.ptmx = ptmx
 
+	bs.applyLimits(cmd.Process.Pid)
+
 	// Create a done channel to signal when the process is finished
 	bs.done = make(chan struct{})
 
+	if bs.opts.UsageInterval > 0 {
+		go bs.monitorUsage(cmd.Process.Pid, bs.done)
+	}
+
 	// Goroutine to monitor the cancellation context
 	bs.startOnce.Do(func() {
 		go bs.monitorCancellation()
@@ -165,11 +196,25 @@ func (bs *BaseService) Start(args []string) error {
 			close(bs.done)
 		}()
 
+		// Wait for the process while its output is read. The processes left
+		// in its session are stopped once it exits.
+		exited := make(chan error, 1)
+		go func() {
+			err := cmd.Wait()
+			untrackChild(cmd.Process.Pid)
+			bs.stopSession(cmd.Process.Pid)
+			exited <- err
+		}()
+
 		scanner := bufio.NewScanner(ptmx)
 		for scanner.Scan() {
 			line := scanner.Text()
 			bs.logger.Info(line)
 
+			bs.mu.Lock()
+			bs.lastOutput = time.Now()
+			bs.mu.Unlock()
+
 			// Invoke registered log handlers
 			// Only the first matching line triggers a restart
 			for _, h := range handlers {
@@ -199,13 +244,21 @@ func (bs *BaseService) Start(args []string) error {
 		}
 
 		// Wait for the process to exit
-		if err := cmd.Wait(); err != nil {
+		if err := <-exited; err != nil {
 			bs.mu.Lock()
 			bs.execErr = fmt.Errorf("process exited with error: %v", err)
 			bs.mu.Unlock()
 		} else {
 			bs.logger.Debug("Process exited normally")
 		}
+
+		bs.mu.Lock()
+		exitHook := bs.exitHook
+		requested := bs.stopping
+		bs.mu.Unlock()
+		if exitHook != nil {
+			exitHook(cmd.ProcessState, requested)
+		}
 	}()
 	return nil
 }
@@ -245,16 +298,16 @@ func (bs *BaseService) Stop() error {
 	case <-time.After(bs.opts.ShutdownTimeout):
 	}
 
-	// Process is still alive, escalate to SIGTERM
+	// Process is still alive, escalate to SIGTERM, sent to its whole group
 	bs.mu.Lock()
 	cmd := bs.cmd
 	bs.mu.Unlock()
 
 	if cmd != nil && cmd.Process != nil {
 		bs.logger.Warn("Process did not exit after SIGINT, attempting SIGTERM...")
-		if err := cmd.Process.Signal(syscall.SIGTERM); err != nil {
+		if err := signalGroup(cmd, syscall.SIGTERM); err != nil && !errors.Is(err, os.ErrProcessDone) {
 			bs.logger.Error("Failed to send SIGTERM, attempting SIGKILL...", "error", err)
-			if err := cmd.Process.Kill(); err != nil {
+			if err := signalGroup(cmd, syscall.SIGKILL); err != nil && !errors.Is(err, os.ErrProcessDone) {
 				return fmt.Errorf("failed to force kill process: %v", err)
 			}
 			bs.logger.Info("Process forcefully killed")
@@ -262,6 +315,20 @@ func (bs *BaseService) Stop() error {
 	}
 
 	// Wait for the process to finish after SIGTERM/SIGKILL
+	select {
+	case <-done:
+		return nil
+	case <-time.After(bs.opts.KillTimeout):
+	}
+
+	// A frozen process may not handle SIGTERM
+	if cmd != nil && cmd.Process != nil {
+		bs.logger.Warn("Process did not exit after SIGTERM, attempting SIGKILL...")
+		if err := signalGroup(cmd, syscall.SIGKILL); err != nil && !errors.Is(err, os.ErrProcessDone) {
+			return fmt.Errorf("failed to force kill process: %v", err)
+		}
+	}
+
 	select {
 	case <-done:
 	case <-time.After(bs.opts.KillTimeout):
@@ -272,50 +339,112 @@ func (bs *BaseService) Stop() error {
 
 // Restart stops the process and starts it again with the same arguments.
 func (bs *BaseService) Restart() {
+	bs.restart(RestartCrash, nil, nil)
+}
+
+// RestartFor restarts the process for the given reason, unless it is not
+// running or already being stopped.
+func (bs *BaseService) RestartFor(reason RestartReason) error {
+	return bs.RestartWith(reason, nil)
+}
+
+// RestartWith is like RestartFor, prepare is called while the process is
+// stopped.
+func (bs *BaseService) RestartWith(reason RestartReason, prepare func() error) error {
+	bs.mu.Lock()
+	if bs.stopping || !bs.isRunning() {
+		bs.mu.Unlock()
+		return nil
+	}
+	bs.mu.Unlock()
+	return bs.restart(reason, nil, prepare)
+}
+
+// Reload stops the process, calls prepare and starts the process again with
+// args. Unlike Restart, it doesn't depend on AutoRestart and isn't counted
+// as a restart attempt. If prepare fails, the previous arguments are used.
+func (bs *BaseService) Reload(args []string, prepare func() error) error {
+	return bs.restart(RestartReload, args, prepare)
+}
+
+// restart runs a restart cycle for the given reason. A nil args restarts the
+// process with the same arguments.
+func (bs *BaseService) restart(reason RestartReason, args []string, prepare func() error) error {
 	bs.mu.Lock()
 	autoRestart := bs.opts.AutoRestart
-	args := make([]string, len(bs.args))
-	copy(args, bs.args)
+	prevArgs := make([]string, len(bs.args))
+	copy(prevArgs, bs.args)
 	bs.stopping = true
 	preHook := bs.preRestartHook
 	postHook := bs.postRestartHook
 	bs.mu.Unlock()
 
+	if args == nil {
+		args = prevArgs
+	}
+
+	bs.logger.Debug("Starting restart cycle", "reason", reason)
+
 	// Allow the embedding service to clean up before the process is stopped
 	if preHook != nil {
 		preHook()
 	}
 
 	if err := bs.Stop(); err != nil {
-		bs.logger.Error("Failed to stop service during restart", "error", err)
-		return
+		bs.logger.Error("Failed to stop service during restart", "reason", reason, "error", err)
+		return err
 	}
 
-	// If the auto restart feature is disabled, exit here
-	if !autoRestart {
-		bs.logger.Info("Auto-restart is disabled; service will remain stopped")
-		bs.cancel() // unblock monitorCancellation and signal the app to exit
-		return
+	if reason.counted() {
+		// If the auto restart feature is disabled, exit here
+		if !autoRestart {
+			bs.logger.Info("Auto-restart is disabled; service will remain stopped")
+			bs.cancel() // unblock monitorCancellation and signal the app to exit
+			return nil
+		}
+
+		// Check whether the restart cap has been reached
+		if !bs.incrementRestartCount() {
+			bs.cancel()
+			return fmt.Errorf("max restart attempts reached")
+		}
 	}
 
-	// Check whether the restart cap has been reached
-	if !bs.incrementRestartCount() {
-		bs.cancel()
-		return
+	var prepareErr error
+	if prepare != nil {
+		if prepareErr = prepare(); prepareErr != nil {
+			bs.logger.Error("Failed to prepare the restart, using the previous arguments", "reason", reason, "error", prepareErr)
+			args = prevArgs
+		}
 	}
 
-	bs.logger.Info("Restarting service...")
-	time.Sleep(bs.opts.RestartDelay)
+	bs.logger.Info("Restarting service...", "reason", reason)
+	if reason.counted() {
+		time.Sleep(bs.opts.RestartDelay)
+	}
 
 	if err := bs.Start(args); err != nil {
-		bs.logger.Error("Failed to restart service", "error", err)
-		return
+		bs.logger.Error("Failed to restart service", "reason", reason, "error", err)
+		return err
 	}
 
 	// Allow the embedding service to reinitialise after the new process is up
 	if postHook != nil {
 		postHook()
 	}
+	return prepareErr
+}
+
+// SendCommand writes command to the process console, as if typed in it.
+func (bs *BaseService) SendCommand(command string) error {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	if bs.ptmx == nil {
+		return fmt.Errorf("not running")
+	}
+	_, err := bs.ptmx.Write([]byte(command + "\n"))
+	return err
 }
 
 // Wait waits for the process to exit and returns any execution error.
@@ -331,6 +460,32 @@ func (bs *BaseService) Wait() error {
 	return bs.execErr
 }
 
+// Pid returns the process ID, or 0 if the process is not running.
+func (bs *BaseService) Pid() int {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	if !bs.isRunning() {
+		return 0
+	}
+	return bs.cmd.Process.Pid
+}
+
+// LastOutput returns the time of the last line printed by the process, or
+// of its start if it printed nothing yet.
+func (bs *BaseService) LastOutput() time.Time {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	return bs.lastOutput
+}
+
+// StartTime returns the start time of the process, or of the last one if it
+// is not running.
+func (bs *BaseService) StartTime() time.Time {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	return bs.startTime
+}
+
 // IsInstalled returns true if the service is present on the system.
 func (bs *BaseService) IsInstalled() bool {
 	return false
@@ -413,6 +568,37 @@ func (bs *BaseService) monitorAutoRestart() {
 	}
 }
 
+// startProcess starts cmd with a pseudo-terminal. The no_new_privs
+// attribute, the nice value and the CPU affinity are per thread and
+// inherited by the forked process, so they're set on a dedicated thread
+// which is discarded once the process is started.
+func (bs *BaseService) startProcess(cmd *exec.Cmd) (*os.File, error) {
+	if !bs.opts.NoNewPrivs && bs.opts.Nice == 0 && len(bs.opts.CPUAffinity) == 0 {
+		return pty.Start(cmd)
+	}
+
+	type result struct {
+		ptmx *os.File
+		err  error
+	}
+	started := make(chan result, 1)
+	go func() {
+		// Never unlocked, so the thread exits with the goroutine
+		runtime.LockOSThread()
+		if bs.opts.NoNewPrivs {
+			if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+				started <- result{nil, fmt.Errorf("failed to set no_new_privs: %w", err)}
+				return
+			}
+		}
+		bs.applyScheduling()
+		ptmx, err := pty.Start(cmd)
+		started <- result{ptmx, err}
+	}()
+	r := <-started
+	return r.ptmx, r.err
+}
+
 // monitorCancellation listens for OS interrupt signals and gracefully shuts down the process.
 func (bs *BaseService) monitorCancellation() {
 	signalChan := make(chan os.Signal, 1)
@@ -0,0 +1,100 @@
+package base
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"golang.org/x/sys/unix"
+)
+
+// applyScheduling sets the nice value and the CPU affinity of the calling
+// thread, which the processes it starts inherit. A value that can't be set
+// is only reported.
+func (bs *BaseService) applyScheduling() {
+	opts := bs.opts
+
+	// With PRIO_PROCESS, 0 is the calling thread and not the whole launcher
+	if opts.Nice != 0 {
+		if err := unix.Setpriority(unix.PRIO_PROCESS, 0, opts.Nice); err != nil {
+			bs.logger.Warn("Failed to set the process priority", "nice", opts.Nice, "error", err)
+		}
+	}
+	if len(opts.CPUAffinity) > 0 {
+		var set unix.CPUSet
+		for _, cpu := range opts.CPUAffinity {
+			set.Set(cpu)
+		}
+		if err := unix.SchedSetaffinity(0, &set); err != nil {
+			bs.logger.Warn("Failed to set the process CPU affinity", "cpus", opts.CPUAffinity, "error", err)
+		}
+	}
+	bs.logger.Debug("Process scheduling set", "nice", opts.Nice, "cpus", opts.CPUAffinity)
+}
+
+// applyLimits applies the resource limits to the started process pid. Unlike
+// the scheduling, they're shared by the threads of the launcher and can't be
+// set for the process alone before it starts, so it runs a brief moment with
+// the launcher limits. A limit that can't be applied is only reported.
+func (bs *BaseService) applyLimits(pid int) {
+	opts := bs.opts
+
+	if opts.MaxOpenFiles > 0 {
+		bs.setRlimit(pid, unix.RLIMIT_NOFILE, "open files", opts.MaxOpenFiles)
+	}
+	if opts.MaxAddressSpace > 0 {
+		bs.setRlimit(pid, unix.RLIMIT_AS, "address space", opts.MaxAddressSpace)
+	}
+	if opts.CoreDumps {
+		bs.enableCoreDumps(pid)
+	}
+
+	if opts.OOMScoreAdj != 0 {
+		path := fmt.Sprintf("/proc/%d/oom_score_adj", pid)
+		if err := os.WriteFile(path, []byte(strconv.Itoa(opts.OOMScoreAdj)), 0); err != nil {
+			bs.logger.Warn("Failed to set the process OOM score adjustment", "pid", pid, "adj", opts.OOMScoreAdj, "error", err)
+		} else {
+			bs.logger.Debug("Process OOM score adjusted", "pid", pid, "adj", opts.OOMScoreAdj)
+		}
+	}
+}
+
+// setRlimit sets the resource soft limit of pid to value. The hard limit is
+// raised when lower, which requires CAP_SYS_RESOURCE.
+func (bs *BaseService) setRlimit(pid int, resource int, name string, value uint64) {
+	var limit unix.Rlimit
+	if err := unix.Prlimit(pid, resource, nil, &limit); err != nil {
+		bs.logger.Warn("Failed to read the process limit", "pid", pid, "limit", name, "error", err)
+		return
+	}
+
+	limit.Cur = value
+	if limit.Max != unix.RLIM_INFINITY && value > limit.Max {
+		limit.Max = value
+	}
+	if err := unix.Prlimit(pid, resource, &limit, nil); err != nil {
+		bs.logger.Warn("Failed to set the process limit", "pid", pid, "limit", name, "value", value, "error", err)
+		return
+	}
+	bs.logger.Debug("Process limit set", "pid", pid, "limit", name, "value", value)
+}
+
+// enableCoreDumps raises the RLIMIT_CORE soft limit of pid to its hard limit.
+func (bs *BaseService) enableCoreDumps(pid int) {
+	var limit unix.Rlimit
+	if err := unix.Prlimit(pid, unix.RLIMIT_CORE, nil, &limit); err != nil {
+		bs.logger.Warn("Failed to read the process core dump limit", "pid", pid, "error", err)
+		return
+	}
+	if limit.Max == 0 {
+		bs.logger.Warn("Core dumps are disabled by the hard limit", "pid", pid)
+		return
+	}
+
+	limit.Cur = limit.Max
+	if err := unix.Prlimit(pid, unix.RLIMIT_CORE, &limit, nil); err != nil {
+		bs.logger.Warn("Failed to enable the process core dumps", "pid", pid, "error", err)
+		return
+	}
+	bs.logger.Debug("Core dumps enabled", "pid", pid, "limit", limit.Cur)
+}
@@ -1,6 +1,7 @@
 package base
 
 import (
+	"syscall"
 	"time"
 )
 
@@ -12,6 +13,19 @@ type ServiceOptions struct {
 	RestartDelay     time.Duration
 	ShutdownTimeout  time.Duration
 	KillTimeout      time.Duration
+	CoreDumps        bool          // Raise the process RLIMIT_CORE soft limit to the hard one
+	Nice             int           // Scheduling priority, from -20 (highest) to 19 (lowest), set before the start
+	CPUAffinity      []int         // CPUs the process can run on, all if empty, set before the start
+	MaxOpenFiles     uint64        // RLIMIT_NOFILE, unchanged if 0
+	MaxAddressSpace  uint64        // RLIMIT_AS in bytes, unchanged if 0
+	OOMScoreAdj      int           // Added to the process OOM killer score, from -1000 to 1000
+	UsageInterval    time.Duration // Interval between the resource usage samples, disabled if 0
+
+	// The process always runs in its own session, and so process group,
+	// since it's started with a pseudo-terminal
+	Credential *syscall.Credential // User and groups the process runs as, the launcher ones if nil
+	Env        []string            // Environment of the process, the launcher one if nil
+	NoNewPrivs bool                // Prevent the process from gaining privileges (PR_SET_NO_NEW_PRIVS)
 }
 
 /*
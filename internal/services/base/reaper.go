@@ -0,0 +1,235 @@
+package base
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/K4rian/kfdsl/internal/log"
+)
+
+const (
+	reapInterval       = 30 * time.Second // SIGCHLD signals can be coalesced
+	sessionPollDelay   = 100 * time.Millisecond
+	sessionKillTimeout = 5 * time.Second // Used when the service has no kill timeout
+)
+
+// children are the processes started by the services, reaped by their
+// exec.Cmd and never by the reaper.
+var (
+	childrenMu sync.Mutex
+	children   = map[int]bool{}
+)
+
+// StartReaper reaps the orphaned processes reparented to the launcher, which
+// happens when it runs as PID 1 (e.g. in a container) or as a child
+// subreaper. With subreaper, the launcher becomes the subreaper of its
+// descendants. The reaper stops when ctx is done.
+func StartReaper(ctx context.Context, subreaper bool) error {
+	if subreaper {
+		if err := unix.Prctl(unix.PR_SET_CHILD_SUBREAPER, 1, 0, 0, 0); err != nil {
+			return fmt.Errorf("failed to become a child subreaper: %w", err)
+		}
+	} else if os.Getpid() != 1 {
+		return nil
+	}
+
+	sigChld := make(chan os.Signal, 1)
+	signal.Notify(sigChld, syscall.SIGCHLD)
+	go func() {
+		defer signal.Stop(sigChld)
+
+		ticker := time.NewTicker(reapInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-sigChld:
+			case <-ticker.C:
+			}
+			reapOrphans()
+		}
+	}()
+	log.Logger.Debug("Orphaned process reaper started",
+		"function", "StartReaper", "pid", os.Getpid(), "subreaper", subreaper)
+	return nil
+}
+
+// reapOrphans reaps the exited children of the launcher not started by a
+// service.
+func reapOrphans() {
+	self := os.Getpid()
+
+	childrenMu.Lock()
+	defer childrenMu.Unlock()
+
+	for _, pid := range listProcesses() {
+		if children[pid] {
+			continue
+		}
+		stat, err := readProcStat(pid)
+		if err != nil || stat.ppid != self || stat.state != "Z" {
+			continue
+		}
+
+		var status unix.WaitStatus
+		if wpid, err := unix.Wait4(pid, &status, unix.WNOHANG, nil); err == nil && wpid == pid {
+			log.Logger.Debug("Orphaned process reaped",
+				"function", "reapOrphans", "pid", pid, "exitCode", status.ExitStatus())
+		}
+	}
+}
+
+// startChild starts cmd with start and tracks it until untrackChild is
+// called, so the reaper leaves it to cmd.Wait.
+func startChild(cmd *exec.Cmd, start func(*exec.Cmd) (*os.File, error)) (*os.File, error) {
+	childrenMu.Lock()
+	defer childrenMu.Unlock()
+
+	ptmx, err := start(cmd)
+	if err == nil {
+		children[cmd.Process.Pid] = true
+	}
+	return ptmx, err
+}
+
+func untrackChild(pid int) {
+	childrenMu.Lock()
+	delete(children, pid)
+	childrenMu.Unlock()
+}
+
+// signalGroup sends sig to the process group of cmd. The process leads its
+// own group, since it's started in a new session, which also holds the
+// descendants that didn't leave it.
+func signalGroup(cmd *exec.Cmd, sig syscall.Signal) error {
+	err := unix.Kill(-cmd.Process.Pid, sig)
+	if errors.Is(err, unix.ESRCH) {
+		return os.ErrProcessDone
+	}
+	return err
+}
+
+// stopSession stops the processes left in the session of the exited process
+// sid, such as the children of a script. They're sent SIGTERM, then SIGKILL
+// when they're still running after the kill timeout.
+func (bs *BaseService) stopSession(sid int) {
+	timeout := bs.opts.KillTimeout
+	if timeout <= 0 {
+		timeout = sessionKillTimeout
+	}
+
+	for _, sig := range []syscall.Signal{syscall.SIGTERM, syscall.SIGKILL} {
+		pids := sessionProcesses(sid)
+		if len(pids) == 0 {
+			return
+		}
+
+		bs.logger.Warn("Processes left after the process exit, sending "+unix.SignalName(sig)+"...", "pids", pids)
+		for _, pid := range pids {
+			if err := unix.Kill(pid, sig); err != nil && !errors.Is(err, unix.ESRCH) {
+				bs.logger.Error("Failed to signal a left process", "pid", pid, "signal", unix.SignalName(sig), "error", err)
+			}
+		}
+
+		for deadline := time.Now().Add(timeout); time.Now().Before(deadline); {
+			if len(sessionProcesses(sid)) == 0 {
+				bs.logger.Info("Left processes stopped", "pids", pids)
+				return
+			}
+			time.Sleep(sessionPollDelay)
+		}
+	}
+
+	if pids := sessionProcesses(sid); len(pids) > 0 {
+		bs.logger.Error("Processes still running after SIGKILL", "pids", pids)
+	}
+}
+
+// procStat holds the fields of /proc/<pid>/stat used by the launcher.
+type procStat struct {
+	state   string
+	ppid    int
+	session int
+	cpuTime uint64 // User and system CPU time, in clock ticks
+}
+
+func readProcStat(pid int) (procStat, error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return procStat{}, err
+	}
+	return parseProcStat(pid, data)
+}
+
+// parseProcStat parses the content of /proc/<pid>/stat.
+func parseProcStat(pid int, data []byte) (procStat, error) {
+	// The process name is enclosed in parentheses and may contain spaces
+	stat := string(data)
+	end := strings.LastIndexByte(stat, ')')
+	if end < 0 {
+		return procStat{}, fmt.Errorf("malformed stat of process %d", pid)
+	}
+
+	// Fields following the name, starting with the state (3rd field)
+	fields := strings.Fields(stat[end+1:])
+	if len(fields) < 13 {
+		return procStat{}, fmt.Errorf("malformed stat of process %d", pid)
+	}
+	ppid, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return procStat{}, err
+	}
+	session, err := strconv.Atoi(fields[3])
+	if err != nil {
+		return procStat{}, err
+	}
+	utime, err := strconv.ParseUint(fields[11], 10, 64)
+	if err != nil {
+		return procStat{}, err
+	}
+	stime, err := strconv.ParseUint(fields[12], 10, 64)
+	if err != nil {
+		return procStat{}, err
+	}
+	return procStat{state: fields[0], ppid: ppid, session: session, cpuTime: utime + stime}, nil
+}
+
+// listProcesses returns the IDs of the running processes.
+func listProcesses() []int {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil
+	}
+
+	var pids []int
+	for _, e := range entries {
+		if pid, err := strconv.Atoi(e.Name()); err == nil {
+			pids = append(pids, pid)
+		}
+	}
+	return pids
+}
+
+// sessionProcesses returns the live processes of the session sid.
+func sessionProcesses(sid int) []int {
+	var pids []int
+	for _, pid := range listProcesses() {
+		stat, err := readProcStat(pid)
+		if err == nil && stat.session == sid && stat.state != "Z" {
+			pids = append(pids, pid)
+		}
+	}
+	return pids
+}
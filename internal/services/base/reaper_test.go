@@ -0,0 +1,57 @@
+package base
+
+import (
+	"os"
+	"testing"
+)
+
+func TestParseProcStat(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    procStat
+		wantErr bool
+	}{
+		{
+			name: "running",
+			data: "1234 (ucc-bin) S 1 1234 1234 0 -1 4194560 3197 0 0 0 150 25 0 0 20 0 9 0 12345 123456789 2345 18446744073709551615\n",
+			want: procStat{state: "S", ppid: 1, session: 1234, cpuTime: 175},
+		},
+		{
+			name: "zombie",
+			data: "42 (sh) Z 1 40 40 0 -1 4227148 0 0 0 0 0 0 0 0 20 0 1 0 100 0 0 18446744073709551615",
+			want: procStat{state: "Z", ppid: 1, session: 40},
+		},
+		{
+			name: "name with spaces and parentheses",
+			data: "77 (a (b) c) R 10 77 5 0 -1 0 0 0 0 0 7 3 0 0 20 0 1 0 100 0 0 0",
+			want: procStat{state: "R", ppid: 10, session: 5, cpuTime: 10},
+		},
+		{name: "no name", data: "1234 ucc-bin S 1 1234 1234", wantErr: true},
+		{name: "truncated", data: "1234 (ucc-bin) S 1 1234 1234 0 -1", wantErr: true},
+		{name: "invalid ppid", data: "1234 (ucc-bin) S x 1234 1234 0 -1 0 0 0 0 0 1 1 0 0", wantErr: true},
+		{name: "invalid cpu time", data: "1234 (ucc-bin) S 1 1234 1234 0 -1 0 0 0 0 0 x 1 0 0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseProcStat(1234, []byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseProcStat() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("parseProcStat() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadProcStat(t *testing.T) {
+	stat, err := readProcStat(os.Getpid())
+	if err != nil {
+		t.Skipf("/proc unavailable: %v", err)
+	}
+	if stat.ppid != os.Getppid() {
+		t.Errorf("readProcStat() ppid = %d, want %d", stat.ppid, os.Getppid())
+	}
+}
@@ -0,0 +1,18 @@
+package base
+
+// RestartReason tells why a service is restarted.
+type RestartReason string
+
+const (
+	RestartCrash     RestartReason = "crash"     // The process crashed or asked for a restart
+	RestartReload    RestartReason = "reload"    // The configuration has been reloaded
+	RestartHang      RestartReason = "hang"      // The process stopped responding
+	RestartScheduled RestartReason = "scheduled" // A scheduled task restarts the process
+	RestartIdle      RestartReason = "idle"      // The process is restarted while unused
+	RestartUsage     RestartReason = "usage"     // The process exceeded a resource usage threshold
+)
+
+// counted reports whether the restart counts toward the restart cap.
+func (r RestartReason) counted() bool {
+	return r == RestartCrash || r == RestartHang
+}
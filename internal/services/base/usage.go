@@ -0,0 +1,128 @@
+package base
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const ClockTicks = 100 // USER_HZ, the unit of the /proc/<pid>/stat CPU times
+
+// Usage is a resource usage sample of the process.
+type Usage struct {
+	Time    time.Time `json:"time"`
+	RSS     uint64    `json:"rss"`     // Resident memory, in bytes
+	VMSize  uint64    `json:"vm_size"` // Virtual memory size, in bytes
+	CPU     float64   `json:"cpu"`     // CPU usage since the previous sample, in percent of a core
+	Threads int       `json:"threads"`
+}
+
+// Usage returns the last resource usage sample of the process, or of the
+// last one if it is not running. It returns false when there is none.
+func (bs *BaseService) Usage() (Usage, bool) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	if bs.usage == nil {
+		return Usage{}, false
+	}
+	return *bs.usage, true
+}
+
+// ProcessCPUTime returns the user and system CPU time of pid, in clock ticks.
+func ProcessCPUTime(pid int) (uint64, error) {
+	stat, err := readProcStat(pid)
+	if err != nil {
+		return 0, err
+	}
+	return stat.cpuTime, nil
+}
+
+// monitorUsage samples the resource usage of pid at each usage interval
+// until done is closed.
+func (bs *BaseService) monitorUsage(pid int, done <-chan struct{}) {
+	ticker := time.NewTicker(bs.opts.UsageInterval)
+	defer ticker.Stop()
+
+	var prevCPUTime uint64
+	var prevTime time.Time
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		usage, cpuTime, err := sampleUsage(pid)
+		if err != nil {
+			bs.logger.Debug("Failed to sample the process resource usage", "pid", pid, "error", err)
+			continue
+		}
+		if !prevTime.IsZero() && cpuTime >= prevCPUTime {
+			elapsed := usage.Time.Sub(prevTime).Seconds()
+			usage.CPU = float64(cpuTime-prevCPUTime) / ClockTicks / elapsed * 100
+		}
+		prevCPUTime, prevTime = cpuTime, usage.Time
+
+		bs.mu.Lock()
+		bs.usage = &usage
+		bs.mu.Unlock()
+		bs.logger.Debug("Process resource usage",
+			"pid", pid, "rssMB", usage.RSS>>20, "vmSizeMB", usage.VMSize>>20, "cpu", fmt.Sprintf("%.1f%%", usage.CPU), "threads", usage.Threads)
+	}
+}
+
+// sampleUsage reads the memory and threads of pid from /proc/<pid>/status,
+// and its CPU time, in clock ticks, from /proc/<pid>/stat.
+func sampleUsage(pid int) (Usage, uint64, error) {
+	usage := Usage{Time: time.Now()}
+
+	file, err := os.Open(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		return usage, 0, err
+	}
+	defer file.Close()
+
+	if err := parseProcStatus(file, &usage); err != nil {
+		return usage, 0, err
+	}
+
+	stat, err := readProcStat(pid)
+	if err != nil {
+		return usage, 0, err
+	}
+	return usage, stat.cpuTime, nil
+}
+
+// parseProcStatus reads the memory and threads of usage from the content of
+// /proc/<pid>/status.
+func parseProcStatus(r io.Reader, usage *Usage) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		name, value, found := strings.Cut(scanner.Text(), ":")
+		if !found {
+			continue
+		}
+		fields := strings.Fields(value)
+		if len(fields) == 0 {
+			continue
+		}
+		n, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		switch name {
+		case "VmRSS":
+			usage.RSS = n * 1024 // In kB
+		case "VmSize":
+			usage.VMSize = n * 1024
+		case "Threads":
+			usage.Threads = int(n)
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,43 @@
+package base
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseProcStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want Usage
+	}{
+		{
+			name: "process",
+			data: "Name:\tucc-bin\nState:\tS (sleeping)\nVmPeak:\t  812345 kB\nVmSize:\t  802816 kB\nVmRSS:\t  524288 kB\nThreads:\t9\nSigQ:\t0/63445\n",
+			want: Usage{RSS: 512 << 20, VMSize: 784 << 20, Threads: 9},
+		},
+		{
+			name: "kernel thread without memory",
+			data: "Name:\tkthreadd\nState:\tS (sleeping)\nThreads:\t1\n",
+			want: Usage{Threads: 1},
+		},
+		{
+			name: "malformed lines",
+			data: "VmRSS\t1024 kB\nVmSize:\nThreads:\tmany\nVmRSS:\t1 kB\n",
+			want: Usage{RSS: 1024},
+		},
+		{name: "empty", data: "", want: Usage{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Usage
+			if err := parseProcStatus(strings.NewReader(tt.data), &got); err != nil {
+				t.Fatalf("parseProcStatus() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("parseProcStatus() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,284 @@
+package kfserver
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/K4rian/kfdsl/internal/services/base"
+	"github.com/K4rian/kfdsl/internal/utils"
+)
+
+const (
+	consoleBufferLines = 500
+	corePatternFile    = "/proc/sys/kernel/core_pattern"
+	coreUsesPidFile    = "/proc/sys/kernel/core_uses_pid"
+)
+
+// consoleBuffer keeps the last lines printed by the server.
+type consoleBuffer struct {
+	mu    sync.Mutex
+	lines []string
+	next  int
+}
+
+func (b *consoleBuffer) add(line string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.lines) < consoleBufferLines {
+		b.lines = append(b.lines, line)
+		return
+	}
+	b.lines[b.next] = line
+	b.next = (b.next + 1) % consoleBufferLines
+}
+
+// reset empties the buffer and returns its lines, oldest first.
+func (b *consoleBuffer) reset() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	lines := append(b.lines[b.next:], b.lines[:b.next]...)
+	b.lines, b.next = nil, 0
+	return lines
+}
+
+// CrashReport describes a server crash.
+type CrashReport struct {
+	Time     time.Time   `json:"time"`
+	Service  string      `json:"service"`
+	Pid      int         `json:"pid"`
+	Pattern  string      `json:"pattern,omitempty"`
+	Line     string      `json:"line,omitempty"`
+	ExitCode int         `json:"exit_code"`
+	Signal   string      `json:"signal,omitempty"`
+	Map      string      `json:"map"`
+	Players  *int        `json:"players"` // Unknown if the server was never queried
+	CoreFile string      `json:"core_file,omitempty"`
+	Usage    *base.Usage `json:"usage,omitempty"` // Last resource usage sample
+}
+
+// CrashReportsDir returns the directory holding the crash reports of the
+// server installed in dir.
+func CrashReportsDir(dir string) string {
+	return filepath.Join(dir, ".kfdsl", "crashes")
+}
+
+// handleExit writes a crash report when the server crashed or hung, either
+// detected from its output or from an unrequested failed exit.
+func (s *KFServer) handleExit(state *os.ProcessState, requested bool) {
+	lines := s.console.reset()
+
+	s.stateMu.Lock()
+	pattern, line := s.crashPattern, s.crashLine
+	report := &CrashReport{
+		Time:    time.Now(),
+		Service: s.Name(),
+		Pattern: pattern,
+		Line:    line,
+		Map:     s.currentMap,
+		Players: s.players,
+	}
+	s.crashPattern, s.crashLine = "", ""
+	s.currentMap, s.players = "", nil
+	keep := s.settings.CrashReports.Value()
+	dir := CrashReportsDir(s.settings.ServerInstallDir.Value())
+	s.stateMu.Unlock()
+
+	crashed := pattern != "" || (!requested && state != nil && !state.Success())
+	if !crashed || keep == 0 {
+		return
+	}
+
+	if usage, ok := s.Usage(); ok {
+		report.Usage = &usage
+	}
+
+	var coreDumped bool
+	if state != nil {
+		report.Pid = state.Pid()
+		report.ExitCode = state.ExitCode()
+		if ws, ok := state.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+			report.Signal = ws.Signal().String()
+			coreDumped = ws.CoreDump()
+		}
+	}
+	if report.Map == "" {
+		report.Map = s.settings.StartupMap.Value()
+	}
+
+	// Don't delay the restart
+	go func() {
+		reportDir, err := writeCrashReport(dir, report, lines, coreDumped, s.Options().WorkingDirectory)
+		if err != nil {
+			s.Logger().Error("Failed to write the crash report", "dir", dir, "error", err)
+			return
+		}
+		s.Logger().Error("Crash report written",
+			"event", "crash-report",
+			"dir", reportDir,
+			"pattern", report.Pattern,
+			"exitCode", report.ExitCode,
+			"signal", report.Signal,
+			"map", report.Map,
+			"coreFile", report.CoreFile,
+		)
+		pruneCrashReports(dir, keep)
+	}()
+}
+
+// writeCrashReport writes report, the console lines and the core file, if
+// any, in a new directory of dir and returns its path.
+func writeCrashReport(dir string, report *CrashReport, lines []string, coreDumped bool, workingDir string) (string, error) {
+	name := report.Time.UTC().Format("20060102-150405") + "_" + strings.NewReplacer("[", "-", "]", "").Replace(report.Service)
+	reportDir, err := utils.CreateDirIfNotExists(dir, name)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.WriteFile(filepath.Join(reportDir, "console.log"), []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		return "", err
+	}
+
+	// UE2 prints the crash history after the error
+	if report.Line != "" {
+		for i := len(lines) - 1; i >= 0; i-- {
+			if lines[i] == report.Line {
+				block := strings.Join(lines[i:], "\n") + "\n"
+				if err := os.WriteFile(filepath.Join(reportDir, "crash.log"), []byte(block), 0644); err != nil {
+					return "", err
+				}
+				break
+			}
+		}
+	}
+
+	if coreDumped {
+		if coreFile := findCoreFile(workingDir, report.Pid); coreFile != "" {
+			dst := filepath.Join(reportDir, filepath.Base(coreFile))
+			if err := utils.MoveFile(coreFile, dst, ""); err != nil {
+				report.CoreFile = coreFile
+			} else {
+				report.CoreFile = dst
+			}
+		}
+	}
+
+	data, err := json.MarshalIndent(report, "", "    ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(reportDir, "report.json"), data, 0644); err != nil {
+		return "", err
+	}
+	return reportDir, nil
+}
+
+// findCoreFile returns the core file of pid written according to the kernel
+// core pattern, or an empty string if it can't be found.
+func findCoreFile(workingDir string, pid int) string {
+	data, err := os.ReadFile(corePatternFile)
+	if err != nil {
+		return ""
+	}
+	usesPid, _ := os.ReadFile(coreUsesPidFile)
+	return matchCoreFile(strings.TrimSpace(string(data)), strings.TrimSpace(string(usesPid)) == "1", workingDir, pid)
+}
+
+// matchCoreFile returns the last core file of pid matching the kernel core
+// pattern, usesPid telling whether the kernel adds the pid to the patterns
+// without one. It returns an empty string when there's none.
+func matchCoreFile(pattern string, usesPid bool, workingDir string, pid int) string {
+	// Piped to a helper (systemd-coredump, apport, ...)
+	if pattern == "" || strings.HasPrefix(pattern, "|") {
+		return ""
+	}
+
+	var b strings.Builder
+	hasPid := false
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] != '%' || i+1 == len(pattern) {
+			b.WriteByte(pattern[i])
+			continue
+		}
+		i++
+		switch pattern[i] {
+		case '%':
+			b.WriteByte('%')
+		case 'p', 'P':
+			b.WriteString(strconv.Itoa(pid))
+			hasPid = true
+		case 'e':
+			b.WriteString(filepath.Base(relExecutablePath))
+		default:
+			b.WriteByte('*')
+		}
+	}
+	path := b.String()
+	if !hasPid && usesPid {
+		path += "." + strconv.Itoa(pid)
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(workingDir, path)
+	}
+
+	matches, _ := filepath.Glob(path)
+	if len(matches) == 0 {
+		return ""
+	}
+	return matches[len(matches)-1]
+}
+
+// pruneCrashReports removes the oldest crash reports of dir beyond keep.
+func pruneCrashReports(dir string, keep int) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	var reports []string
+	for _, e := range entries {
+		if e.IsDir() {
+			reports = append(reports, e.Name())
+		}
+	}
+	if len(reports) <= keep {
+		return
+	}
+
+	// Names start with the crash time
+	sort.Strings(reports)
+	for _, name := range reports[:len(reports)-keep] {
+		os.RemoveAll(filepath.Join(dir, name))
+	}
+}
+
+// trackMap records the map being loaded from a server line.
+func (s *KFServer) trackMap(line string) bool {
+	_, url, found := strings.Cut(line, "LoadMap: ")
+	if !found {
+		return false
+	}
+	mapName, options, _ := strings.Cut(strings.TrimSpace(url), "?")
+	mapName = strings.TrimSuffix(filepath.Base(mapName), ".rom")
+	if mapName == "" {
+		return false
+	}
+
+	s.stateMu.Lock()
+	s.currentMap = mapName
+	s.mapOptions = options
+	s.stateMu.Unlock()
+	return false
+}
+
+// recordConsole keeps the line for the crash reports.
+func (s *KFServer) recordConsole(line string) bool {
+	s.console.add(line)
+	return false
+}
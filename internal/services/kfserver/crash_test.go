@@ -0,0 +1,135 @@
+package kfserver
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestConsoleBuffer(t *testing.T) {
+	lines := func(from, to int) []string {
+		var l []string
+		for i := from; i <= to; i++ {
+			l = append(l, fmt.Sprintf("line %d", i))
+		}
+		return l
+	}
+
+	tests := []struct {
+		name  string
+		added int
+		want  []string
+	}{
+		{"empty", 0, nil},
+		{"partly filled", 3, lines(1, 3)},
+		{"full", consoleBufferLines, lines(1, consoleBufferLines)},
+		{"wrapped", consoleBufferLines + 10, lines(11, consoleBufferLines+10)},
+		{"wrapped twice", 2*consoleBufferLines + 1, lines(consoleBufferLines+2, 2*consoleBufferLines+1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b consoleBuffer
+			for _, line := range lines(1, tt.added) {
+				b.add(line)
+			}
+			if got := b.reset(); !slices.Equal(got, tt.want) {
+				t.Errorf("reset() = %d lines from %q, want %d lines", len(got), got[:min(len(got), 1)], len(tt.want))
+			}
+			if got := b.reset(); len(got) != 0 {
+				t.Errorf("reset() after a reset = %d lines, want none", len(got))
+			}
+		})
+	}
+}
+
+func TestMatchCoreFile(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"core", "core.1234", "core.ucc-bin.1234.1700000000", "core.ucc-bin.1234.1800000000", "core.5678"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		pattern string
+		usesPid bool
+		pid     int
+		want    string // Relative to dir
+	}{
+		{"plain", "core", false, 1234, "core"},
+		{"uses pid", "core", true, 1234, "core.1234"},
+		{"pid in the pattern", "core.%p", true, 1234, "core.1234"},
+		{"absolute path", filepath.Join(dir, "core.%p"), false, 1234, "core.1234"},
+		{"executable and time", "core.%e.%p.%t", false, 1234, "core.ucc-bin.1234.1800000000"},
+		{"other pid", "core.%p", false, 4321, ""},
+		{"piped", "|/usr/lib/systemd/systemd-coredump %P %u %g %s %t %c %h", false, 1234, ""},
+		{"empty", "", false, 1234, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := tt.want
+			if want != "" {
+				want = filepath.Join(dir, want)
+			}
+			if got := matchCoreFile(tt.pattern, tt.usesPid, dir, tt.pid); got != want {
+				t.Errorf("matchCoreFile(%q, %v) = %q, want %q", tt.pattern, tt.usesPid, got, want)
+			}
+		})
+	}
+}
+
+func TestPruneCrashReports(t *testing.T) {
+	reports := []string{"20260101-120000_kf1", "20260102-120000_kf1", "20260103-120000_kf1"}
+
+	tests := []struct {
+		name string
+		keep int
+		want []string
+	}{
+		{"under the limit", 5, reports},
+		{"at the limit", 3, reports},
+		{"over the limit", 2, reports[1:]},
+		{"keep none", 0, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, name := range reports {
+				if err := os.Mkdir(filepath.Join(dir, name), 0755); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// Files aren't reports
+			if err := os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			pruneCrashReports(dir, tt.keep)
+
+			var got []string
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, e := range entries {
+				if e.IsDir() {
+					got = append(got, e.Name())
+				} else if e.Name() != "notes.txt" {
+					t.Errorf("unexpected file %s", e.Name())
+				}
+			}
+			if len(entries)-len(got) != 1 {
+				t.Error("pruneCrashReports() removed a file")
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("reports after pruneCrashReports(%d) = %q, want %q", tt.keep, got, tt.want)
+			}
+		})
+	}
+}
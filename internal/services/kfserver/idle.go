@@ -0,0 +1,123 @@
+package kfserver
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/K4rian/kfdsl/internal/services/base"
+)
+
+const idleInterval = 30 * time.Second
+
+// gameOptions are the map URL options changing the game, set by the map
+// vote game configs.
+var gameOptions = []string{"Difficulty", "GameLength"}
+
+// SetIdlePrepare registers a function to be called while the server is
+// stopped during an idle restart, e.g. to rewrite its configuration files.
+func (s *KFServer) SetIdlePrepare(fn func() error) {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+	s.idlePrepare = fn
+}
+
+// idleWatch queries the players count and restarts the server when it has
+// been empty for the idle restart time and up for the idle restart uptime,
+// or for the idle reset time while it isn't on its startup map. It returns
+// when ctx is done.
+func (s *KFServer) idleWatch(ctx context.Context) {
+	ticker := time.NewTicker(idleInterval)
+	defer ticker.Stop()
+
+	var pid int
+	var emptySince time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// The idle time starts over with each process
+		if current := s.Pid(); current != pid {
+			pid, emptySince = current, time.Time{}
+		}
+		if pid == 0 {
+			continue
+		}
+
+		now := time.Now()
+		players, err := s.PlayerCount()
+		if err != nil || players > 0 {
+			emptySince = time.Time{}
+			continue
+		}
+		if emptySince.IsZero() {
+			emptySince = now
+			continue
+		}
+
+		s.stateMu.RLock()
+		restartAfter := s.settings.IdleRestart.Value()
+		minUptime := s.settings.IdleRestartUptime.Value()
+		resetAfter := s.settings.IdleReset.Value()
+		prepare := s.idlePrepare
+		s.stateMu.RUnlock()
+
+		idle := now.Sub(emptySince)
+		uptime := now.Sub(s.StartTime())
+		switch {
+		case restartAfter > 0 && idle >= restartAfter && uptime >= minUptime:
+			s.Logger().Info("Restarting the idle server", "idle", idle.Round(time.Second), "uptime", uptime.Round(time.Second))
+		case resetAfter > 0 && idle >= resetAfter && !s.onStartupMap():
+			s.Logger().Info("Restarting the idle server on its startup map", "idle", idle.Round(time.Second), "map", s.CurrentMap())
+		default:
+			continue
+		}
+
+		s.restartIdle(pid, prepare)
+		emptySince = time.Time{}
+	}
+}
+
+// restartIdle restarts the process pid under the restart lock, unless it was
+// restarted or players joined while waiting for the lock.
+func (s *KFServer) restartIdle(pid int, prepare func() error) {
+	unlock, ok := s.lockRestart(pid)
+	defer unlock()
+	if !ok || !s.isEmpty() {
+		s.Logger().Info("The server was restarted or players joined meanwhile, skipping the idle restart")
+		return
+	}
+	if err := s.RestartWith(base.RestartIdle, prepare); err != nil {
+		s.Logger().Error("Failed to restart the idle server", "error", err)
+	}
+}
+
+// onStartupMap reports whether the server runs its startup map and game
+// mode, without the game options of a map vote. It's assumed until the map
+// is known.
+func (s *KFServer) onStartupMap() bool {
+	s.stateMu.RLock()
+	defer s.stateMu.RUnlock()
+
+	if s.currentMap == "" {
+		return true
+	}
+	if !strings.EqualFold(s.currentMap, s.settings.StartupMap.Value()) {
+		return false
+	}
+	for _, option := range strings.Split(s.mapOptions, "?") {
+		key, value, _ := strings.Cut(option, "=")
+		if strings.EqualFold(key, "game") && !strings.EqualFold(value, s.settings.GameMode.Value()) {
+			return false
+		}
+		for _, name := range gameOptions {
+			if strings.EqualFold(key, name) {
+				return false
+			}
+		}
+	}
+	return true
+}
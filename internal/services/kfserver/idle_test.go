@@ -0,0 +1,45 @@
+package kfserver
+
+import (
+	"testing"
+
+	"github.com/K4rian/kfdsl/internal/arguments"
+	"github.com/K4rian/kfdsl/internal/settings"
+)
+
+func TestOnStartupMap(t *testing.T) {
+	sett := &settings.Settings{
+		StartupMap: arguments.New("Startup Map", "KF-BioticsLab", nil, nil, false),
+		GameMode:   arguments.New("Game Mode", "KFmod.KFGameType", nil, nil, false),
+	}
+	if err := sett.Parse(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		line string // Server line loading the map, none if empty
+		want bool
+	}{
+		{"unknown map", "", true},
+		{"startup map", "LoadMap: KF-BioticsLab", true},
+		{"case insensitive", "LoadMap: kf-bioticslab.rom?game=kfmod.kfgametype", true},
+		{"startup game mode", "LoadMap: KF-BioticsLab?game=KFmod.KFGameType?Mutator=MutLoader.MutLoader", true},
+		{"other map", "LoadMap: KF-Farm?game=KFmod.KFGameType", false},
+		{"other game mode", "LoadMap: KF-BioticsLab?game=KFStoryGame.KFStoryGameInfo", false},
+		{"difficulty vote", "LoadMap: KF-BioticsLab?game=KFmod.KFGameType?Difficulty=7", false},
+		{"length vote", "LoadMap: KF-BioticsLab?GameLength=2", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &KFServer{settings: sett}
+			if tt.line != "" {
+				s.trackMap(tt.line)
+			}
+			if got := s.onStartupMap(); got != tt.want {
+				t.Errorf("onStartupMap() on %q = %v, want %v", tt.line, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package kfserver
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// QueryInfo sends a GameSpy \info\ query to addr (the GameSpy port of the
+// server) and returns the key/values of the reply.
+func QueryInfo(addr string, timeout time.Duration) (map[string]string, error) {
+	conn, err := net.DialTimeout("udp", addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write([]byte(`\info\`)); err != nil {
+		return nil, err
+	}
+
+	// The reply can be split in several packets, the last one ends with \final\
+	var reply strings.Builder
+	buf := make([]byte, 2048)
+	for !strings.Contains(reply.String(), `\final\`) {
+		n, err := conn.Read(buf)
+		if err != nil {
+			if reply.Len() > 0 {
+				break
+			}
+			return nil, err
+		}
+		reply.Write(buf[:n])
+	}
+	return parseQueryReply(reply.String()), nil
+}
+
+// parseQueryReply parses a \key\value\key\value\ GameSpy reply.
+func parseQueryReply(reply string) map[string]string {
+	values := map[string]string{}
+	parts := strings.Split(strings.Trim(reply, `\`), `\`)
+	for i := 0; i+1 < len(parts); i += 2 {
+		key := strings.ToLower(parts[i])
+		if key == "final" || key == "queryid" {
+			continue
+		}
+		values[key] = parts[i+1]
+	}
+	return values
+}
+
+// QueryPlayerCount returns the number of players connected to the server
+// answering GameSpy queries on addr.
+func QueryPlayerCount(addr string, timeout time.Duration) (int, error) {
+	info, err := QueryInfo(addr, timeout)
+	if err != nil {
+		return 0, err
+	}
+
+	numPlayers, ok := info["numplayers"]
+	if !ok {
+		return 0, fmt.Errorf("invalid query reply from %s: numplayers is missing", addr)
+	}
+	return strconv.Atoi(numPlayers)
+}
+
+// QueryWave sends an Unreal Engine 2 server info query to addr (the query
+// port of the server, game port + 1) and returns the current and final
+// waves, which Killing Floor appends to the reply.
+func QueryWave(addr string, timeout time.Duration) (int, int, error) {
+	conn, err := net.DialTimeout("udp", addr, timeout)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return 0, 0, err
+	}
+	if _, err := conn.Write([]byte{0x79, 0, 0, 0, 0}); err != nil {
+		return 0, 0, err
+	}
+
+	buf := make([]byte, 2048)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return 0, 0, err
+	}
+	return parseWaveReply(buf[:n])
+}
+
+// parseWaveReply reads the waves of a server info reply: a header and the
+// query type, then the server ID, IP address, game port, query port, name,
+// map, game type, players, max players, current wave and final wave.
+func parseWaveReply(reply []byte) (int, int, error) {
+	r := &replyReader{data: reply}
+	r.skip(5)
+	r.uint32()
+	r.string()
+	r.uint32()
+	r.uint32()
+	r.string()
+	r.string()
+	r.string()
+	r.uint32()
+	r.uint32()
+	wave := r.uint32()
+	finalWave := r.uint32()
+	if r.short {
+		return 0, 0, fmt.Errorf("invalid server info reply: no wave")
+	}
+	return int(wave), int(finalWave), nil
+}
+
+// replyReader reads the fields of an Unreal Engine 2 query reply. short is
+// set when a field goes beyond the reply.
+type replyReader struct {
+	data  []byte
+	pos   int
+	short bool
+}
+
+func (r *replyReader) skip(n int) {
+	if r.pos+n > len(r.data) {
+		r.short = true
+		r.pos = len(r.data)
+		return
+	}
+	r.pos += n
+}
+
+func (r *replyReader) uint32() uint32 {
+	start := r.pos
+	r.skip(4)
+	if r.short {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(r.data[start:r.pos])
+}
+
+// string skips a string: its length, with the high bit set for UCS-2
+// strings, then its characters.
+func (r *replyReader) string() {
+	start := r.pos
+	r.skip(1)
+	if r.short {
+		return
+	}
+	length := int(r.data[start])
+	if length >= 0x80 {
+		length = (length & 0x7f) * 2
+	}
+	r.skip(length)
+}
@@ -3,9 +3,14 @@ package kfserver
 import (
 	"context"
 	"fmt"
+	"net"
+	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
+	"syscall"
+	"time"
 
 	"github.com/K4rian/kfdsl/internal/services/base"
 	"github.com/K4rian/kfdsl/internal/settings"
@@ -14,14 +19,24 @@ import (
 
 type KFServer struct {
 	*base.BaseService
-	settings   *settings.Settings
-	executable string
-	ready      bool
-	stateMu    sync.RWMutex
+	settings     *settings.Settings
+	executable   string
+	ready        bool
+	console      consoleBuffer
+	crashPattern string // Crash pattern matched by the running process
+	crashLine    string
+	currentMap   string
+	mapOptions   string // URL options of the current map
+	idlePrepare  func() error
+	restartLock  sync.Locker // Held by the idle and usage restarts
+	players      *int        // Players connected at the last answered query
+	wave         waveTracker
+	stateMu      sync.RWMutex
 }
 
 const (
 	relExecutablePath = "System/ucc-bin"
+	queryTimeout      = 2 * time.Second
 )
 
 // UE2 patterns that indicate a fatal crash
@@ -35,12 +50,30 @@ var crashPatterns = []string{
 }
 
 func New(ctx context.Context, sett *settings.Settings) *KFServer {
+	return newServer(ctx, "KFServer", sett)
+}
+
+// NewInstance creates the server of a named instance.
+// The instance name tags the server log lines.
+func NewInstance(ctx context.Context, name string, sett *settings.Settings) *KFServer {
+	return newServer(ctx, fmt.Sprintf("KFServer[%s]", name), sett)
+}
+
+func newServer(ctx context.Context, name string, sett *settings.Settings) *KFServer {
 	rootDir := sett.ServerInstallDir.Value()
 	executable := filepath.Join(rootDir, relExecutablePath)
 	workingDir := filepath.Dir(executable)
 
+	// Validated when parsing the settings
+	cpuAffinity, _ := utils.ParseCPUList(sett.CPUAffinity.Value())
+	var credential *syscall.Credential
+	home := os.Getenv("HOME")
+	if runAs := sett.RunAs.Value(); runAs != "" {
+		credential, home, _ = utils.LookupCredential(runAs)
+	}
+
 	kfs := &KFServer{
-		BaseService: base.NewBaseService("KFServer", ctx, base.ServiceOptions{
+		BaseService: base.NewBaseService(name, ctx, base.ServiceOptions{
 			RootDirectory:    rootDir,
 			WorkingDirectory: workingDir,
 			AutoRestart:      sett.AutoRestart.Value(),
@@ -48,14 +81,65 @@ func New(ctx context.Context, sett *settings.Settings) *KFServer {
 			RestartDelay:     sett.RestartDelay.Value(),
 			ShutdownTimeout:  sett.ShutdownTimeout.Value(),
 			KillTimeout:      sett.KillTimeout.Value(),
+			CoreDumps:        sett.CrashCoreDump.Value(),
+			Nice:             sett.Nice.Value(),
+			CPUAffinity:      cpuAffinity,
+			MaxOpenFiles:     uint64(sett.LimitNoFile.Value()),
+			MaxAddressSpace:  uint64(sett.LimitAS.Value()) * 1024 * 1024,
+			OOMScoreAdj:      sett.OOMScoreAdj.Value(),
+			Credential:       credential,
+			Env:              serverEnv(sett.EnvAllow.Value(), home),
+			NoNewPrivs:       sett.NoNewPrivs.Value(),
+			UsageInterval:    sett.UsageInterval.Value(),
 		}),
 		settings:   sett,
 		executable: executable,
 	}
+	kfs.AddLogHandler(kfs.recordConsole)
+	kfs.AddLogHandler(kfs.trackMap)
 	kfs.AddLogHandler(kfs.handleCrash)
+	kfs.SetExitHook(kfs.handleExit)
+	kfs.SetPreRestartHook(func() { kfs.setReady(false) })
+
+	// Hung servers are restarted like crashed ones
+	if sett.AutoRestart.Value() && sett.HangTimeout.Value() > 0 {
+		go kfs.watchdog(ctx, sett.HangTimeout.Value())
+	}
+	if sett.IdleRestart.Value() > 0 || sett.IdleReset.Value() > 0 {
+		go kfs.idleWatch(ctx)
+	}
+	if sett.ShutdownWaitWave.Value() {
+		go kfs.watchWave(ctx)
+	}
+	if interval := sett.UsageInterval.Value(); interval > 0 && len(sett.UsageRules.Value()) > 0 {
+		go kfs.watchUsage(ctx, interval, sett.UsageRules.Value())
+	}
 	return kfs
 }
 
+// SetRestartLock registers the lock held while the server restarts when
+// idle or for a usage rule, so these restarts don't race the launcher ones.
+func (s *KFServer) SetRestartLock(lock sync.Locker) {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+	s.restartLock = lock
+}
+
+// lockRestart takes the restart lock, if any, and reports whether the
+// process pid still runs: the launcher may have restarted the server
+// meanwhile. unlock releases the lock.
+func (s *KFServer) lockRestart(pid int) (unlock func(), ok bool) {
+	s.stateMu.RLock()
+	lock := s.restartLock
+	s.stateMu.RUnlock()
+
+	if lock == nil {
+		return func() {}, s.Pid() == pid
+	}
+	lock.Lock()
+	return lock.Unlock, s.Pid() == pid
+}
+
 func (s *KFServer) Start() error {
 	if err := s.BaseService.Start(s.buildCommandLine()); err != nil {
 		return err
@@ -68,6 +152,79 @@ func (s *KFServer) Stop() error {
 	return s.BaseService.Stop()
 }
 
+// Reload restarts the server with new settings. prepare is called while
+// the server is stopped, the previous settings are kept if it fails.
+func (s *KFServer) Reload(sett *settings.Settings, prepare func() error) error {
+	s.stateMu.Lock()
+	prevSettings := s.settings
+	s.settings = sett
+	args := s.buildCommandLine()
+	s.stateMu.Unlock()
+
+	err := s.BaseService.Reload(args, prepare)
+	if err != nil {
+		s.stateMu.Lock()
+		s.settings = prevSettings
+		s.stateMu.Unlock()
+	}
+	return err
+}
+
+// ReloadMap swaps the settings of the running server, sends the commands,
+// then restarts the current map so the game uses the new values. Only the
+// settings written in the configuration files can differ, the command-line
+// is left as-is.
+func (s *KFServer) ReloadMap(sett *settings.Settings, commands []string) error {
+	s.stateMu.Lock()
+	prevSettings := s.settings
+	s.settings = sett
+	mapName := s.currentMap
+	if mapName == "" {
+		mapName = sett.StartupMap.Value()
+	}
+	s.stateMu.Unlock()
+
+	for _, command := range commands {
+		if err := s.SendCommand(command); err != nil {
+			s.stateMu.Lock()
+			s.settings = prevSettings
+			s.stateMu.Unlock()
+			return err
+		}
+	}
+	return s.ChangeMap(mapName)
+}
+
+// PlayerCount queries the server for the number of connected players.
+func (s *KFServer) PlayerCount() (int, error) {
+	s.stateMu.RLock()
+	addr := s.queryAddr()
+	s.stateMu.RUnlock()
+
+	players, err := QueryPlayerCount(addr, queryTimeout)
+	if err == nil {
+		s.recordPlayers(players)
+	}
+	return players, err
+}
+
+// recordPlayers records the players count of the last answered query.
+func (s *KFServer) recordPlayers(players int) {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+	s.players = &players
+}
+
+// queryAddr returns the address of the server GameSpy port.
+// The caller must hold stateMu.
+func (s *KFServer) queryAddr() string {
+	host := "127.0.0.1"
+	if ip := s.settings.IP.Value(); ip != "" {
+		host = ip
+	}
+	return net.JoinHostPort(host, strconv.Itoa(s.settings.GameSpyPort.Value()))
+}
+
 // IsInstalled returns true when the server executable is present on disk.
 func (s *KFServer) IsInstalled() bool {
 	return utils.FileExists(s.executable)
@@ -80,6 +237,24 @@ func (s *KFServer) IsReady() bool {
 	return s.ready
 }
 
+// CurrentMap returns the map loaded by the server, empty until known.
+func (s *KFServer) CurrentMap() string {
+	s.stateMu.RLock()
+	defer s.stateMu.RUnlock()
+	return s.currentMap
+}
+
+// ChangeMap travels the server to mapName, keeping the game mode. The players
+// travel along.
+func (s *KFServer) ChangeMap(mapName string) error {
+	s.stateMu.RLock()
+	url := mapName + "?game=" + s.settings.GameMode.Value()
+	s.stateMu.RUnlock()
+
+	s.Logger().Info("Changing the map", "map", mapName)
+	return s.SendCommand("servertravel " + url)
+}
+
 func (s *KFServer) buildCommandLine() []string {
 	var argsBuilder strings.Builder
 
@@ -111,6 +286,11 @@ func (s *KFServer) buildCommandLine() []string {
 		"-nohomedir",
 	}
 
+	// Bind to a single address
+	if ip := s.settings.IP.Value(); ip != "" {
+		args = append(args, "multihome="+ip)
+	}
+
 	// Extra arguments
 	extraArgs := s.settings.ExtraArgs
 	if len(extraArgs) > 0 {
@@ -119,11 +299,53 @@ func (s *KFServer) buildCommandLine() []string {
 	return args
 }
 
+// serverEnv returns the launcher environment variables matching allowList, a
+// comma-separated list of names which may end with *, or all of them when
+// allowList is empty. HOME is set to home. The launcher settings, passwords
+// included, and the Steam credentials are never passed to the server.
+func serverEnv(allowList string, home string) []string {
+	var patterns []string
+	for _, name := range strings.Split(allowList, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			patterns = append(patterns, name)
+		}
+	}
+	allowed := func(name string) bool {
+		if len(patterns) == 0 {
+			return true
+		}
+		for _, pattern := range patterns {
+			if prefix, wildcard := strings.CutSuffix(pattern, "*"); wildcard && strings.HasPrefix(name, prefix) || name == pattern {
+				return true
+			}
+		}
+		return false
+	}
+
+	env := []string{}
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		if name == "HOME" || strings.HasPrefix(name, "KF_") || strings.HasPrefix(name, "STEAMACC_") || !allowed(name) {
+			continue
+		}
+		env = append(env, kv)
+	}
+	if home != "" && allowed("HOME") {
+		env = append(env, "HOME="+home)
+	}
+	return env
+}
+
 func (s *KFServer) handleCrash(line string) bool {
 	for _, pattern := range crashPatterns {
 		if strings.Contains(line, pattern) {
 			s.Logger().Error("Crash detected", "pattern", pattern, "line", line)
-			s.setReady(false)
+			s.stateMu.Lock()
+			s.ready = false
+			if s.crashPattern == "" {
+				s.crashPattern, s.crashLine = pattern, line
+			}
+			s.stateMu.Unlock()
 			return true
 		}
 	}
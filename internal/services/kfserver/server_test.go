@@ -0,0 +1,61 @@
+package kfserver
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/K4rian/kfdsl/internal/settings"
+)
+
+func TestServerEnv(t *testing.T) {
+	t.Setenv("KFDSL_TEST_A", "1")
+	t.Setenv("KFDSL_TEST_B", "2")
+	t.Setenv("XKFDSL_TEST", "3")
+	t.Setenv("STEAMACC_USERNAME", "user")
+	t.Setenv("STEAMACC_PASSWORD", "secret")
+	t.Setenv("KF_ADMINPASSWORD", "secret")
+	t.Setenv("KF_API_TOKEN", "secret")
+	t.Setenv("HOME", "/root")
+
+	tests := []struct {
+		name      string
+		allowList string
+		home      string
+		want      []string
+	}{
+		{"all", "", "/home/kf", []string{"KFDSL_TEST_A=1", "KFDSL_TEST_B=2", "XKFDSL_TEST=3", "HOME=/home/kf"}},
+		{"all without home", "", "", []string{"KFDSL_TEST_A=1", "KFDSL_TEST_B=2", "XKFDSL_TEST=3"}},
+		{"single name", "KFDSL_TEST_A", "/home/kf", []string{"KFDSL_TEST_A=1"}},
+		{"wildcard and home", "KFDSL_TEST_*,HOME", "/home/kf", []string{"KFDSL_TEST_A=1", "KFDSL_TEST_B=2", "HOME=/home/kf"}},
+		{"spaces and empty names", " KFDSL_TEST_A , ,XKFDSL_TEST", "", []string{"KFDSL_TEST_A=1", "XKFDSL_TEST=3"}},
+		{"no partial match", "KFDSL_TEST", "/home/kf", nil},
+		{"case sensitive", "kfdsl_test_a", "/home/kf", nil},
+		{"wildcard", "*", "", []string{"KFDSL_TEST_A=1", "KFDSL_TEST_B=2", "XKFDSL_TEST=3"}},
+		{"default", settings.DefaultEnvAllow, "/home/kf", []string{"HOME=/home/kf"}},
+		{"steam credentials", "STEAMACC_*,STEAMACC_PASSWORD", "/home/kf", nil},
+		{"launcher settings", "KF_*,KF_ADMINPASSWORD", "/home/kf", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, kv := range serverEnv(tt.allowList, tt.home) {
+				name, _, _ := strings.Cut(kv, "=")
+				if strings.HasPrefix(name, "STEAMACC_") || strings.HasPrefix(name, "KF_") {
+					t.Errorf("serverEnv() passed a secret: %s", name)
+				}
+				if strings.Contains(name, "KFDSL_TEST") || name == "HOME" {
+					got = append(got, kv)
+				}
+			}
+			// The order of the environment isn't specified
+			want := slices.Clone(tt.want)
+			slices.Sort(got)
+			slices.Sort(want)
+			if !slices.Equal(got, want) {
+				t.Errorf("serverEnv(%q, %q) = %q, want %q", tt.allowList, tt.home, got, tt.want)
+			}
+		})
+	}
+}
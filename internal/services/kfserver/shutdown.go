@@ -0,0 +1,206 @@
+package kfserver
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/K4rian/kfdsl/internal/utils"
+)
+
+const wavePollInterval = 5 * time.Second
+
+// Shutdown warns the players with Countdown, then stops the server.
+func (s *KFServer) Shutdown(ctx context.Context) error {
+	s.Countdown(ctx, "shutting down")
+	return s.Stop()
+}
+
+// Countdown warns the players that the server is about to be stopped, e.g.
+// "Server restarting in 1m", at each of the shutdown warnings. It can first
+// wait for the end of the current wave. It returns when the server can be
+// stopped: once the countdown is over, the server is empty, the shutdown
+// grace period is elapsed or ctx is done.
+func (s *KFServer) Countdown(ctx context.Context, action string) {
+	s.stateMu.RLock()
+	warnings, _ := utils.ParseDurationList(s.settings.ShutdownWarnings.Value()) // Validated when parsing the settings
+	waitWave := s.settings.ShutdownWaitWave.Value()
+	grace := s.settings.ShutdownGrace.Value()
+	waveAddr := s.waveQueryAddr()
+	traderTime := s.wave.inTraderTime(time.Now(), time.Duration(s.settings.TimeBetweenWaves.Value())*time.Second)
+	s.stateMu.RUnlock()
+
+	if (len(warnings) == 0 && !waitWave) || grace == 0 || s.isEmpty() {
+		return
+	}
+
+	// Longest warning first
+	slices.Sort(warnings)
+	slices.Reverse(warnings)
+
+	deadline := time.Now().Add(grace)
+	var countdown time.Duration
+	if len(warnings) > 0 {
+		countdown = min(warnings[0], grace)
+	}
+
+	if waitWave {
+		// The next wave would start before the end of the countdown
+		if traderTime {
+			s.Logger().Info("Trader time, stopping the server without waiting for the next wave")
+			s.say(fmt.Sprintf("Server %s now", action))
+			return
+		}
+		if !s.waitWaveEnd(ctx, action, waveAddr, deadline.Add(-countdown)) {
+			return
+		}
+	}
+
+	start := time.Now()
+	end := start.Add(min(countdown, deadline.Sub(start)))
+	for _, warning := range warnings {
+		if warning > end.Sub(start) {
+			continue
+		}
+		if !sleepUntil(ctx, end.Add(-warning)) || s.isEmpty() {
+			return
+		}
+		s.say(fmt.Sprintf("Server %s in %s", action, formatCountdown(warning)))
+	}
+	sleepUntil(ctx, end)
+}
+
+// waitWaveEnd waits until the wave number changes, which happens when the
+// trader time starts. It returns false when the server is empty or ctx is
+// done meanwhile.
+func (s *KFServer) waitWaveEnd(ctx context.Context, action string, addr string, deadline time.Time) bool {
+	wave, _, err := QueryWave(addr, queryTimeout)
+	if err != nil {
+		s.Logger().Warn("Unable to get the current wave, not waiting for its end", "error", err)
+		return true
+	}
+
+	s.Logger().Info("Waiting for the end of the wave before stopping the server", "wave", wave)
+	s.say(fmt.Sprintf("Server %s at the end of the wave", action))
+	for time.Now().Before(deadline) {
+		next := time.Now().Add(wavePollInterval)
+		if next.After(deadline) {
+			next = deadline
+		}
+		if !sleepUntil(ctx, next) || s.isEmpty() {
+			return false
+		}
+		current, _, err := QueryWave(addr, queryTimeout)
+		if err == nil && current != wave {
+			return true
+		}
+	}
+	s.Logger().Info("Shutdown grace period elapsed before the end of the wave", "wave", wave)
+	return true
+}
+
+// waveTracker records when the wave number last changed. Killing Floor moves
+// to the next wave number when the trader time starts.
+type waveTracker struct {
+	wave    int
+	known   bool      // A wave was read
+	changed time.Time // Last change, zero until one is seen
+}
+
+// update records the wave read at now.
+func (w *waveTracker) update(wave int, now time.Time) {
+	if w.known && wave != w.wave {
+		w.changed = now
+	}
+	w.wave, w.known = wave, true
+}
+
+// inTraderTime reports whether the trader time, lasting traderTime, is
+// running at now. It's unknown, and false, until a wave change is seen.
+func (w *waveTracker) inTraderTime(now time.Time, traderTime time.Duration) bool {
+	return !w.changed.IsZero() && now.Sub(w.changed) < traderTime
+}
+
+// watchWave reads the current wave every wavePollInterval, so a shutdown can
+// tell the trader time apart from a wave. It returns when ctx is done.
+func (s *KFServer) watchWave(ctx context.Context) {
+	ticker := time.NewTicker(wavePollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if !s.IsRunning() {
+			continue
+		}
+
+		s.stateMu.RLock()
+		addr := s.waveQueryAddr()
+		s.stateMu.RUnlock()
+
+		wave, _, err := QueryWave(addr, queryTimeout)
+		if err != nil {
+			continue
+		}
+		s.stateMu.Lock()
+		s.wave.update(wave, time.Now())
+		s.stateMu.Unlock()
+	}
+}
+
+// isEmpty returns true when no player is connected. A server that can't be
+// queried is considered in use.
+func (s *KFServer) isEmpty() bool {
+	players, err := s.PlayerCount()
+	return err == nil && players == 0
+}
+
+// say broadcasts message to the players.
+func (s *KFServer) say(message string) {
+	s.Logger().Info("Broadcasting a message", "message", message)
+	if err := s.SendCommand("say " + message); err != nil {
+		s.Logger().Warn("Failed to broadcast a message", "message", message, "error", err)
+	}
+}
+
+// waveQueryAddr returns the address of the server query port.
+// The caller must hold stateMu.
+func (s *KFServer) waveQueryAddr() string {
+	host := "127.0.0.1"
+	if ip := s.settings.IP.Value(); ip != "" {
+		host = ip
+	}
+	return net.JoinHostPort(host, strconv.Itoa(s.settings.GamePort.Value()+1))
+}
+
+// formatCountdown formats d without its zero units, e.g. 5m instead of 5m0s.
+func formatCountdown(d time.Duration) string {
+	str := d.Round(time.Second).String()
+	if strings.HasSuffix(str, "m0s") {
+		str = strings.TrimSuffix(str, "0s")
+	}
+	if strings.HasSuffix(str, "h0m") {
+		str = strings.TrimSuffix(str, "0m")
+	}
+	return str
+}
+
+// sleepUntil waits until t. It returns false if ctx is done meanwhile.
+func sleepUntil(ctx context.Context, t time.Time) bool {
+	timer := time.NewTimer(time.Until(t))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
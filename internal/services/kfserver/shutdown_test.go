@@ -0,0 +1,68 @@
+package kfserver
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormatCountdown(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "0s"},
+		{10 * time.Second, "10s"},
+		{1400 * time.Millisecond, "1s"},
+		{1500 * time.Millisecond, "2s"},
+		{5 * time.Minute, "5m"},
+		{10 * time.Minute, "10m"},
+		{90 * time.Second, "1m30s"},
+		{70 * time.Second, "1m10s"},
+		{time.Hour, "1h"},
+		{90 * time.Minute, "1h30m"},
+		{time.Hour + 30*time.Second, "1h0m30s"},
+		{2*time.Hour + 10*time.Minute, "2h10m"},
+		{5*time.Minute + 200*time.Millisecond, "5m"},
+	}
+
+	for _, tt := range tests {
+		if got := formatCountdown(tt.d); got != tt.want {
+			t.Errorf("formatCountdown(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestWaveTracker(t *testing.T) {
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(secs int) time.Time { return start.Add(time.Duration(secs) * time.Second) }
+
+	type read struct {
+		wave int
+		at   time.Time
+	}
+	tests := []struct {
+		name  string
+		reads []read
+		now   time.Time
+		want  bool
+	}{
+		{"nothing read", nil, at(0), false},
+		{"first read", []read{{3, at(0)}}, at(1), false},
+		{"same wave", []read{{3, at(0)}, {3, at(5)}}, at(6), false},
+		{"wave changed", []read{{3, at(0)}, {4, at(5)}}, at(10), true},
+		{"trader time over", []read{{3, at(0)}, {4, at(5)}, {4, at(60)}}, at(65), false},
+		{"last change counts", []read{{3, at(0)}, {4, at(5)}, {1, at(100)}}, at(110), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var w waveTracker
+			for _, r := range tt.reads {
+				w.update(r.wave, r.at)
+			}
+			if got := w.inTraderTime(tt.now, time.Minute); got != tt.want {
+				t.Errorf("inTraderTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package kfserver
+
+import (
+	"context"
+	"time"
+
+	"github.com/K4rian/kfdsl/internal/monitor"
+	"github.com/K4rian/kfdsl/internal/services/base"
+)
+
+const emptyPollInterval = 15 * time.Second
+
+// usageState is the state of a resource usage rule.
+type usageState struct {
+	since     time.Time // First sample above the threshold, zero if below
+	triggered bool      // The action ran since the metric went above the threshold
+}
+
+// watchUsage checks the resource usage samples of the server against the
+// usage rules, and runs the action of a rule when its metric stays above the
+// threshold for its duration. It returns when ctx is done.
+func (s *KFServer) watchUsage(ctx context.Context, interval time.Duration, rules monitor.Rules) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var pid int
+	var last time.Time
+	states := make([]usageState, len(rules))
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// The rules start over with each process
+		if current := s.Pid(); current != pid {
+			pid = current
+			clear(states)
+		}
+		usage, ok := s.Usage()
+		if pid == 0 || !ok || !usage.Time.After(last) {
+			continue
+		}
+		last = usage.Time
+
+		for i, rule := range rules {
+			value := usageValue(usage, rule.Metric)
+			state := &states[i]
+			if value <= rule.Above {
+				*state = usageState{}
+				continue
+			}
+			if state.since.IsZero() {
+				state.since = usage.Time
+			}
+			if state.triggered || usage.Time.Sub(state.since) < rule.For {
+				continue
+			}
+			state.triggered = true
+
+			s.Logger().Warn("Resource usage threshold exceeded",
+				"rule", rule.Name, "threshold", rule.String(), "value", value, "action", rule.Action)
+			if rule.Action == monitor.ActionRestart {
+				// Tried again on the next sample when skipped
+				if !s.restartForUsage(ctx, rule, pid) {
+					state.triggered = false
+				}
+				break
+			}
+		}
+	}
+}
+
+// restartForUsage waits up to the rule defer_until_empty for the server to be
+// empty, then restarts the process pid once the players are warned. It
+// returns false when the restart is skipped: players are still connected and
+// the rule restarts only if empty.
+func (s *KFServer) restartForUsage(ctx context.Context, rule monitor.Rule, pid int) bool {
+	deadline := time.Now().Add(rule.DeferUntilEmpty)
+	for !s.isEmpty() && time.Now().Before(deadline) {
+		next := time.Now().Add(emptyPollInterval)
+		if next.After(deadline) {
+			next = deadline
+		}
+		if !sleepUntil(ctx, next) {
+			return true
+		}
+	}
+	if rule.OnlyIfEmpty && !s.isEmpty() {
+		s.Logger().Info("Players connected, skipping the restart", "rule", rule.Name)
+		return false
+	}
+
+	unlock, ok := s.lockRestart(pid)
+	defer unlock()
+	if !ok {
+		s.Logger().Info("The server was restarted meanwhile, skipping the restart", "rule", rule.Name)
+		return true
+	}
+
+	s.Countdown(ctx, "restarting")
+	if err := s.RestartWith(base.RestartUsage, nil); err != nil {
+		s.Logger().Error("Failed to restart the server", "rule", rule.Name, "error", err)
+	}
+	return true
+}
+
+// usageValue returns the value of metric in usage, in the unit of the rules.
+func usageValue(usage base.Usage, metric monitor.Metric) float64 {
+	switch metric {
+	case monitor.MetricRSS:
+		return float64(usage.RSS) / (1 << 20)
+	case monitor.MetricVM:
+		return float64(usage.VMSize) / (1 << 20)
+	case monitor.MetricCPU:
+		return usage.CPU
+	case monitor.MetricThreads:
+		return float64(usage.Threads)
+	}
+	return 0
+}
@@ -0,0 +1,184 @@
+package kfserver
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/K4rian/kfdsl/internal/services/base"
+)
+
+const (
+	watchdogInterval = 15 * time.Second
+	spinThreshold    = 0.95 // CPU usage of a thread stuck in a busy loop
+)
+
+// watchdogState is the state of the watched process.
+type watchdogState struct {
+	pid        int
+	armed      bool      // The server answered a query since it started
+	lastAnswer time.Time // Last answered query
+	players    int       // Players connected at the last answered query
+	cpuTime    uint64    // CPU time at the last check
+	cpuSampled time.Time // Time of the last CPU sample
+	warned     bool      // The server is unresponsive but still making progress
+}
+
+// watchdog checks that the server still answers the queries and restarts it
+// when it's judged hung. It returns when ctx is done.
+func (s *KFServer) watchdog(ctx context.Context, timeout time.Duration) {
+	ticker := time.NewTicker(watchdogInterval)
+	defer ticker.Stop()
+
+	state := &watchdogState{}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		pid := s.Pid()
+		if pid == 0 {
+			continue
+		}
+		// New process, wait for it to answer before arming the watchdog
+		if pid != state.pid {
+			*state = watchdogState{pid: pid}
+		}
+
+		if s.checkHang(state, timeout) {
+			*state = watchdogState{}
+			go func() {
+				if err := s.RestartFor(base.RestartHang); err != nil {
+					s.Logger().Error("Failed to restart the hung server", "error", err)
+				}
+			}()
+		}
+	}
+}
+
+// watchdogSample is what the watchdog reads of the server at each check.
+type watchdogSample struct {
+	now        time.Time
+	cpuTime    uint64    // CPU time of the process
+	answered   bool      // The server answered the query
+	players    int       // Players connected, if answered
+	lastOutput time.Time // Last line printed by the server
+}
+
+// hangCheck is the outcome of a watchdog check.
+type hangCheck struct {
+	hung         bool
+	warn         bool // First check of an unresponsive server still making progress
+	armed        bool // First answer of the process
+	recovered    bool // The server answers again after a warning
+	unresponsive time.Duration
+	silence      time.Duration
+	cpuUsage     float64 // Since the last check, 1 being a full core
+	stalled      bool    // The CPU time doesn't progress
+	spinning     bool    // A thread spins at 100%
+}
+
+// check updates the state with sample and judges the server. The server is
+// hung when it hasn't answered the queries for timeout and either its CPU
+// time doesn't progress, it spins at 100%, or it's silent while players are
+// connected.
+func (st *watchdogState) check(sample watchdogSample, timeout time.Duration) hangCheck {
+	var c hangCheck
+
+	// CPU usage since the last check
+	sampled := !st.cpuSampled.IsZero()
+	if sampled {
+		c.cpuUsage = float64(sample.cpuTime-st.cpuTime) / base.ClockTicks / sample.now.Sub(st.cpuSampled).Seconds()
+	}
+	c.stalled = sampled && sample.cpuTime == st.cpuTime
+	c.spinning = sampled && c.cpuUsage >= spinThreshold
+	st.cpuTime, st.cpuSampled = sample.cpuTime, sample.now
+
+	if sample.answered {
+		c.armed, c.recovered = !st.armed, st.warned
+		st.armed, st.warned = true, false
+		st.lastAnswer = sample.now
+		st.players = sample.players
+		return c
+	}
+
+	// The server is loading, or the watchdog can't query it
+	if !st.armed {
+		return c
+	}
+
+	c.unresponsive = sample.now.Sub(st.lastAnswer)
+	if c.unresponsive < timeout {
+		return c
+	}
+
+	// An empty server may print nothing for a long time
+	c.silence = sample.now.Sub(sample.lastOutput)
+	silent := st.players > 0 && c.silence >= timeout
+
+	if !c.stalled && !c.spinning && !silent {
+		c.warn = !st.warned
+		st.warned = true
+		return c
+	}
+	c.hung = true
+	return c
+}
+
+// checkHang samples the server, updates state and reports whether the
+// server is hung.
+func (s *KFServer) checkHang(state *watchdogState, timeout time.Duration) bool {
+	cpuTime, err := base.ProcessCPUTime(state.pid)
+	if err != nil {
+		s.Logger().Debug("Unable to read the server CPU time", "pid", state.pid, "error", err)
+		return false
+	}
+	sample := watchdogSample{now: time.Now(), cpuTime: cpuTime, lastOutput: s.LastOutput()}
+
+	// Query the server
+	s.stateMu.RLock()
+	addr := s.queryAddr()
+	s.stateMu.RUnlock()
+	players, err := QueryPlayerCount(addr, queryTimeout)
+	sample.answered, sample.players = err == nil, players
+
+	c := state.check(sample, timeout)
+	if c.armed {
+		s.Logger().Debug("Server answers the queries, watchdog armed", "pid", state.pid)
+		s.setReady(true)
+	}
+	if c.recovered {
+		s.Logger().Info("Server answers the queries again")
+	}
+	if sample.answered {
+		s.recordPlayers(players)
+		return false
+	}
+	if c.warn {
+		s.Logger().Warn("Server doesn't answer the queries but is still running",
+			"unresponsive", c.unresponsive.Round(time.Second), "cpuUsage", fmt.Sprintf("%.0f%%", c.cpuUsage*100))
+	}
+	if !c.hung {
+		return false
+	}
+
+	s.stateMu.Lock()
+	s.ready = false
+	if s.crashPattern == "" {
+		s.crashPattern = string(base.RestartHang)
+	}
+	s.stateMu.Unlock()
+
+	s.Logger().Error("Server hang detected",
+		"pid", state.pid,
+		"unresponsive", c.unresponsive.Round(time.Second),
+		"silence", c.silence.Round(time.Second),
+		"players", state.players,
+		"cpuUsage", fmt.Sprintf("%.0f%%", c.cpuUsage*100),
+		"stalled", c.stalled,
+		"spinning", c.spinning,
+	)
+	return true
+}
@@ -0,0 +1,100 @@
+package kfserver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/K4rian/kfdsl/internal/services/base"
+)
+
+func TestWatchdogCheck(t *testing.T) {
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(secs int) time.Time { return start.Add(time.Duration(secs) * time.Second) }
+	const timeout = 60 * time.Second
+
+	// armed is a server which answered at start and was sampled 15s before
+	// the check, with cpu ticks of CPU time then
+	armed := func(players int, warned bool) watchdogState {
+		return watchdogState{pid: 1, armed: true, lastAnswer: start, players: players,
+			cpuTime: 1000, cpuSampled: at(75), warned: warned}
+	}
+	// cpu is the CPU time after 15s at usage, 1 being a full core
+	cpu := func(usage float64) uint64 { return 1000 + uint64(usage*15*base.ClockTicks) }
+
+	tests := []struct {
+		name   string
+		state  watchdogState
+		sample watchdogSample
+		want   hangCheck
+	}{
+		{
+			"first answer",
+			watchdogState{pid: 1},
+			watchdogSample{now: at(90), answered: true, players: 2},
+			hangCheck{armed: true},
+		},
+		{
+			"answers again",
+			armed(2, true),
+			watchdogSample{now: at(90), cpuTime: cpu(0.5), answered: true, players: 2},
+			hangCheck{recovered: true, cpuUsage: 0.5},
+		},
+		{
+			"loading",
+			watchdogState{pid: 1, cpuTime: 1000, cpuSampled: at(75)},
+			watchdogSample{now: at(90), cpuTime: 1000},
+			hangCheck{stalled: true},
+		},
+		{
+			"within the timeout",
+			watchdogState{pid: 1, armed: true, lastAnswer: at(40), cpuTime: 1000, cpuSampled: at(75)},
+			watchdogSample{now: at(90), cpuTime: 1000},
+			hangCheck{unresponsive: 50 * time.Second, stalled: true},
+		},
+		{
+			"stalled",
+			armed(0, false),
+			watchdogSample{now: at(90), cpuTime: 1000, lastOutput: at(85)},
+			hangCheck{hung: true, unresponsive: 90 * time.Second, silence: 5 * time.Second, stalled: true},
+		},
+		{
+			"spinning",
+			armed(0, false),
+			watchdogSample{now: at(90), cpuTime: cpu(1), lastOutput: at(85)},
+			hangCheck{hung: true, unresponsive: 90 * time.Second, silence: 5 * time.Second, cpuUsage: 1, spinning: true},
+		},
+		{
+			"silent with players",
+			armed(2, false),
+			watchdogSample{now: at(90), cpuTime: cpu(0.5), lastOutput: at(0)},
+			hangCheck{hung: true, unresponsive: 90 * time.Second, silence: 90 * time.Second, cpuUsage: 0.5},
+		},
+		{
+			"silent without players",
+			armed(0, false),
+			watchdogSample{now: at(90), cpuTime: cpu(0.5), lastOutput: at(0)},
+			hangCheck{warn: true, unresponsive: 90 * time.Second, silence: 90 * time.Second, cpuUsage: 0.5},
+		},
+		{
+			"still running, already warned",
+			armed(2, true),
+			watchdogSample{now: at(90), cpuTime: cpu(0.5), lastOutput: at(85)},
+			hangCheck{unresponsive: 90 * time.Second, silence: 5 * time.Second, cpuUsage: 0.5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := tt.state
+			if got := state.check(tt.sample, timeout); got != tt.want {
+				t.Errorf("check() = %+v, want %+v", got, tt.want)
+			}
+			if state.cpuTime != tt.sample.cpuTime || !state.cpuSampled.Equal(tt.sample.now) {
+				t.Errorf("check() CPU sample = %d at %v, want %d at %v", state.cpuTime, state.cpuSampled, tt.sample.cpuTime, tt.sample.now)
+			}
+			if tt.sample.answered && (!state.armed || state.warned || !state.lastAnswer.Equal(tt.sample.now) || state.players != tt.sample.players) {
+				t.Errorf("check() state after an answer = %+v", state)
+			}
+		})
+	}
+}
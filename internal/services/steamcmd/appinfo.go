@@ -0,0 +1,94 @@
+package steamcmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// vdfToken matches a quoted key or value of a Valve KeyValues (VDF) line.
+var vdfToken = regexp.MustCompile(`"((?:[^"\\]|\\.)*)"`)
+
+// AppBuildID returns the build ID of the branch of appID on Steam, printed by
+// app_info_print. The login is anonymous, so no credentials are exposed on
+// the command-line.
+func (s *SteamCMD) AppBuildID(appID int, branch string) (string, error) {
+	var mu sync.Mutex
+	var lines []string
+	s.AddLogHandler(func(line string) bool {
+		mu.Lock()
+		lines = append(lines, line)
+		mu.Unlock()
+		return false
+	})
+
+	id := strconv.Itoa(appID)
+	if err := s.Run("+login", "anonymous", "+app_info_update", "1", "+app_info_print", id, "+quit"); err != nil {
+		return "", err
+	}
+	if err := s.Wait(); err != nil {
+		return "", err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	buildID, ok := vdfValue(lines, id, "depots", "branches", branch, "buildid")
+	if !ok {
+		return "", fmt.Errorf("build ID of the %s branch of app %d not found in the app info", branch, appID)
+	}
+	return buildID, nil
+}
+
+// InstalledBuildID returns the build ID of appID installed in installDir,
+// read from its app manifest.
+func InstalledBuildID(installDir string, appID int) (string, error) {
+	manifest := filepath.Join(installDir, "steamapps", fmt.Sprintf("appmanifest_%d.acf", appID))
+	data, err := os.ReadFile(manifest)
+	if err != nil {
+		return "", err
+	}
+
+	buildID, ok := vdfValue(strings.Split(string(data), "\n"), "AppState", "buildid")
+	if !ok {
+		return "", fmt.Errorf("build ID not found in %s", manifest)
+	}
+	return buildID, nil
+}
+
+// vdfValue returns the value at path in the VDF lines. The lines before the
+// root key, such as the SteamCMD output, are skipped.
+func vdfValue(lines []string, path ...string) (string, bool) {
+	var keys []string // Keys of the opened blocks
+	var pending string
+
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "{":
+			keys = append(keys, pending)
+			continue
+		case line == "}":
+			if len(keys) > 0 {
+				keys = keys[:len(keys)-1]
+			}
+			continue
+		}
+
+		tokens := vdfToken.FindAllStringSubmatch(line, -1)
+		switch len(tokens) {
+		case 1:
+			pending = tokens[0][1]
+		case 2:
+			key := append(slices.Clone(keys), tokens[0][1])
+			if slices.EqualFunc(key, path, strings.EqualFold) {
+				return tokens[1][1], true
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,91 @@
+package steamcmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testAppInfo = `Redirecting stderr to '/home/steam/Steam/logs/stderr.txt'
+Loading Steam API...OK
+AppID : 215360, change number : 24612345/0, last change : Mon Jan  1 00:00:00 2024
+"215360"
+{
+	"common"
+	{
+		"name"		"Killing Floor Dedicated Server"
+		"buildid"		"1"
+	}
+	"depots"
+	{
+		"215361"
+		{
+			"name"		"Killing Floor Dedicated Server Linux"
+		}
+		"branches"
+		{
+			"public"
+			{
+				"buildid"		"6372153"
+				"timeupdated"		"1700000000"
+			}
+			"beta"
+			{
+				"buildid"		"6400000"
+				"description"		"say \"hi\""
+			}
+		}
+	}
+}
+`
+
+func TestVDFValue(t *testing.T) {
+	lines := strings.Split(testAppInfo, "\n")
+
+	tests := []struct {
+		name   string
+		path   []string
+		want   string
+		wantOk bool
+	}{
+		{"public branch", []string{"215360", "depots", "branches", "public", "buildid"}, "6372153", true},
+		{"other branch", []string{"215360", "depots", "branches", "beta", "buildid"}, "6400000", true},
+		{"case insensitive", []string{"215360", "Depots", "Branches", "PUBLIC", "BuildID"}, "6372153", true},
+		{"escaped quotes", []string{"215360", "depots", "branches", "beta", "description"}, `say \"hi\"`, true},
+		{"after a closed block", []string{"215360", "depots", "branches", "public", "timeupdated"}, "1700000000", true},
+		{"same key elsewhere", []string{"215360", "common", "buildid"}, "1", true},
+		{"missing branch", []string{"215360", "depots", "branches", "dev", "buildid"}, "", false},
+		{"partial path", []string{"depots", "branches", "public", "buildid"}, "", false},
+		{"block", []string{"215360", "depots", "branches", "public"}, "", false},
+		{"other app", []string{"1250", "depots", "branches", "public", "buildid"}, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := vdfValue(lines, tt.path...)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("vdfValue(%v) = %q, %v, want %q, %v", tt.path, got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestInstalledBuildID(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := InstalledBuildID(dir, 215360); err == nil {
+		t.Error("InstalledBuildID() without manifest returned no error")
+	}
+
+	manifest := "\"AppState\"\r\n{\r\n\t\"appid\"\t\t\"215360\"\r\n\t\"buildid\"\t\t\"6372153\"\r\n}\r\n"
+	if err := os.MkdirAll(filepath.Join(dir, "steamapps"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "steamapps", "appmanifest_215360.acf"), []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := InstalledBuildID(dir, 215360)
+	if err != nil || got != "6372153" {
+		t.Errorf("InstalledBuildID() = %q, %v, want 6372153, nil", got, err)
+	}
+}
@@ -1,18 +1,31 @@
 package settings
 
 const (
+	DefaultLauncherConfigFile   = ""
 	DefaultConfigFile           = "KillingFloor.ini"
+	DefaultConfigBackups        = 10
 	DefaultModsFile             = "mods.json"
+	DefaultModsTrustStore       = ".kfdsl/trustedkeys" // Relative to the user home directory
+	DefaultModsRequireSignature = false
 	DefaultServerName           = "Killing Floor Server"
 	DefaultShortName            = "KF Server"
+	DefaultIP                   = ""
 	DefaultGamePort             = 7707
 	DefaultWebAdminPort         = 8075
+	DefaultWebAdminIP           = ""
 	DefaultGameSpyPort          = 7717
 	DefaultGameMode             = "survival"
 	DefaultStartupMap           = "KF-BioticsLab"
 	DefaultGameDifficulty       = "hard"
 	DefaultGameLength           = "medium"
 	DefaultFriendlyFire         = 0.0
+	DefaultStartingCash         = 300
+	DefaultMinRespawnCash       = 250
+	DefaultTimeBetweenWaves     = 60
+	DefaultDisableWaveFunding   = false
+	DefaultMaxZombiesOnce       = 32
+	DefaultDisableEndGameBoss   = false
+	DefaultCustomWavesFile      = ""
 	DefaultMaxPlayers           = 6
 	DefaultMaxSpectators        = 6
 	DefaultPassword             = ""
@@ -57,6 +70,29 @@ const (
 	DefaultRestartDelay         = 5
 	DefaultShutdownTimeout      = 10
 	DefaultKillTimeout          = 5
+	DefaultShutdownWarnings     = ""
+	DefaultShutdownWaitWave     = false
+	DefaultShutdownGrace        = 300
+	DefaultReloadWait           = 300
+	DefaultAPIAddr              = ""
+	DefaultAPIToken             = ""
+	DefaultCrashReports         = 10
+	DefaultCrashCoreDump        = false
+	DefaultHangTimeout          = 120
+	DefaultIdleRestart          = 0
+	DefaultIdleRestartUptime    = 21600
+	DefaultIdleReset            = 0
+	DefaultUpdateCheck          = 0
+	DefaultUsageInterval        = 15
+	DefaultNice                 = 0
+	DefaultCPUAffinity          = ""
+	DefaultLimitNoFile          = 0
+	DefaultLimitAS              = 0
+	DefaultOOMScoreAdj          = 0
+	DefaultRunAs                = ""
+	DefaultEnvAllow             = "PATH,HOME,LANG,LC_*,TZ,TERM,LD_LIBRARY_PATH"
+	DefaultNoNewPrivs           = false
+	DefaultSubreaper            = false
 	DefaultSteamLogin           = "anonymous"
 	DefaultSteamPassword        = ""
 )
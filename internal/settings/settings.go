@@ -3,25 +3,46 @@ package settings
 import (
 	"fmt"
 	"reflect"
+	"strings"
 	"time"
 
 	"github.com/K4rian/kfdsl/internal/arguments"
+	"github.com/K4rian/kfdsl/internal/config/ini"
+	"github.com/K4rian/kfdsl/internal/cron"
 	"github.com/K4rian/kfdsl/internal/log"
+	"github.com/K4rian/kfdsl/internal/monitor"
 )
 
+// RedactedValue replaces the sensitive values in exported settings.
+const RedactedValue = "<redacted>"
+
 type Settings struct {
+	LauncherConfigFile   *arguments.Argument[string]        // Launcher Configuration File
 	ConfigFile           *arguments.Argument[string]        // Server Configuration File
+	IniOverrides         *arguments.Argument[[]string]      // Server Configuration File overrides (Section.Key=value)
+	ConfigBackups        *arguments.Argument[int]           // Number of backups to keep for each configuration file
 	ModsFile             *arguments.Argument[string]        // File defining which mods to install
+	ModsTrustStore       *arguments.Argument[string]        // Directory holding the trusted mods publisher keys
+	ModsRequireSignature *arguments.Argument[bool]          // Refuse to install unsigned mods
 	ServerName           *arguments.Argument[string]        // Server Name
 	ShortName            *arguments.Argument[string]        // Server Alias
+	IP                   *arguments.Argument[string]        // IP address the server is bound to (multihome)
 	GamePort             *arguments.Argument[int]           // Port
 	WebAdminPort         *arguments.Argument[int]           // Web Admin Panel Port
+	WebAdminIP           *arguments.Argument[string]        // Web Admin Panel IP address
 	GameSpyPort          *arguments.Argument[int]           // GameSpy Port
 	GameMode             *arguments.Argument[string]        // Game Mode to use (Survival, Objective, Toy Master or Custom)
 	StartupMap           *arguments.Argument[string]        // Starting map
 	GameDifficulty       *arguments.Argument[int]           // Game Difficulty
 	GameLength           *arguments.Argument[int]           // Game Length
 	FriendlyFire         *arguments.Argument[float64]       // Friendly Fire Rate
+	StartingCash         *arguments.Argument[int]           // Players starting cash
+	MinRespawnCash       *arguments.Argument[int]           // Minimum cash given to the respawning players
+	TimeBetweenWaves     *arguments.Argument[int]           // Trader time between waves in seconds
+	DisableWaveFunding   *arguments.Argument[bool]          // Don't give cash to the players at the end of each wave
+	MaxZombiesOnce       *arguments.Argument[int]           // Maximum specimens alive at the same time
+	DisableEndGameBoss   *arguments.Argument[bool]          // Don't spawn the Patriarch on the final wave
+	CustomWavesFile      *arguments.Argument[string]        // File defining the waves of the custom game length
 	MaxPlayers           *arguments.Argument[int]           // Maximum Players
 	MaxSpectators        *arguments.Argument[int]           // Maximum Spectators
 	Password             *arguments.Argument[string]        // Server Password
@@ -66,6 +87,31 @@ type Settings struct {
 	RestartDelay         *arguments.Argument[time.Duration] // Delay between restart in seconds
 	ShutdownTimeout      *arguments.Argument[time.Duration] // Server shutdown timeout in seconds
 	KillTimeout          *arguments.Argument[time.Duration] // Server process kill timeout in seconds
+	ShutdownWarnings     *arguments.Argument[string]        // Countdown warnings broadcast before stopping the server
+	ShutdownWaitWave     *arguments.Argument[bool]          // Wait for the end of the current wave before stopping the server
+	ShutdownGrace        *arguments.Argument[time.Duration] // Max time to warn the players before stopping the server
+	ReloadWait           *arguments.Argument[time.Duration] // Max time to wait for an empty server before applying a reload
+	APIAddr              *arguments.Argument[string]        // Address of the launcher HTTP API
+	APIToken             *arguments.Argument[string]        // Bearer token required by the launcher HTTP API
+	Schedule             *arguments.Argument[cron.Tasks]    // Scheduled tasks (restarts, updates, messages...)
+	CrashReports         *arguments.Argument[int]           // Number of crash reports to keep
+	CrashCoreDump        *arguments.Argument[bool]          // Enable the server core dumps
+	HangTimeout          *arguments.Argument[time.Duration] // Time without answering queries after which a stuck server is restarted
+	IdleRestart          *arguments.Argument[time.Duration] // Time the server must be empty before it's restarted
+	IdleRestartUptime    *arguments.Argument[time.Duration] // Min server uptime before an idle restart
+	IdleReset            *arguments.Argument[time.Duration] // Time the server must be empty before it returns to its startup map
+	UpdateCheck          *arguments.Argument[time.Duration] // Interval between the checks for a server update on Steam
+	UsageInterval        *arguments.Argument[time.Duration] // Interval between the server resource usage samples
+	UsageRules           *arguments.Argument[monitor.Rules] // Server resource usage thresholds and their actions
+	Nice                 *arguments.Argument[int]           // Server process scheduling priority
+	CPUAffinity          *arguments.Argument[string]        // CPUs the server process runs on
+	LimitNoFile          *arguments.Argument[int]           // Max open files of the server process
+	LimitAS              *arguments.Argument[int]           // Max address space of the server process (MB)
+	OOMScoreAdj          *arguments.Argument[int]           // OOM score adjustment of the server process
+	RunAs                *arguments.Argument[string]        // User and group the server process runs as
+	EnvAllow             *arguments.Argument[string]        // Environment variables passed to the server process
+	NoNewPrivs           *arguments.Argument[bool]          // Prevent the server process from gaining privileges
+	Subreaper            *arguments.Argument[bool]          // Reap the orphaned processes of the servers and SteamCMD
 	SteamCMDRoot         *arguments.Argument[string]        // SteamCMD root directory
 	ServerInstallDir     *arguments.Argument[string]        // Server install directory
 	ExtraArgs            []string                           // Extra arguments passed to the server
@@ -136,3 +182,67 @@ func (s *Settings) Print() {
 	}
 	log.Logger.Info("=====================================================")
 }
+
+// Values returns the parsed settings by name. The non-empty sensitive
+// values are replaced by RedactedValue, the schedule and the usage rules by
+// their summary.
+func (s *Settings) Values() map[string]any {
+	val := reflect.ValueOf(s).Elem()
+
+	values := make(map[string]any, val.NumField())
+	for i := 0; i < val.NumField(); i++ {
+		field := val.Field(i)
+		if field.Kind() != reflect.Ptr || field.IsNil() {
+			continue
+		}
+		pField, ok := field.Interface().(arguments.ParsableArgument)
+		if !ok {
+			continue
+		}
+
+		value := pField.AnyValue()
+		switch v := value.(type) {
+		case time.Duration:
+			value = v.String()
+		case cron.Tasks, monitor.Rules:
+			value = pField.FormattedValue()
+		case string:
+			if pField.IsSensitive() && v != "" {
+				value = RedactedValue
+			}
+		case []string:
+			if field.Interface() == any(s.IniOverrides) {
+				value = redactOverrides(v)
+			}
+		}
+		values[pField.Name()] = value
+	}
+	return values
+}
+
+// secretKeyWords are the words of the ini keys holding secrets, such as
+// AdminPassword or GamePassword.
+var secretKeyWords = []string{"password", "passwd", "secret", "token"}
+
+// redactOverrides replaces the values of the ini overrides setting a secret
+// key by RedactedValue.
+func redactOverrides(specs []string) []string {
+	redacted := make([]string, 0, len(specs))
+	for _, spec := range specs {
+		o, err := ini.ParseOverride(spec)
+		if err != nil {
+			// Not an override, it can't be told which part is the value
+			redacted = append(redacted, RedactedValue)
+			continue
+		}
+		key := strings.ToLower(o.Key)
+		for _, word := range secretKeyWords {
+			if o.Value != "" && strings.Contains(key, word) {
+				o.Value = RedactedValue
+				break
+			}
+		}
+		redacted = append(redacted, o.String())
+	}
+	return redacted
+}
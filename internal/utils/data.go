@@ -1,5 +1,12 @@
 package utils
 
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
 func RemoveDuplicates[T comparable](sliceList []T) []T {
 	keys := make(map[T]bool)
 	l := []T{}
@@ -11,3 +18,49 @@ func RemoveDuplicates[T comparable](sliceList []T) []T {
 	}
 	return l
 }
+
+// ParseCPUList parses a list of CPU numbers and ranges such as "0,2-3".
+func ParseCPUList(list string) ([]int, error) {
+	var cpus []int
+	for _, part := range strings.Split(list, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		first, last, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(strings.TrimSpace(first))
+		if err != nil || start < 0 {
+			return nil, fmt.Errorf("invalid CPU number: '%s'", part)
+		}
+		end := start
+		if isRange {
+			end, err = strconv.Atoi(strings.TrimSpace(last))
+			if err != nil || end < start {
+				return nil, fmt.Errorf("invalid CPU range: '%s'", part)
+			}
+		}
+		for cpu := start; cpu <= end; cpu++ {
+			cpus = append(cpus, cpu)
+		}
+	}
+	return RemoveDuplicates(cpus), nil
+}
+
+// ParseDurationList parses a comma-separated list of durations such as
+// "5m,1m,10s".
+func ParseDurationList(list string) ([]time.Duration, error) {
+	var durations []time.Duration
+	for _, part := range strings.Split(list, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		d, err := time.ParseDuration(part)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid duration: '%s'", part)
+		}
+		durations = append(durations, d)
+	}
+	return durations, nil
+}
@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"fmt"
+	"os/user"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// LookupCredential returns the credential and home directory of spec, a
+// user name or ID optionally followed by a group name or ID (user:group).
+// The user primary group is used when no group is given, or the user ID if
+// the user has no passwd entry.
+func LookupCredential(spec string) (*syscall.Credential, string, error) {
+	userSpec, groupSpec, hasGroup := strings.Cut(strings.TrimSpace(spec), ":")
+
+	// A numeric ID doesn't need a passwd entry, common in containers
+	var uid, gid uint64
+	var home string
+	u, err := user.Lookup(userSpec)
+	if err != nil {
+		if uid, err = strconv.ParseUint(userSpec, 10, 32); err != nil {
+			return nil, "", fmt.Errorf("unknown user '%s'", userSpec)
+		}
+		gid = uid
+		u, _ = user.LookupId(userSpec)
+	}
+	if u != nil {
+		if uid, err = strconv.ParseUint(u.Uid, 10, 32); err != nil {
+			return nil, "", fmt.Errorf("invalid user ID %s", u.Uid)
+		}
+		if gid, err = strconv.ParseUint(u.Gid, 10, 32); err != nil {
+			return nil, "", fmt.Errorf("invalid group ID %s", u.Gid)
+		}
+		home = u.HomeDir
+	}
+
+	if hasGroup {
+		g, err := user.LookupGroup(groupSpec)
+		if err == nil {
+			gid, err = strconv.ParseUint(g.Gid, 10, 32)
+		} else {
+			gid, err = strconv.ParseUint(groupSpec, 10, 32)
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("unknown group '%s'", groupSpec)
+		}
+	}
+
+	// Supplementary groups, the primary one alone if they can't be listed
+	cred := &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid), Groups: []uint32{}}
+	if u != nil {
+		if groupIDs, err := u.GroupIds(); err == nil {
+			for _, id := range groupIDs {
+				if g, err := strconv.ParseUint(id, 10, 32); err == nil && uint32(g) != cred.Gid {
+					cred.Groups = append(cred.Groups, uint32(g))
+				}
+			}
+		}
+	}
+	return cred, home, nil
+}